@@ -0,0 +1,267 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//ErrAdvisoryLockUnsupported is returned when the driver of the client has no advisory locks
+var ErrAdvisoryLockUnsupported = errors.New("Advisory locks are not supported by the driver")
+
+//mysqlLockNameLimit is the maximum length of a MySQL lock name
+const mysqlLockNameLimit = 64
+
+//advisoryDialect represent the advisory lock statements of a driver
+type advisoryDialect struct {
+	lock        string
+	tryLock     string
+	unlock      string
+	xactLock    string
+	xactTryLock string
+	checkLock   bool //lock returns whether the lock is acquired
+	key         func(key string) interface{}
+}
+
+var (
+	postgresAdvisory = &advisoryDialect{
+		lock:        "SELECT pg_advisory_lock(?)",
+		tryLock:     "SELECT pg_try_advisory_lock(?)",
+		unlock:      "SELECT pg_advisory_unlock(?)",
+		xactLock:    "SELECT pg_advisory_xact_lock(?)",
+		xactTryLock: "SELECT pg_try_advisory_xact_lock(?)",
+		key:         func(key string) interface{} { return AdvisoryKey(key) },
+	}
+	mysqlAdvisory = &advisoryDialect{
+		lock:      "SELECT GET_LOCK(?, -1)",
+		tryLock:   "SELECT GET_LOCK(?, 0)",
+		unlock:    "SELECT RELEASE_LOCK(?)",
+		checkLock: true,
+		key: func(key string) interface{} {
+			if len(key) <= mysqlLockNameLimit {
+				return key
+			}
+			return fmt.Sprintf("dbx:%x", uint64(AdvisoryKey(key)))
+		},
+	}
+)
+
+//advisoryDialectOf returns the advisory lock statements of the driver
+func advisoryDialectOf(driverName string) (*advisoryDialect, error) {
+	switch driverName {
+	case "postgres", "pgx":
+		return postgresAdvisory, nil
+	case "mysql":
+		return mysqlAdvisory, nil
+	}
+	return nil, ErrAdvisoryLockUnsupported
+}
+
+//AdvisoryKey returns the int64 key of a string advisory lock key
+func AdvisoryKey(key string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	return int64(hash.Sum64())
+}
+
+//isAcquired determine if the result of a lock statement reports the lock as acquired
+func isAcquired(result interface{}) bool {
+	switch value := result.(type) {
+	case bool:
+		return value
+	case int64:
+		return value == 1
+	case []byte:
+		return string(value) == "1" || string(value) == "t" || string(value) == "true"
+	case string:
+		return value == "1" || value == "t" || value == "true"
+	}
+	return false
+}
+
+//AdvisoryLock represent a session advisory lock, it holds a connection of the pool until it's unlocked
+type AdvisoryLock struct {
+	conn   *sql.Conn
+	unlock string
+	key    interface{}
+	mutex  sync.Mutex
+	locked bool
+}
+
+//Unlock releases the lock and returns its connection to the pool. If the lock cannot be released,
+//the connection is closed which releases it as well. Unlocking a released lock does nothing
+func (me *AdvisoryLock) Unlock(ctx context.Context) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if !me.locked {
+		return nil
+	}
+	me.locked = false
+
+	var result interface{}
+	err := me.conn.QueryRowContext(ctx, me.unlock, me.key).Scan(&result)
+	if err != nil {
+		me.conn.Raw(func(driverConn interface{}) error { return driver.ErrBadConn })
+	}
+	if closeErr := me.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+//acquire runs the lock statement on a dedicated connection and returns the lock, ErrLockNotAvailable if
+//it's not acquired
+func (me *Client) acquire(ctx context.Context, key string, try bool) (*AdvisoryLock, error) {
+	dialect, err := advisoryDialectOf(me.DriverName())
+	if err != nil {
+		return nil, err
+	}
+	conn, err := me.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statement := dialect.lock
+	if try {
+		statement = dialect.tryLock
+	}
+
+	bindType := sqlx.BindType(me.DriverName())
+	lock := &AdvisoryLock{conn: conn, unlock: sqlx.Rebind(bindType, dialect.unlock), key: dialect.key(key)}
+	var result interface{}
+	if err := conn.QueryRowContext(ctx, sqlx.Rebind(bindType, statement), lock.key).Scan(&result); err != nil {
+		conn.Raw(func(driverConn interface{}) error { return driver.ErrBadConn })
+		conn.Close()
+		return nil, err
+	}
+	if (try || dialect.checkLock) && !isAcquired(result) {
+		conn.Close()
+		return nil, ErrLockNotAvailable
+	}
+	lock.locked = true
+	return lock, nil
+}
+
+//AdvisoryLock waits until it acquires the session advisory lock of the key, or ctx is done.
+//String keys are hashed to int64 keys on Postgres, see AdvisoryKey. MySQL uses GET_LOCK
+func (me *Client) AdvisoryLock(ctx context.Context, key string) (*AdvisoryLock, error) {
+	return me.acquire(ctx, key, false)
+}
+
+//TryAdvisoryLock acquires the session advisory lock of the key if it's available, ErrLockNotAvailable otherwise
+func (me *Client) TryAdvisoryLock(ctx context.Context, key string) (*AdvisoryLock, error) {
+	return me.acquire(ctx, key, true)
+}
+
+//xactLock runs the transaction lock statement
+func (me *Transaction) xactLock(ctx context.Context, key string, try bool) error {
+	dialect, err := advisoryDialectOf(me.db.DriverName())
+	if err != nil {
+		return err
+	}
+	if dialect.xactLock == "" {
+		return ErrAdvisoryLockUnsupported
+	}
+	statement := dialect.xactLock
+	if try {
+		statement = dialect.xactTryLock
+	}
+	tx := me.tx()
+	var result interface{}
+	if err := tx.QueryRowxContext(ctx, tx.Rebind(statement), dialect.key(key)).Scan(&result); err != nil {
+		return err
+	}
+	if try && !isAcquired(result) {
+		return ErrLockNotAvailable
+	}
+	return nil
+}
+
+//AdvisoryLock waits until it acquires the advisory lock of the key for the transaction, it's released
+//when the transaction completes. It's supported on Postgres only
+func (me *Transaction) AdvisoryLock(ctx context.Context, key string) error {
+	return me.xactLock(ctx, key, false)
+}
+
+//TryAdvisoryLock acquires the advisory lock of the key for the transaction if it's available,
+//ErrLockNotAvailable otherwise. It's supported on Postgres only
+func (me *Transaction) TryAdvisoryLock(ctx context.Context, key string) error {
+	return me.xactLock(ctx, key, true)
+}
+
+//Mutex is a mutual exclusion lock shared by every process using the database, backed by a session advisory lock.
+//It can elect the leader running a cron job among replicas, see Do
+type Mutex struct {
+	client *Client
+	key    string
+	mutex  sync.Mutex
+	lock   *AdvisoryLock
+}
+
+//Lock waits until the mutex is acquired or ctx is done
+func (me *Mutex) Lock(ctx context.Context) error {
+	return me.acquire(ctx, false)
+}
+
+//TryLock acquires the mutex if it's available and reports whether it's acquired
+func (me *Mutex) TryLock(ctx context.Context) (bool, error) {
+	err := me.acquire(ctx, true)
+	if errors.Is(err, ErrLockNotAvailable) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+//acquire acquires the advisory lock of the mutex
+func (me *Mutex) acquire(ctx context.Context, try bool) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.lock != nil {
+		return errors.New("Mutex " + me.key + " is already locked by this instance")
+	}
+	lock, err := me.client.acquire(ctx, me.key, try)
+	if err != nil {
+		return err
+	}
+	me.lock = lock
+	return nil
+}
+
+//Unlock releases the mutex, unlocking a mutex which is not locked does nothing
+func (me *Mutex) Unlock(ctx context.Context) error {
+	me.mutex.Lock()
+	lock := me.lock
+	me.lock = nil
+	me.mutex.Unlock()
+	if lock == nil {
+		return nil
+	}
+	return lock.Unlock(ctx)
+}
+
+//Do runs fn if the mutex is available and releases it afterwards. It reports whether fn has run,
+//every replica can call Do on schedule and a single one runs the job
+func (me *Mutex) Do(ctx context.Context, fn func(ctx context.Context) error) (bool, error) {
+	locked, err := me.TryLock(ctx)
+	if err != nil || !locked {
+		return false, err
+	}
+	err = fn(ctx)
+	if unlockErr := me.Unlock(context.Background()); err == nil {
+		err = unlockErr
+	}
+	return true, err
+}
+
+//NewMutex create new mutex instance of the key
+func NewMutex(client *Client, key string) *Mutex {
+	return &Mutex{
+		client: client,
+		key:    key,
+	}
+}
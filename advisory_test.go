@@ -0,0 +1,121 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/supendi/dbx/internal/driverutil"
+)
+
+func Test_AdvisoryKey(t *testing.T) {
+	if AdvisoryKey("nightly-report") != AdvisoryKey("nightly-report") || AdvisoryKey("nightly-report") == AdvisoryKey("weekly-report") {
+		t.Errorf("Expected equal keys to hash equally and different keys to differ")
+	}
+	name := mysqlAdvisory.key(strings.Repeat("x", 100)).(string)
+	if len(name) > mysqlLockNameLimit || name != mysqlAdvisory.key(strings.Repeat("x", 100)) {
+		t.Errorf("Expected long MySQL lock names to be hashed, got %s", name)
+	}
+}
+
+func Test_Client_AdvisoryLockUnsupported(t *testing.T) {
+	db, err := getSqlxDb(t)
+	if err != nil {
+		t.Fatalf("Fatal create db error: %s", err.Error())
+	}
+	if db.DriverName() != "sqlite3" {
+		t.Skip("Advisory locks are supported by the driver")
+	}
+	if _, err := NewClient(db).AdvisoryLock(context.Background(), "nightly-report"); err != ErrAdvisoryLockUnsupported {
+		t.Errorf("Expected ErrAdvisoryLockUnsupported, got %v", err)
+	}
+}
+
+func Test_Client_AdvisoryLock(t *testing.T) {
+	client, database := newScriptedClient(t, "postgres", func(call *scriptedCall) (*driverutil.Rows, error) {
+		switch {
+		case strings.Contains(call.SQL, "pg_try_advisory_xact_lock"):
+			return scriptedRows("pg_try_advisory_xact_lock", true), nil
+		case strings.Contains(call.SQL, "pg_try_advisory_lock"):
+			return scriptedRows("pg_try_advisory_lock", false), nil
+		case strings.Contains(call.SQL, "pg_advisory_unlock"):
+			return scriptedRows("pg_advisory_unlock", true), nil
+		case strings.Contains(call.SQL, "pg_advisory_lock"):
+			return scriptedRows("pg_advisory_lock", ""), nil
+		}
+		return nil, nil
+	})
+
+	ctx := context.Background()
+	lock, err := client.AdvisoryLock(ctx, "nightly-report")
+	if err != nil {
+		t.Fatalf("AdvisoryLock error: %s", err.Error())
+	}
+	if err := lock.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock error: %s", err.Error())
+	}
+	if err := lock.Unlock(ctx); err != nil {
+		t.Errorf("Expected unlocking a released lock to do nothing, got %s", err.Error())
+	}
+	if _, err := client.TryAdvisoryLock(ctx, "nightly-report"); !errors.Is(err, ErrLockNotAvailable) {
+		t.Errorf("Expected ErrLockNotAvailable, got %v", err)
+	}
+
+	calls := database.statements("")
+	if len(calls) != 3 || calls[0].SQL != "SELECT pg_advisory_lock($1)" || calls[1].SQL != "SELECT pg_advisory_unlock($1)" {
+		t.Fatalf("Expected lock, unlock and try lock, got %d calls", len(calls))
+	}
+	if calls[0].Args[0] != AdvisoryKey("nightly-report") || calls[1].Args[0] != calls[0].Args[0] {
+		t.Errorf("Expected the hashed key, got %v and %v", calls[0].Args[0], calls[1].Args[0])
+	}
+
+	ran, err := NewMutex(client, "nightly-report").Do(ctx, func(ctx context.Context) error { return nil })
+	if err != nil || ran {
+		t.Errorf("Expected the job not to run while another replica is leader, got %v, %v", ran, err)
+	}
+
+	dbContext := client.NewContext()
+	transaction, err := dbContext.BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+	defer dbContext.CompleteTransaction()
+	if err := transaction.TryAdvisoryLock(ctx, "nightly-report"); err != nil {
+		t.Errorf("TryAdvisoryLock error: %s", err.Error())
+	}
+	if len(database.statements(`^SELECT pg_try_advisory_xact_lock\(\$1\)$`)) != 1 {
+		t.Errorf("Expected the lock to be taken for the transaction")
+	}
+}
+
+func Test_Client_MySQLAdvisoryLock(t *testing.T) {
+	client, database := newScriptedClient(t, "mysql", func(call *scriptedCall) (*driverutil.Rows, error) {
+		switch {
+		case strings.Contains(call.SQL, "GET_LOCK(?, -1)"):
+			return scriptedRows("GET_LOCK", int64(1)), nil
+		case strings.Contains(call.SQL, "GET_LOCK(?, 0)"):
+			return scriptedRows("GET_LOCK", int64(0)), nil
+		case strings.Contains(call.SQL, "RELEASE_LOCK"):
+			return scriptedRows("RELEASE_LOCK", int64(1)), nil
+		}
+		return nil, nil
+	})
+
+	ctx := context.Background()
+	mutex := NewMutex(client, "nightly-report")
+	if err := mutex.Lock(ctx); err != nil {
+		t.Fatalf("Lock error: %s", err.Error())
+	}
+	if err := mutex.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock error: %s", err.Error())
+	}
+	if locked, err := mutex.TryLock(ctx); locked || err != nil {
+		t.Errorf("Expected the mutex to be held elsewhere, got %v, %v", locked, err)
+	}
+
+	calls := database.statements("")
+	if len(calls) != 3 || calls[0].Args[0] != "nightly-report" || calls[1].SQL != "SELECT RELEASE_LOCK(?)" {
+		t.Errorf("Expected GET_LOCK and RELEASE_LOCK of the lock name, got %+v", calls)
+	}
+}
@@ -0,0 +1,323 @@
+package dbx
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//AuditTable is the table changes of records of audited tables are recorded into
+const AuditTable = "audit_log"
+
+//AuditLogSchema is the statement creating the audit_log table
+const AuditLogSchema = `CREATE TABLE IF NOT EXISTS audit_log (
+	id VARCHAR(36) PRIMARY KEY,
+	table_name VARCHAR(255) NOT NULL,
+	record_key VARCHAR(255) NOT NULL,
+	operation VARCHAR(6) NOT NULL,
+	actor VARCHAR(255),
+	changed_at TIMESTAMP NOT NULL,
+	before_image TEXT,
+	after_image TEXT,
+	diff TEXT NOT NULL
+)`
+
+const (
+	//AuditInsert is the operation of a change inserting a record
+	AuditInsert = "INSERT"
+	//AuditUpdate is the operation of a change updating a record
+	AuditUpdate = "UPDATE"
+	//AuditDelete is the operation of a change deleting a record
+	AuditDelete = "DELETE"
+)
+
+const actorKey txContextKey = "actor"
+
+//WithActor returns ctx carrying the actor changes are recorded for
+func WithActor(ctx context.Context, actor string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+//ActorFrom returns the actor carried by ctx and whether there is one
+func ActorFrom(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	actor, ok := ctx.Value(actorKey).(string)
+	return actor, ok && actor != ""
+}
+
+//AuditChange represent the change of a column value
+type AuditChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+//AuditEntry represent a recorded change of a record. Before is nil for an insert and After is nil for a delete
+type AuditEntry struct {
+	ID        string
+	Table     string
+	Key       string
+	Operation string
+	Actor     string
+	ChangedAt time.Time
+	Before    map[string]interface{}
+	After     map[string]interface{}
+	Diff      map[string]AuditChange
+}
+
+//AuditHistory represent the recorded changes of a record, oldest first
+type AuditHistory []*AuditEntry
+
+//At returns the record as it was at the time, nil if it did not exist
+func (me AuditHistory) At(at time.Time) map[string]interface{} {
+	var record map[string]interface{}
+	for _, entry := range me {
+		if entry.ChangedAt.After(at) {
+			break
+		}
+		record = entry.After
+	}
+	return record
+}
+
+//auditRow represent a row of the audit_log table
+type auditRow struct {
+	ID        string         `db:"id"`
+	Table     string         `db:"table_name"`
+	Key       string         `db:"record_key"`
+	Operation string         `db:"operation"`
+	Actor     sql.NullString `db:"actor"`
+	ChangedAt time.Time      `db:"changed_at"`
+	Before    sql.NullString `db:"before_image"`
+	After     sql.NullString `db:"after_image"`
+	Diff      string         `db:"diff"`
+}
+
+//entry decodes the row
+func (me *auditRow) entry() (*AuditEntry, error) {
+	entry := &AuditEntry{
+		ID:        me.ID,
+		Table:     me.Table,
+		Key:       me.Key,
+		Operation: me.Operation,
+		Actor:     me.Actor.String,
+		ChangedAt: me.ChangedAt,
+	}
+	if me.Before.Valid {
+		if err := decodeJSON(me.Before.String, &entry.Before); err != nil {
+			return nil, err
+		}
+	}
+	if me.After.Valid {
+		if err := decodeJSON(me.After.String, &entry.After); err != nil {
+			return nil, err
+		}
+	}
+	if err := decodeJSON(me.Diff, &entry.Diff); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+//WithAudit set the tables whose record changes are recorded into audit_log by Context.SaveChanges,
+//in the transaction of the changes. Only statements declaring their record with Statement.Affects are recorded.
+//It must be set before the client is used
+func (me *Client) WithAudit(tables ...string) *Client {
+	me.audited = map[string]bool{}
+	for _, table := range tables {
+		me.audited[table] = true
+	}
+	return me
+}
+
+//isAudited determine if the change of the statement is recorded
+func (me *Client) isAudited(statement *Statement) bool {
+	return statement.table != "" && me.audited[statement.table]
+}
+
+//History returns the recorded changes of the record of the table identified by the key values,
+//given in the order of the key columns
+func (me *Client) History(ctx context.Context, table string, key ...interface{}) (AuditHistory, error) {
+	statement := NewStatement("SELECT id, table_name, record_key, operation, actor, changed_at, before_image, after_image, diff FROM " + AuditTable +
+		" WHERE table_name = :table_name AND record_key = :record_key ORDER BY changed_at")
+	statement.AddParameter("table_name", table)
+	statement.AddParameter("record_key", recordKey(key))
+
+	rows, err := me.QueryStatementContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	history := AuditHistory{}
+	for rows.Next() {
+		row := &auditRow{}
+		if err := rows.StructScan(row); err != nil {
+			return nil, err
+		}
+		entry, err := row.entry()
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+//execAudited executes the statement in the transaction and records the change of its record
+func (me *Client) execAudited(ctx context.Context, transactioner Transactioner, statement *Statement) (sql.Result, error) {
+	key := make([]interface{}, len(statement.keyColumns))
+	predicates := make([]string, len(statement.keyColumns))
+	selectStatement := NewStatement("")
+	for i, column := range statement.keyColumns {
+		value, ok := statement.Parameters[column]
+		if !ok {
+			return nil, fmt.Errorf("Audited statement of %s has no %s parameter", statement.table, column)
+		}
+		key[i] = value
+		predicates[i] = fmt.Sprintf("%s = :dbx_key_%d", column, i)
+		selectStatement.AddParameter(fmt.Sprintf("dbx_key_%d", i), value)
+	}
+	driverName := ""
+	if named, ok := transactioner.(interface{ DriverName() string }); ok {
+		driverName = named.DriverName()
+	}
+	selectStatement.SQL = "SELECT * FROM " + quoteIdentifier(driverName, statement.table) + " WHERE " + strings.Join(predicates, " AND ")
+
+	before, err := readImage(ctx, transactioner, selectStatement)
+	if err != nil {
+		return nil, err
+	}
+	result, err := transactioner.ExecStatementContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	after, err := readImage(ctx, transactioner, selectStatement)
+	if err != nil {
+		return nil, err
+	}
+
+	operation := AuditUpdate
+	switch {
+	case before == nil && after == nil:
+		return result, nil
+	case before == nil:
+		operation = AuditInsert
+	case after == nil:
+		operation = AuditDelete
+	}
+	diff := diffImages(before, after)
+	if operation == AuditUpdate && len(diff) == 0 {
+		return result, nil
+	}
+
+	insert := NewStatement("INSERT INTO " + AuditTable + " (id, table_name, record_key, operation, actor, changed_at, before_image, after_image, diff) " +
+		"VALUES (:id, :table_name, :record_key, :operation, :actor, :changed_at, :before_image, :after_image, :diff)")
+	insert.AddParameter("id", uuid.New().String())
+	insert.AddParameter("table_name", statement.table)
+	insert.AddParameter("record_key", recordKey(key))
+	insert.AddParameter("operation", operation)
+	insert.AddParameter("actor", nil)
+	if actor, ok := ActorFrom(ctx); ok {
+		insert.AddParameter("actor", actor)
+	}
+	insert.AddParameter("changed_at", me.Now().UTC())
+	for name, value := range map[string]interface{}{"before_image": before, "after_image": after, "diff": diff} {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if string(encoded) == "null" {
+			insert.AddParameter(name, nil)
+			continue
+		}
+		insert.AddParameter(name, string(encoded))
+	}
+	if _, err := transactioner.ExecStatementContext(ctx, insert); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+//quoteIdentifier quotes the identifier for the driver, with backticks on MySQL and double quotes otherwise
+func quoteIdentifier(driverName string, identifier string) string {
+	if driverName == "mysql" {
+		return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+//readImage returns the column values of the record selected by the statement, nil if there is none
+func readImage(ctx context.Context, querier Querier, statement *Statement) (map[string]interface{}, error) {
+	rows, err := querier.QueryStatementContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	image := map[string]interface{}{}
+	if err := rows.MapScan(image); err != nil {
+		return nil, err
+	}
+	for column, value := range image {
+		if raw, ok := value.([]byte); ok {
+			image[column] = string(raw)
+		}
+	}
+	return image, nil
+}
+
+//diffImages returns the changed column values between the images
+func diffImages(before map[string]interface{}, after map[string]interface{}) map[string]AuditChange {
+	diff := map[string]AuditChange{}
+	for column, value := range before {
+		diff[column] = AuditChange{Old: value}
+	}
+	for column, value := range after {
+		change := diff[column]
+		change.New = value
+		diff[column] = change
+	}
+	for column, change := range diff {
+		previous, _ := json.Marshal(change.Old)
+		current, _ := json.Marshal(change.New)
+		if bytes.Equal(previous, current) {
+			delete(diff, column)
+		}
+	}
+	return diff
+}
+
+//recordKey returns the record_key of the key values
+func recordKey(key []interface{}) string {
+	values := make([]string, len(key))
+	for i, value := range key {
+		if converted, err := driver.DefaultParameterConverter.ConvertValue(value); err == nil {
+			value = converted
+		}
+		if raw, ok := value.([]byte); ok {
+			value = string(raw)
+		}
+		values[i] = fmt.Sprint(value)
+	}
+	return strings.Join(values, ",")
+}
+
+//decodeJSON decodes the JSON text keeping numbers as json.Number
+func decodeJSON(text string, v interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,132 @@
+package dbx
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Client_AuditTrail(t *testing.T) {
+	db, err := getSqlxDb(t)
+	if err != nil {
+		t.Fatalf("Fatal create db error: %s", err.Error())
+	}
+	db.MustExec(AuditLogSchema)
+	db.MustExec("CREATE TABLE IF NOT EXISTS payment (id VARCHAR(36) PRIMARY KEY, amount INTEGER NOT NULL, status VARCHAR(20) NOT NULL)")
+
+	client := NewClient(db).WithAudit("payment")
+	ctx := WithActor(context.Background(), "dadang")
+	payment := func(sql string, amount int, status string) *Statement {
+		statement := NewStatement(sql).Affects("payment", "id")
+		statement.AddParameter("id", "p1")
+		statement.AddParameter("amount", amount)
+		statement.AddParameter("status", status)
+		return statement
+	}
+
+	dbContext := client.NewContext()
+	dbContext.AddStatement(payment("INSERT INTO payment (id, amount, status) VALUES (:id, :amount, :status)", 100, "pending"))
+	if _, err := dbContext.SaveChanges(ctx); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	inserted := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	dbContext.AddStatements(
+		payment("UPDATE payment SET amount = :amount, status = :status WHERE id = :id", 100, "paid"),
+		payment("UPDATE payment SET amount = :amount, status = :status WHERE id = :id", 100, "paid"),
+	)
+	if _, err := dbContext.SaveChanges(ctx); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	dbContext.AddStatement(payment("DELETE FROM payment WHERE id = :id", 0, ""))
+	if _, err := dbContext.SaveChanges(context.Background()); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+
+	history, err := client.History(context.Background(), "payment", "p1")
+	if err != nil {
+		t.Fatalf("History error: %s", err.Error())
+	}
+	if len(history) != 3 {
+		t.Fatalf("Expected insert, update and delete to be recorded without the unchanged update, got %d entries", len(history))
+	}
+	if history[0].Operation != AuditInsert || history[1].Operation != AuditUpdate || history[2].Operation != AuditDelete {
+		t.Errorf("Unexpected operations %s, %s, %s", history[0].Operation, history[1].Operation, history[2].Operation)
+	}
+	if history[0].Actor != "dadang" || history[2].Actor != "" || history[0].Before != nil || history[2].After != nil {
+		t.Errorf("Unexpected entries %+v, %+v", history[0], history[2])
+	}
+	if len(history[1].Diff) != 1 || history[1].Diff["status"].Old != "pending" || history[1].Diff["status"].New != "paid" {
+		t.Errorf("Expected the status change only, got %+v", history[1].Diff)
+	}
+	if amount, _ := history[1].After["amount"].(json.Number); amount != "100" {
+		t.Errorf("Expected after image amount 100, got %v", history[1].After["amount"])
+	}
+
+	if record := history.At(inserted); record == nil || record["status"] != "pending" {
+		t.Errorf("Expected pending payment after insert, got %v", record)
+	}
+	if record := history.At(time.Now()); record != nil {
+		t.Errorf("Expected the payment to be deleted, got %v", record)
+	}
+	if record := history.At(inserted.Add(-time.Hour)); record != nil {
+		t.Errorf("Expected no payment before insert, got %v", record)
+	}
+}
+
+func Test_Context_AuditRollsBackWithChanges(t *testing.T) {
+	db, err := getSqlxDb(t)
+	if err != nil {
+		t.Fatalf("Fatal create db error: %s", err.Error())
+	}
+	db.MustExec(AuditLogSchema)
+
+	client := NewClient(db).WithAudit("person")
+	dbContext := client.NewContext()
+	first := newPersonStatement("Dadang").Affects("person", "id")
+	duplicate := newPersonStatement("Asep").Affects("person", "id")
+	duplicate.Parameters["id"] = first.Parameters["id"]
+	dbContext.AddStatements(first, duplicate)
+	if _, err := dbContext.SaveChanges(context.Background()); err == nil {
+		t.Fatalf("Expected duplicate key error")
+	}
+
+	history, err := client.History(context.Background(), "person", first.Parameters["id"])
+	if err != nil {
+		t.Fatalf("History error: %s", err.Error())
+	}
+	if len(history) != 0 {
+		t.Errorf("Expected audit entries to be rolled back with the changes, got %d", len(history))
+	}
+
+	unaudited := client.NewContext()
+	unaudited.AddStatement(newPersonStatement("Bowo"))
+	if _, err := unaudited.SaveChanges(context.Background()); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	var count int
+	db.Get(&count, "SELECT COUNT(*) FROM audit_log")
+	if count != 0 {
+		t.Errorf("Expected statements without Affects not to be recorded, got %d entries", count)
+	}
+}
+
+func Test_Client_AuditQuotesTableForDriver(t *testing.T) {
+	client, database := newScriptedClient(t, "mysql", nil)
+	client.WithAudit("payment")
+	statement := NewStatement("UPDATE payment SET status = :status WHERE id = :id").Affects("payment", "id")
+	statement.AddParameter("id", "p1")
+	statement.AddParameter("status", "paid")
+	dbContext := client.NewContext()
+	dbContext.AddStatement(statement)
+	if _, err := dbContext.SaveChanges(context.Background()); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+
+	images := database.statements(`^SELECT \* FROM`)
+	if len(images) != 2 || !strings.HasPrefix(images[0].SQL, "SELECT * FROM `payment` WHERE") {
+		t.Errorf("Expected the images to be read from the table quoted with backticks, got %+v", images)
+	}
+}
@@ -0,0 +1,496 @@
+package dbx
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+
+	"github.com/supendi/dbx/internal/driverutil"
+)
+
+//Cache stores encoded query results by key, see Statement.Cache. Implementations must be safe for concurrent use
+type Cache interface {
+	//Get returns the value of the key, false if it's missing or expired
+	Get(ctx context.Context, key string) ([]byte, bool)
+	//Set stores the value of the key for the ttl, with no expiration if ttl is not positive. The tags are the ones
+	//invalidating it
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string)
+	//Invalidate removes the values of the tags
+	Invalidate(ctx context.Context, tags ...string)
+}
+
+//WithCache set the cache of the results of the statements opting in, see Statement.Cache.
+//It must be set before the client is used
+func (me *Client) WithCache(cache Cache) *Client {
+	me.cache = cache
+	return me
+}
+
+//Invalidate removes the cached results of the tags, it does nothing if the client has no cache.
+//Context.SaveChanges invalidates the tags of its statements once they are committed
+func (me *Client) Invalidate(ctx context.Context, tags ...string) {
+	if me.cache != nil && len(tags) > 0 {
+		me.cache.Invalidate(ctx, tags...)
+	}
+}
+
+//Cache opts the statement in the cache of the client for the ttl, see Select. The tags invalidate its results,
+//table names are the tags invalidated by the statements affecting them, see Affects and Invalidates
+func (me *Statement) Cache(ttl time.Duration, tags ...string) *Statement {
+	me.cacheTTL = ttl
+	me.cacheTags = tags
+	return me
+}
+
+//Invalidates declares the tags whose cached results are invalidated once the statement is saved, besides the table
+//it affects. See Context.SaveChanges
+func (me *Statement) Invalidates(tags ...string) *Statement {
+	me.invalidates = append(me.invalidates, tags...)
+	return me
+}
+
+//invalidatedTags returns the tags invalidated by the statements
+func invalidatedTags(statements []*Statement) []string {
+	tags := []string{}
+	for _, statement := range statements {
+		if statement.table != "" {
+			tags = append(tags, statement.table)
+		}
+		tags = append(tags, statement.invalidates...)
+	}
+	return tags
+}
+
+//Select queries the records of the statement and scan each of them into T, which is a struct or a pointer to struct.
+//Results of a statement opting in the cache are read from and stored in the cache of the client, unless querier
+//or the scope of ctx is in a transaction. See Statement.Cache
+func Select[T any](ctx context.Context, querier Querier, statement *Statement) ([]T, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	client := cacheOf(ctx, querier, statement)
+	var cache Cache
+	var key string
+	if client != nil {
+		cache = client.cache
+		var err error
+		key, err = cacheKey[T](ctx, client, statement)
+		if err != nil {
+			return nil, err
+		}
+		if value, ok := cache.Get(ctx, key); ok {
+			if rows, err := client.cachedRows(ctx, value); err == nil {
+				items, _, err := scanItems[T](rows, false)
+				return items, err
+			}
+		}
+	}
+
+	rows, err := querier.QueryStatementContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	items, cached, err := scanItems[T](rows, cache != nil)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		if value, err := cached.encode(); err == nil {
+			cache.Set(ctx, key, value, statement.cacheTTL, statement.cacheTags...)
+		}
+	}
+	return items, nil
+}
+
+//scanItems scans every row into T and closes the rows. When cache is true it returns their column values as well,
+//so that the results read from the cache are scanned the same way
+func scanItems[T any](rows *Rows, cache bool) ([]T, *cachedResult, error) {
+	defer rows.Close()
+	var cached *cachedResult
+	if cache {
+		columns, err := rows.Columns()
+		if err != nil {
+			return nil, nil, err
+		}
+		cached = &cachedResult{Columns: columns}
+	}
+	items := []T{}
+	for rows.Next() {
+		item, err := scanItem[T](rows, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, item)
+		if cached != nil {
+			values := make([]interface{}, len(cached.Columns))
+			destinations := make([]interface{}, len(values))
+			for i := range values {
+				destinations[i] = &values[i]
+			}
+			if err := rows.Scan(destinations...); err != nil {
+				return nil, nil, err
+			}
+			cached.Rows = append(cached.Rows, values)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return items, cached, nil
+}
+
+//Get queries the first record of the statement and scan it into T like Select does, sql.ErrNoRows if there is none
+func Get[T any](ctx context.Context, querier Querier, statement *Statement) (T, error) {
+	var item T
+	items, err := Select[T](ctx, querier, statement)
+	if err != nil {
+		return item, err
+	}
+	if len(items) == 0 {
+		return item, sql.ErrNoRows
+	}
+	return items[0], nil
+}
+
+//cacheOf returns the client whose cache the results of the statement are read from and stored in, nil if they are
+//not cached
+func cacheOf(ctx context.Context, querier Querier, statement *Statement) *Client {
+	if statement.cacheTTL <= 0 || scopeTransaction(ctx) != nil {
+		return nil
+	}
+	cacher, ok := querier.(cacher)
+	if !ok {
+		return nil
+	}
+	client := cacher.cacheClient()
+	if client == nil || client.cache == nil {
+		return nil
+	}
+	return client
+}
+
+//cacher is implemented by Client and Context, and so by the types embedding them
+type cacher interface {
+	//cacheClient returns the client whose cache results are read from and stored in, nil if they must not be cached
+	cacheClient() *Client
+}
+
+//cacheClient implements cacher
+func (me *Client) cacheClient() *Client {
+	return me
+}
+
+//cacheClient implements cacher, results read in a transaction are not cached
+func (me *Context) cacheClient() *Client {
+	if transaction := me.GetTransaction(); transaction != nil && !transaction.IsComplete() {
+		return nil
+	}
+	return me.Client
+}
+
+//cacheKey returns the key of the results of the statement scanned into T, bound given ctx. It includes the shard
+//the statement runs on and the tenant of ctx when the tenancy strategy applies it to the connection, so their
+//results are not shared
+func cacheKey[T any](ctx context.Context, client *Client, statement *Statement) (string, error) {
+	bound, err := statement.Bind(ctx)
+	if err != nil {
+		return "", err
+	}
+	shard, err := client.shardOf(ctx, statement)
+	if err != nil {
+		return "", err
+	}
+	tenant := ""
+	if client.tenantSetup(ctx) != nil {
+		tenant, _ = TenantFrom(ctx)
+	}
+	parameters, err := json.Marshal(bound.Parameters)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\x00%d\x00%s\x00%s\x00%s", reflect.TypeOf((*T)(nil)).Elem(), shard, tenant, bound.SQL, parameters), nil
+}
+
+//cachedResult represent the column values of cached results. They are scanned into T by database/sql like the rows
+//they were read from, so that a cached result is the same as a queried one
+type cachedResult struct {
+	Columns []string
+	Rows    [][]interface{}
+}
+
+//Kinds of cachedValue
+const (
+	nullValue = iota
+	int64Value
+	float64Value
+	boolValue
+	bytesValue
+	stringValue
+	timeValue
+)
+
+//cachedValue represent a driver value of a cached result, gob does not encode interface values of unregistered types
+type cachedValue struct {
+	Kind   int
+	Int    int64
+	Float  float64
+	Bool   bool
+	Bytes  []byte
+	String string
+	Time   time.Time
+}
+
+//encode returns the gob encoding of the result, an error if a value is not a driver value
+func (me *cachedResult) encode() ([]byte, error) {
+	rows := make([][]cachedValue, len(me.Rows))
+	for i, row := range me.Rows {
+		rows[i] = make([]cachedValue, len(row))
+		for j, value := range row {
+			switch value := value.(type) {
+			case nil:
+				rows[i][j] = cachedValue{Kind: nullValue}
+			case int64:
+				rows[i][j] = cachedValue{Kind: int64Value, Int: value}
+			case float64:
+				rows[i][j] = cachedValue{Kind: float64Value, Float: value}
+			case bool:
+				rows[i][j] = cachedValue{Kind: boolValue, Bool: value}
+			case []byte:
+				rows[i][j] = cachedValue{Kind: bytesValue, Bytes: value}
+			case string:
+				rows[i][j] = cachedValue{Kind: stringValue, String: value}
+			case time.Time:
+				rows[i][j] = cachedValue{Kind: timeValue, Time: value}
+			default:
+				return nil, fmt.Errorf("Can't cache value of type %T", value)
+			}
+		}
+	}
+	var buffer bytes.Buffer
+	err := gob.NewEncoder(&buffer).Encode(struct {
+		Columns []string
+		Rows    [][]cachedValue
+	}{me.Columns, rows})
+	return buffer.Bytes(), err
+}
+
+//value returns the driver value
+func (me cachedValue) value() driver.Value {
+	switch me.Kind {
+	case int64Value:
+		return me.Int
+	case float64Value:
+		return me.Float
+	case boolValue:
+		return me.Bool
+	case bytesValue:
+		return append([]byte{}, me.Bytes...)
+	case stringValue:
+		return me.String
+	case timeValue:
+		return me.Time
+	}
+	return nil
+}
+
+//cachedRowsKey is the context key of the rows served by cacheDB
+type cachedRowsKey struct{}
+
+//cacheDB serves the rows of cached results, see Client.cachedRows
+var cacheDB = sql.OpenDB(cacheConnector{})
+
+//cachedRows returns the rows of a cached result encoded by cachedResult.encode, mapped like the rows of the client
+func (me *Client) cachedRows(ctx context.Context, value []byte) (*Rows, error) {
+	var decoded struct {
+		Columns []string
+		Rows    [][]cachedValue
+	}
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&decoded); err != nil {
+		return nil, err
+	}
+	values := make([][]driver.Value, len(decoded.Rows))
+	for i, row := range decoded.Rows {
+		values[i] = make([]driver.Value, len(row))
+		for j, value := range row {
+			values[i][j] = value.value()
+		}
+	}
+	rows, err := cacheDB.QueryContext(context.WithValue(ctx, cachedRowsKey{}, driverutil.NewRows(decoded.Columns, values, nil)), "")
+	if err != nil {
+		return nil, err
+	}
+	return newRows(&sqlx.Rows{Rows: rows, Mapper: me.mapper()}, nil), nil
+}
+
+//mapper returns the mapper of the columns of the client rows to struct fields
+func (me *Client) mapper() *reflectx.Mapper {
+	if me.DB != nil {
+		return me.DB.Mapper
+	}
+	if len(me.shards) > 0 {
+		return me.shards[0].Mapper
+	}
+	return reflectx.NewMapperFunc("db", sqlx.NameMapper)
+}
+
+//cacheConnector is the driver of cacheDB, its connections serve the rows of the query context
+type cacheConnector struct{}
+
+//Connect implements driver.Connector
+func (me cacheConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return cacheConn{}, nil
+}
+
+//Driver implements driver.Connector
+func (me cacheConnector) Driver() driver.Driver {
+	return me
+}
+
+//Open implements driver.Driver
+func (me cacheConnector) Open(name string) (driver.Conn, error) {
+	return cacheConn{}, nil
+}
+
+//cacheConn is a connection of cacheDB
+type cacheConn struct{}
+
+//Prepare implements driver.Conn, the rows are served without statement
+func (me cacheConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("Cached results can't be prepared")
+}
+
+//Close implements driver.Conn
+func (me cacheConn) Close() error {
+	return nil
+}
+
+//Begin implements driver.Conn
+func (me cacheConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("Cached results can't be read in a transaction")
+}
+
+//QueryContext implements driver.QueryerContext, it returns the rows of ctx
+func (me cacheConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, ok := ctx.Value(cachedRowsKey{}).(*driverutil.Rows)
+	if !ok {
+		return nil, errors.New("No cached rows to read")
+	}
+	return rows, nil
+}
+
+//MemoryCache is an in-memory Cache evicting the least recently used values beyond its capacity
+type MemoryCache struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	recent   *list.List
+	tags     map[string]map[string]bool
+	clock    func() time.Time
+}
+
+//cacheEntry represent a value of the memory cache
+type cacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+	tags    []string
+}
+
+//WithClock set the clock expiring values
+func (me *MemoryCache) WithClock(clock func() time.Time) *MemoryCache {
+	me.clock = clock
+	return me
+}
+
+//Get returns the value of the key, false if it's missing or expired
+func (me *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	element := me.entries[key]
+	if element == nil {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && !me.clock().Before(entry.expires) {
+		me.remove(element)
+		return nil, false
+	}
+	me.recent.MoveToFront(element)
+	return entry.value, true
+}
+
+//Set stores the value of the key for the ttl, with no expiration if ttl is not positive
+func (me *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if element := me.entries[key]; element != nil {
+		me.remove(element)
+	}
+	entry := &cacheEntry{key: key, value: value, tags: tags}
+	if ttl > 0 {
+		entry.expires = me.clock().Add(ttl)
+	}
+	me.entries[key] = me.recent.PushFront(entry)
+	for _, tag := range tags {
+		if me.tags[tag] == nil {
+			me.tags[tag] = map[string]bool{}
+		}
+		me.tags[tag][key] = true
+	}
+	for me.capacity > 0 && me.recent.Len() > me.capacity {
+		me.remove(me.recent.Back())
+	}
+}
+
+//Invalidate removes the values of the tags
+func (me *MemoryCache) Invalidate(ctx context.Context, tags ...string) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	for _, tag := range tags {
+		for key := range me.tags[tag] {
+			me.remove(me.entries[key])
+		}
+	}
+}
+
+//Len returns the number of values, including the expired ones which are not removed yet
+func (me *MemoryCache) Len() int {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.recent.Len()
+}
+
+//remove removes the element and its tags, the mutex must be held
+func (me *MemoryCache) remove(element *list.Element) {
+	entry := me.recent.Remove(element).(*cacheEntry)
+	delete(me.entries, entry.key)
+	for _, tag := range entry.tags {
+		delete(me.tags[tag], entry.key)
+		if len(me.tags[tag]) == 0 {
+			delete(me.tags, tag)
+		}
+	}
+}
+
+//NewMemoryCache create new memory cache instance holding up to capacity values, unbounded if capacity is not positive
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{
+		capacity: capacity,
+		entries:  map[string]*list.Element{},
+		recent:   list.New(),
+		tags:     map[string]map[string]bool{},
+		clock:    time.Now,
+	}
+}
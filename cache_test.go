@@ -0,0 +1,153 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type cachedPerson struct {
+	ID   string `db:"id"`
+	Name string `db:"name"`
+}
+
+func Test_MemoryCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewMemoryCache(2).WithClock(func() time.Time { return now })
+
+	cache.Set(ctx, "a", []byte("1"), time.Minute, "person")
+	cache.Set(ctx, "b", []byte("2"), 0, "order")
+	cache.Get(ctx, "a")
+	cache.Set(ctx, "c", []byte("3"), 0, "person")
+	if _, ok := cache.Get(ctx, "b"); ok {
+		t.Errorf("Expected the least recently used value to be evicted")
+	}
+	if value, ok := cache.Get(ctx, "a"); !ok || string(value) != "1" {
+		t.Errorf("Expected a to be cached, got %q", value)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := cache.Get(ctx, "a"); ok {
+		t.Errorf("Expected a to be expired")
+	}
+	cache.Invalidate(ctx, "person")
+	if cache.Len() != 0 {
+		t.Errorf("Expected the values of the tag to be invalidated, got %d values", cache.Len())
+	}
+}
+
+func Test_Select_Cache(t *testing.T) {
+	db, err := getSqlxDb(t)
+	if err != nil {
+		t.Fatalf("Fatal create db error: %s", err.Error())
+	}
+	client := NewClient(db).WithCache(NewMemoryCache(100))
+	ctx := context.Background()
+	query := func() *Statement {
+		return NewStatement("SELECT id, name FROM person ORDER BY name").Cache(time.Minute, "person")
+	}
+	if persons, err := Select[cachedPerson](ctx, client, query()); err != nil || len(persons) != 0 {
+		t.Fatalf("Expected no person, got %v, %v", persons, err)
+	}
+
+	db.MustExec("INSERT INTO person (id, name, created_at) VALUES ('p0', 'Asep', CURRENT_TIMESTAMP)")
+	if persons, _ := Select[cachedPerson](ctx, client, query()); len(persons) != 0 {
+		t.Errorf("Expected the cached result, got %v", persons)
+	}
+	if persons, _ := Select[cachedPerson](ctx, client, NewStatement("SELECT id, name FROM person")); len(persons) != 1 {
+		t.Errorf("Expected statements not opting in to bypass the cache, got %v", persons)
+	}
+
+	dbContext := client.NewContext()
+	if _, err := dbContext.BeginTransaction(); err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+	dbContext.AddStatement(newPersonStatement("Bowo").Affects("person", "id"))
+	if _, err := dbContext.SaveChanges(ctx); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	if persons, _ := Select[cachedPerson](ctx, dbContext, query()); len(persons) != 2 {
+		t.Errorf("Expected reads in a transaction to bypass the cache, got %v", persons)
+	}
+	if persons, _ := Select[cachedPerson](ctx, client, query()); len(persons) != 0 {
+		t.Errorf("Expected the cache not to be invalidated before commit, got %v", persons)
+	}
+	if err := dbContext.CompleteTransaction(); err != nil {
+		t.Fatalf("CompleteTransaction error: %s", err.Error())
+	}
+	person, err := Get[*cachedPerson](ctx, client, query())
+	if err != nil || person.Name != "Asep" {
+		t.Errorf("Expected the cache to be invalidated after commit, got %v, %v", person, err)
+	}
+}
+
+func Test_Transaction_AfterCommit(t *testing.T) {
+	db, err := getSqlxDb(t)
+	if err != nil {
+		t.Fatalf("Fatal create db error: %s", err.Error())
+	}
+	called := 0
+	rolledBack, _ := NewTransaction(db)
+	rolledBack.AfterCommit(func() { called++ })
+	rolledBack.Rollback()
+	committed, _ := NewTransaction(db)
+	committed.AfterCommit(func() { called++ })
+	committed.Commit()
+	if called != 1 {
+		t.Errorf("Expected AfterCommit to be called on commit only, got %d calls", called)
+	}
+}
+
+func Test_cacheKey(t *testing.T) {
+	client := NewClient(getSQLiteFileDb(t)).WithCache(NewMemoryCache(100)).WithTenancy(TenancySetting)
+	acme, err := cacheKey[struct{}](WithTenant(context.Background(), "acme"), client, NewStatement("SELECT 1"))
+	if err != nil {
+		t.Fatalf("cacheKey error: %s", err.Error())
+	}
+	globex, _ := cacheKey[struct{}](WithTenant(context.Background(), "globex"), client, NewStatement("SELECT 1"))
+	if acme == globex {
+		t.Errorf("Expected the results of tenants not to be shared, got %q", acme)
+	}
+}
+
+func Test_Select_CacheHitScansLikeMiss(t *testing.T) {
+	db := getSQLiteFileDb(t)
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	db.MustExec("INSERT INTO person (id, name, created_at) VALUES ('p0', 'Asep', ?)", createdAt)
+	client := NewClient(db).WithCache(NewMemoryCache(100))
+	type person struct {
+		ID        []byte         `db:"id"`
+		Name      string         `db:"name" json:"-"`
+		CreatedAt time.Time      `db:"created_at"`
+		UpdatedAt sql.NullString `db:"updated_at"`
+	}
+	query := NewStatement("SELECT id, name, created_at, updated_at FROM person").Cache(time.Minute, "person")
+
+	missed, err := Select[person](context.Background(), client, query)
+	if err != nil || len(missed) != 1 || missed[0].Name != "Asep" {
+		t.Fatalf("Expected the person, got %+v, %v", missed, err)
+	}
+	db.MustExec("DELETE FROM person")
+	hit, err := Select[person](context.Background(), client, query)
+	if err != nil || !reflect.DeepEqual(hit, missed) {
+		t.Errorf("Expected the cached result to be the queried one %+v, got %+v, %v", missed, hit, err)
+	}
+}
+
+func Test_Select_CacheEmbeddedContext(t *testing.T) {
+	db := getSQLiteFileDb(t)
+	client := NewClient(db).WithCache(NewMemoryCache(100))
+	dbContext := struct{ *Context }{client.NewContext()}
+	query := NewStatement("SELECT id, name FROM person").Cache(time.Minute, "person")
+	if persons, err := Select[cachedPerson](context.Background(), dbContext, query); err != nil || len(persons) != 0 {
+		t.Fatalf("Expected no person, got %v, %v", persons, err)
+	}
+
+	db.MustExec("INSERT INTO person (id, name, created_at) VALUES ('p0', 'Asep', CURRENT_TIMESTAMP)")
+	if persons, _ := Select[cachedPerson](context.Background(), dbContext, query); len(persons) != 0 {
+		t.Errorf("Expected the result of a type embedding Context to be cached, got %v", persons)
+	}
+}
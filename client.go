@@ -4,128 +4,231 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
-//Client represent db client
+//Client represent db client. It holds no state besides the connection pool, so a single client is meant to be
+//shared by the whole application and is safe for concurrent use. Units of work are created with NewContext or NewScope
 type Client struct {
 	*sqlx.DB
-	transaction              *Transaction
-	IsUserDefinedTransaction bool
+	tenancy        TenancyStrategy
+	audited        map[string]bool
+	clock          func() time.Time
+	dataSourceName string
+	cache          Cache
+	timeout        time.Duration
+	localTimeout   bool
+	retryPolicy    *RetryPolicy
+	breaker        *breaker
+	replicas       []*sqlx.DB
+	replicaNext    uint32
+	transactions   *transactionTracker
+	lifecycle      *lifecycle
+	router         ShardRouter
+	shards         []*sqlx.DB
 }
 
-//ExecStatement create, update or update statement
-func (me *Client) ExecStatement(statement *Statement) (sql.Result, error) {
-	if me.transaction != nil {
-		return me.transaction.ExecStatement(statement)
-	}
-	if me.DB == nil {
-		return nil, errors.New("DB instance of type (*sql.DB) is nil")
+//WithClock set the clock filling stamped time columns and audit entries, see Statement.Stamp.
+//It must be set before the client is used
+func (me *Client) WithClock(clock func() time.Time) *Client {
+	me.clock = clock
+	return me
+}
+
+//Now returns the time of the client clock
+func (me *Client) Now() time.Time {
+	if me.clock == nil {
+		return time.Now()
 	}
-	return me.DB.NamedExec(statement.SQL, statement.Parameters)
+	return me.clock()
 }
 
-//ExecStatementContext create, update or update statement
+//WithTenancy set the strategy applying the tenant of ctx, see WithTenant. It must be set before the client is used
+func (me *Client) WithTenancy(strategy TenancyStrategy) *Client {
+	me.tenancy = strategy
+	return me
+}
+
+//ExecStatement create, update or update statement, within its timeout
+func (me *Client) ExecStatement(statement *Statement) (sql.Result, error) {
+	return me.ExecStatementContext(context.Background(), statement)
+}
+
+//ExecStatementContext create, update or update statement.
+//It runs in the transaction of the scope of ctx if there is one, see NewScope. Otherwise it runs in its own
+//transaction if the tenancy strategy applies the tenant of ctx to transactions. It fails with ErrStatementTimeout
+//if it runs longer than its timeout, see WithStatementTimeout. Outside a transaction, it's guarded by the circuit
+//breaker and retried on transient errors if it's idempotent, see Statement.Idempotent, and it fails with
+//ErrClientClosed once the client is shut down
 func (me *Client) ExecStatementContext(ctx context.Context, statement *Statement) (sql.Result, error) {
-	if me.transaction != nil {
-		return me.transaction.ExecStatementContext(ctx, statement)
+	if transaction := scopeTransaction(ctx); transaction != nil {
+		return transaction.ExecStatementContext(ctx, statement)
 	}
 	if me.DB == nil {
 		return nil, errors.New("DB instance of type (*sql.DB) is nil")
 	}
-	return me.DB.NamedExecContext(ctx, statement.SQL, statement.Parameters)
+	if err := me.lifecycle.enter(); err != nil {
+		return nil, err
+	}
+	defer me.lifecycle.leave()
+	if setup := me.tenantSetup(ctx); setup != nil {
+		shard, err := me.shardOf(ctx, statement)
+		if err != nil {
+			return nil, err
+		}
+		transaction, err := me.newTransactionOn(ctx, shard)
+		if err != nil {
+			return nil, err
+		}
+		result, err := transaction.ExecStatementContext(ctx, statement)
+		if err != nil {
+			transaction.Rollback()
+			return nil, err
+		}
+		return result, transaction.Commit()
+	}
+	if statement.IsLocking() {
+		return nil, ErrLockRequiresTransaction
+	}
+	return me.exec(ctx, statement)
 }
 
-//QueryStatement records on database and return it as sqlx.Rows
-func (me *Client) QueryStatement(statement *Statement) (*sqlx.Rows, error) {
-	if me.transaction != nil && !me.transaction.IsComplete() {
-		return me.transaction.QueryStatement(statement)
+//exec executes the statement outside a transaction within its timeout, guarded by the circuit breaker and retried
+//on transient errors if it's idempotent
+func (me *Client) exec(ctx context.Context, statement *Statement) (sql.Result, error) {
+	shard, err := me.shardOf(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	bound, err := statement.Bind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var result sql.Result
+	err = me.resilient(ctx, statement, func() error {
+		statementCtx, cancel := statementContext(ctx, timeoutOf(statement, me.timeout))
+		defer cancel()
+		var err error
+		result, err = me.database(shard).NamedExecContext(statementCtx, bound.SQL, bound.Parameters)
+		return timeoutErr(ctx, statementCtx, err)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return me.DB.NamedQuery(statement.SQL, statement.Parameters)
+	return result, nil
 }
 
-//QueryStatementContext records on database and return it as sqlx.Rows
-func (me *Client) QueryStatementContext(ctx context.Context, statement *Statement) (*sqlx.Rows, error) {
-	if me.transaction != nil && !me.transaction.IsComplete() {
-		return me.transaction.QueryStatementContext(ctx, statement)
-	}
-	return me.DB.NamedQueryContext(ctx, statement.SQL, statement.Parameters)
+//QueryStatement records on database and return it as Rows, within its timeout
+func (me *Client) QueryStatement(statement *Statement) (*Rows, error) {
+	return me.QueryStatementContext(context.Background(), statement)
 }
 
-//BeginTransaction begin a new transaction
-func (me *Client) BeginTransaction() (*Transaction, error) {
-	sqlxTx, err := me.DB.Beginx()
+//QueryStatementContext records on database and return it as Rows.
+//It runs in the transaction of the scope of ctx if there is one, see NewScope. It fails with
+//ErrTenantTransactionRequired if there is none while the tenancy strategy applies the tenant of ctx to transactions,
+//or with ErrLockRequiresTransaction if the statement is locking. It fails with ErrStatementTimeout if it runs longer
+//than its timeout, the rows are closed if they are read afterwards. Outside a transaction, it's guarded by the circuit
+//breaker, retried on transient errors if it's idempotent, see Statement.Idempotent, and reads from a replica if
+//the statement opts in, see Statement.FromReplica. It fails with ErrClientClosed once the client is shut down,
+//the rows must be closed for Shutdown not to wait for them
+func (me *Client) QueryStatementContext(ctx context.Context, statement *Statement) (*Rows, error) {
+	if transaction := scopeTransaction(ctx); transaction != nil {
+		return transaction.QueryStatementContext(ctx, statement)
+	}
+	if err := me.lifecycle.enter(); err != nil {
+		return nil, err
+	}
+	defer me.lifecycle.leave()
+	if me.tenantSetup(ctx) != nil {
+		return nil, ErrTenantTransactionRequired
+	}
+	if statement.IsLocking() {
+		return nil, ErrLockRequiresTransaction
+	}
+	db, err := me.reader(ctx, statement)
 	if err != nil {
 		return nil, err
 	}
-	newTransaction := &Transaction{
-		Tx: sqlxTx,
+	rows, err := me.query(ctx, db, statement)
+	if err != nil {
+		return nil, err
 	}
-	me.SetTransaction(newTransaction)
-	return newTransaction, nil
+	return me.lifecycle.track(rows), nil
 }
 
-//CompleteTransaction commit and reset current transaction
-func (me *Client) CompleteTransaction() error {
-	if me.transaction != nil && !me.transaction.isComplete {
-		err := me.transaction.Commit()
+//query queries the statement on the pool outside a transaction within its timeout, guarded by the circuit breaker
+//and retried on transient errors if it's idempotent
+func (me *Client) query(ctx context.Context, db *sqlx.DB, statement *Statement) (*Rows, error) {
+	bound, err := statement.Bind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var rows *Rows
+	err = me.resilient(ctx, statement, func() error {
+		statementCtx, cancel := statementContext(ctx, timeoutOf(statement, me.timeout))
+		queried, err := db.NamedQueryContext(statementCtx, bound.SQL, bound.Parameters)
 		if err != nil {
-			if rollbackError := me.transaction.Rollback(); rollbackError != nil {
-				return rollbackError
-			}
-			me.ResetTransaction()
-			return err
+			cancel()
+			return timeoutErr(ctx, statementCtx, err)
 		}
+		rows = newRows(queried, func() error {
+			cancel()
+			return nil
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	me.ResetTransaction()
-
-	return nil
+	return rows, nil
 }
 
-//GetTransaction return current transaction
-func (me *Client) GetTransaction() *Transaction {
-	return me.transaction
-}
-
-//SetTransaction set the dbclient to which transaction it's belong to
-func (me *Client) SetTransaction(transaction *Transaction) {
-	me.transaction = transaction
-	if transaction != nil {
-		me.IsUserDefinedTransaction = true
+//NewContext create new unit of work. A context is meant to be used by a single request or job and discarded
+func (me *Client) NewContext() *Context {
+	return &Context{
+		Client: me,
 	}
 }
 
-//SetTransactionScope set transaction to the specified context
-func (me *Context) SetTransactionScope(ctx context.Context) error {
-	newTransaction, err := NewTransaction(me.DB)
-	if err != nil {
-		return err
+//NewScope create new unit of work and returns ctx carrying it. Client methods given the returned context,
+//or a context derived from it, run in the transaction of the unit of work while it has one
+func (me *Client) NewScope(ctx context.Context) (context.Context, *Context) {
+	if ctx == nil {
+		ctx = context.Background()
 	}
-
-	ctx = context.WithValue(ctx, contextKey, newTransaction)
-	return nil
+	scope := me.NewContext()
+	return context.WithValue(ctx, contextKey, scope), scope
 }
 
-//GetTransactionScope get transaction from context
-func (me *Context) GetTransactionScope(ctx context.Context) *Transaction {
-	value := ctx.Value(contextKey)
-	if value != nil {
-		return value.(*Transaction)
+//ScopeFrom returns the unit of work carried by ctx, nil if there is none
+func ScopeFrom(ctx context.Context) *Context {
+	if ctx == nil {
+		return nil
 	}
-	return nil
+	scope, _ := ctx.Value(contextKey).(*Context)
+	return scope
 }
 
-//ResetTransaction set current transaction to nil
-func (me *Client) ResetTransaction() {
-	me.IsUserDefinedTransaction = false
-	me.SetTransaction(nil)
+//scopeTransaction returns the active transaction of the scope of ctx, nil if there is none
+func scopeTransaction(ctx context.Context) *Transaction {
+	scope := ScopeFrom(ctx)
+	if scope == nil {
+		return nil
+	}
+	transaction := scope.GetTransaction()
+	if transaction == nil || transaction.IsComplete() {
+		return nil
+	}
+	return transaction
 }
 
 //NewClient create new DB client instance
 func NewClient(db *sqlx.DB) *Client {
 	return &Client{
-		DB: db,
+		DB:           db,
+		transactions: newTransactionTracker(),
+		lifecycle:    newLifecycle(),
 	}
 }
@@ -1,7 +1,9 @@
 package dbx
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -9,7 +11,7 @@ import (
 )
 
 func Test_Client_ExecStatement(t *testing.T) {
-	db, err := getSqlxDb()
+	db, err := getSqlxDb(t)
 	defer db.Close()
 	if err != nil {
 		t.Errorf("Fatal create db error: %s", err.Error())
@@ -43,7 +45,7 @@ func Test_Client_ExecStatement(t *testing.T) {
 }
 
 func Test_Client_QueryStatement(t *testing.T) {
-	db, err := getSqlxDb()
+	db, err := getSqlxDb(t)
 	defer db.Close()
 	if err != nil {
 		t.Errorf("Fatal create db error: %s", err.Error())
@@ -94,88 +96,108 @@ func Test_Client_QueryStatement(t *testing.T) {
 	t.Error("Query result must have records.")
 }
 
-func Test_Client_BeginTransaction(t *testing.T) {
-	db, err := getSqlxDb()
+func Test_Client_NewClient(t *testing.T) {
+	db, err := getSqlxDb(t)
 	defer db.Close()
 	if err != nil {
 		t.Errorf("Fatal create db error: %s", err.Error())
 	}
 	dbClient := NewClient(db)
-	tx, err := dbClient.BeginTransaction()
-
-	if err != nil {
-		t.Errorf("Fatal begin transaction error: %s", err.Error())
+	if dbClient.DB == nil {
+		t.Errorf("Fatal error: dbClient.DB expected to be not <nil>")
 	}
+	fmt.Println("Test NewClient success")
+}
 
-	if tx == nil {
-		t.Errorf("Transaction expected to be not nil")
-	}
+func Test_Client_ConcurrentUnitsOfWork(t *testing.T) {
+	db := getSQLiteFileDb(t)
+	client := NewClient(db)
 
-	if dbClient.transaction == nil {
-		t.Errorf("Fatal transaction error: transaction expected to be not <nil>")
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dbContext := client.NewContext()
+			dbContext.AddStatements(newPersonStatement(fmt.Sprintf("Person %d", i)), newPersonStatement(fmt.Sprintf("Person %d'", i)))
+			if _, err := dbContext.SaveChanges(context.Background()); err != nil {
+				errs <- err
+				return
+			}
+			rows, err := client.QueryStatementContext(context.Background(), NewStatement("SELECT id FROM person"))
+			if err != nil {
+				errs <- err
+				return
+			}
+			rows.Close()
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Unit of work error: %s", err.Error())
+	}
+
+	if count := countPersons(t, db); count != 32 {
+		t.Errorf("Expected 32 persons, got %d", count)
 	}
-	dbClient.CompleteTransaction()
 }
 
-func Test_Client_GetTransaction(t *testing.T) {
-	db, err := getSqlxDb()
-	defer db.Close()
-	if err != nil {
-		t.Errorf("Fatal create db error: %s", err.Error())
-	}
-	dbClient := NewClient(db)
-	tx, err := dbClient.BeginTransaction()
+func Test_Client_NewScope(t *testing.T) {
+	db := getSQLiteFileDb(t)
+	client := NewClient(db)
 
+	if ScopeFrom(context.Background()) != nil {
+		t.Errorf("Expected no scope in background context")
+	}
+	ctx, scope := client.NewScope(context.Background())
+	if ScopeFrom(ctx) != scope {
+		t.Fatalf("Expected the scope to be carried by its context")
+	}
+	tx, err := scope.BeginTransaction()
 	if err != nil {
-		t.Errorf("Fatal begin transaction error: %s", err.Error())
+		t.Fatalf("BeginTransaction error: %s", err.Error())
 	}
 
-	if tx == nil {
-		t.Errorf("Fatal transaction error: transaction expected to be not <nil>")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := client.ExecStatementContext(ctx, newPersonStatement(fmt.Sprintf("Person %d", i))); err != nil {
+				t.Errorf("ExecStatementContext error: %s", err.Error())
+			}
+		}(i)
 	}
+	wg.Wait()
 
-	if dbClient.GetTransaction() == nil {
-		t.Errorf("Fatal transaction error: transaction expected to be not <nil>")
+	repositoryContext := client.NewContext()
+	repositoryContext.AddStatements(newPersonStatement("Dadang"), newPersonStatement("Asep"))
+	if _, err := repositoryContext.SaveChanges(ctx); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
 	}
-
-	fmt.Println("Test GetTransaction success")
-}
-
-func Test_Client_SetTransaction(t *testing.T) {
-	db, err := getSqlxDb()
-	defer db.Close()
-	if err != nil {
-		t.Errorf("Fatal create db error: %s", err.Error())
+	if tx.IsComplete() {
+		t.Fatalf("SaveChanges must not complete the transaction of the scope")
 	}
-	dbClient := NewClient(db)
-	tx, err := NewTransaction(db)
 
+	var count int
+	rows, err := client.QueryStatementContext(ctx, NewStatement("SELECT COUNT(*) FROM person"))
 	if err != nil {
-		t.Errorf("Fatal begin transaction error: %s", err.Error())
+		t.Fatalf("QueryStatementContext error: %s", err.Error())
 	}
-
-	if tx == nil {
-		t.Errorf("Fatal transaction error: transaction expected to be not <nil>")
+	for rows.Next() {
+		rows.Scan(&count)
 	}
-
-	dbClient.SetTransaction(tx)
-
-	if dbClient.GetTransaction() == nil {
-		t.Errorf("Fatal transaction error: transaction expected to be not <nil>")
+	rows.Close()
+	if count != 10 {
+		t.Errorf("Expected 10 persons in the scope transaction, got %d", count)
 	}
 
-	fmt.Println("Test SetTransaction success")
-}
-
-func Test_Client_NewClient(t *testing.T) {
-	db, err := getSqlxDb()
-	defer db.Close()
-	if err != nil {
-		t.Errorf("Fatal create db error: %s", err.Error())
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback error: %s", err.Error())
 	}
-	dbClient := NewClient(db)
-	if dbClient.DB == nil {
-		t.Errorf("Fatal error: dbClient.DB expected to be not <nil>")
+	if count := countPersons(t, db); count != 0 {
+		t.Errorf("Expected rolled back scope to leave no person, got %d", count)
 	}
-	fmt.Println("Test NewClient success")
 }
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/supendi/dbx"
+	"github.com/supendi/dbx/gen"
+	"github.com/supendi/dbx/migrate"
+)
+
+//entityDefinition represent an entity struct parsed from Go source
+type entityDefinition struct {
+	Struct string
+	Table  string
+	Fields []*fieldDefinition
+}
+
+//fieldDefinition represent an entity struct field
+type fieldDefinition struct {
+	Name    string
+	Column  string
+	Pointer bool
+	Slice   bool
+}
+
+//runDiff executes dbx diff and returns the number of differences found
+func runDiff(ctx context.Context, args []string, stdout, stderr io.Writer) (int, error) {
+	cfg := &config{}
+	flags := newFlagSet("diff", cfg, stderr)
+	dir := flags.String("entities", "entities", "directory of the entity package")
+	exclude := flags.String("exclude", migrate.DefaultTable, "comma separated tables to skip")
+	if err := flags.Parse(args); err != nil {
+		return 0, err
+	}
+
+	definitions, err := parseEntities(*dir)
+	if err != nil {
+		return 0, err
+	}
+	client, err := cfg.open(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+	tables, err := readTables(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+	tables = excludeTables(tables, strings.Split(*exclude, ","))
+
+	differences := diffEntities(tables, definitions)
+	for _, difference := range differences {
+		fmt.Fprintln(stdout, difference)
+	}
+	return len(differences), nil
+}
+
+//diffEntities returns the differences between the schema tables and the entity definitions
+func diffEntities(tables []*dbx.Table, definitions []*entityDefinition) []string {
+	differences := []string{}
+	mapped := map[string]bool{}
+	for _, definition := range definitions {
+		var t *dbx.Table
+		for _, candidate := range tables {
+			if candidate.Name == definition.Table {
+				t = candidate
+			}
+		}
+		if t == nil {
+			differences = append(differences, fmt.Sprintf("entity %s: table %q does not exist", definition.Struct, definition.Table))
+			continue
+		}
+		mapped[t.Name] = true
+
+		fieldColumns := map[string]bool{}
+		for _, f := range definition.Fields {
+			fieldColumns[f.Column] = true
+			col := t.Column(f.Column)
+			if col == nil {
+				differences = append(differences, fmt.Sprintf("entity %s: column %q of field %s does not exist in table %q", definition.Struct, f.Column, f.Name, t.Name))
+				continue
+			}
+			if col.Nullable && !f.Pointer && !f.Slice {
+				differences = append(differences, fmt.Sprintf("entity %s: column %q is nullable but field %s is not a pointer", definition.Struct, f.Column, f.Name))
+			}
+		}
+		for _, col := range t.Columns {
+			if !fieldColumns[col.Name] {
+				differences = append(differences, fmt.Sprintf("table %q: column %q is not mapped by entity %s", t.Name, col.Name, definition.Struct))
+			}
+		}
+	}
+	for _, t := range tables {
+		if !mapped[t.Name] {
+			differences = append(differences, fmt.Sprintf("table %q: no entity", t.Name))
+		}
+	}
+	return differences
+}
+
+//parseEntities parses entity structs of the package in dir, test files aside. A struct is an entity when it has a
+//TableName method, db tagged fields or a repository, a struct named after it with the Repository suffix
+func parseEntities(dir string) ([]*entityDefinition, error) {
+	fileSet := token.NewFileSet()
+	notTest := func(info fs.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}
+	packages, err := parser.ParseDir(fileSet, dir, notTest, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	structs := map[string]*ast.StructType{}
+	tableNames := map[string]string{}
+	for _, pkg := range packages {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				switch decl := decl.(type) {
+				case *ast.GenDecl:
+					for _, spec := range decl.Specs {
+						if typeSpec, ok := spec.(*ast.TypeSpec); ok {
+							if structType, ok := typeSpec.Type.(*ast.StructType); ok {
+								structs[typeSpec.Name.Name] = structType
+							}
+						}
+					}
+				case *ast.FuncDecl:
+					if receiver, name, ok := tableNameMethod(decl); ok {
+						tableNames[receiver] = name
+					}
+				}
+			}
+		}
+	}
+
+	definitions := []*entityDefinition{}
+	for name, structType := range structs {
+		definition := &entityDefinition{Struct: name, Table: tableNames[name]}
+		tagged := false
+		for _, astField := range structType.Fields.List {
+			column := ""
+			if astField.Tag != nil {
+				tag, _ := strconv.Unquote(astField.Tag.Value)
+				column = strings.Split(reflect.StructTag(tag).Get("db"), ",")[0]
+				tagged = tagged || column != ""
+			}
+			if column == "-" {
+				continue
+			}
+			for _, ident := range astField.Names {
+				if !ident.IsExported() {
+					continue
+				}
+				f := &fieldDefinition{Name: ident.Name, Column: column}
+				if f.Column == "" {
+					f.Column = gen.ColumnName(ident.Name)
+				}
+				_, f.Pointer = astField.Type.(*ast.StarExpr)
+				_, f.Slice = astField.Type.(*ast.ArrayType)
+				definition.Fields = append(definition.Fields, f)
+			}
+		}
+		_, repository := structs[name+"Repository"]
+		if definition.Table == "" && !tagged && !repository {
+			continue
+		}
+		if definition.Table == "" {
+			definition.Table = gen.ColumnName(name)
+		}
+		definitions = append(definitions, definition)
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Struct < definitions[j].Struct
+	})
+	return definitions, nil
+}
+
+//tableNameMethod returns the receiver and the returned literal of a TableName() string method
+func tableNameMethod(decl *ast.FuncDecl) (string, string, bool) {
+	if decl.Name.Name != "TableName" || decl.Recv == nil || len(decl.Recv.List) != 1 || decl.Body == nil || len(decl.Body.List) != 1 {
+		return "", "", false
+	}
+	receiverType := decl.Recv.List[0].Type
+	if star, ok := receiverType.(*ast.StarExpr); ok {
+		receiverType = star.X
+	}
+	receiver, ok := receiverType.(*ast.Ident)
+	if !ok {
+		return "", "", false
+	}
+	ret, ok := decl.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return "", "", false
+	}
+	literal, ok := ret.Results[0].(*ast.BasicLit)
+	if !ok || literal.Kind != token.STRING {
+		return "", "", false
+	}
+	name, err := strconv.Unquote(literal.Value)
+	if err != nil {
+		return "", "", false
+	}
+	return receiver.Name, name, true
+}
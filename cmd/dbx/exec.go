@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/supendi/dbx"
+)
+
+//queryPattern matches statements which return rows
+var queryPattern = regexp.MustCompile(`(?is)^\s*(select|with|show|pragma|values|explain)\b|\breturning\b`)
+
+//paramFlags collects repeated -param name=value flags
+type paramFlags map[string]interface{}
+
+//String implements flag.Value
+func (me paramFlags) String() string {
+	return fmt.Sprint(map[string]interface{}(me))
+}
+
+//Set implements flag.Value
+func (me paramFlags) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("parameter must be written as name=value, got %q", value)
+	}
+	me[parts[0]] = parts[1]
+	return nil
+}
+
+//runExec executes dbx exec
+func runExec(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	cfg := &config{}
+	flags := newFlagSet("exec", cfg, stderr)
+	file := flags.String("file", "", "statement file, - reads standard input")
+	jsonParams := flags.String("params", "", "JSON object of parameters, @path reads it from a file")
+	params := paramFlags{}
+	flags.Var(params, "param", "statement parameter as name=value, can be repeated")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		fmt.Fprintln(stderr, "usage: dbx exec -file statement.sql [flags]")
+		return errUsage
+	}
+
+	sql, err := readInput(*file)
+	if err != nil {
+		return err
+	}
+	statement := dbx.NewStatement(string(sql))
+	if *jsonParams != "" {
+		content := []byte(*jsonParams)
+		if strings.HasPrefix(*jsonParams, "@") {
+			content, err = readInput(strings.TrimPrefix(*jsonParams, "@"))
+			if err != nil {
+				return err
+			}
+		}
+		decoded := map[string]interface{}{}
+		if err := json.Unmarshal(content, &decoded); err != nil {
+			return fmt.Errorf("invalid params: %v", err)
+		}
+		for name, value := range decoded {
+			statement.AddParameter(name, value)
+		}
+	}
+	for name, value := range params {
+		statement.AddParameter(name, value)
+	}
+
+	client, err := cfg.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if !queryPattern.MatchString(statement.SQL) {
+		result, err := client.ExecStatementContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%d row(s) affected\n", affected)
+		return nil
+	}
+
+	rows, err := client.QueryStatementContext(ctx, statement)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return err
+		}
+		line, err := encodeRow(columns, values)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(line))
+	}
+	return rows.Err()
+}
+
+//encodeRow encodes the row as JSON object keeping the column order
+func encodeRow(columns []string, values []interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, column := range columns {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		name, _ := json.Marshal(column)
+		value := values[i]
+		if raw, ok := value.([]byte); ok {
+			value = string(raw)
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(name)
+		buffer.WriteByte(':')
+		buffer.Write(encoded)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+//readInput reads a file, - reads standard input
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
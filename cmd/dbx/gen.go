@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/supendi/dbx"
+	"github.com/supendi/dbx/gen"
+	"github.com/supendi/dbx/migrate"
+)
+
+//runGen executes dbx gen entities
+func runGen(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "entities" {
+		fmt.Fprintln(stderr, "usage: dbx gen entities [flags]")
+		return errUsage
+	}
+
+	cfg := &config{}
+	flags := newFlagSet("gen entities", cfg, stderr)
+	out := flags.String("out", "entities", "output directory")
+	packageName := flags.String("package", "", "package name, defaults to the output directory name")
+	ddl := flags.String("ddl", "", "DDL file to generate from instead of a live database")
+	exclude := flags.String("exclude", migrate.DefaultTable, "comma separated tables to skip")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *packageName == "" {
+		*packageName = filepath.Base(*out)
+	}
+
+	tables, driverName, err := loadTables(ctx, cfg, *ddl)
+	if err != nil {
+		return err
+	}
+	tables = excludeTables(tables, strings.Split(*exclude, ","))
+
+	files, err := gen.NewGenerator(*packageName).WithDriver(driverName).Write(*out, tables)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.Changed {
+			fmt.Fprintln(stdout, "generated", file.Path)
+		}
+	}
+	return nil
+}
+
+//loadTables introspects the live database, or the DDL file when it is set. It returns the tables and the driver of
+//the database
+func loadTables(ctx context.Context, cfg *config, ddl string) ([]*dbx.Table, string, error) {
+	if ddl == "" {
+		client, err := cfg.open(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		defer client.Close()
+		tables, err := readTables(ctx, client)
+		return tables, client.DriverName(), err
+	}
+
+	content, err := os.ReadFile(ddl)
+	if err != nil {
+		return nil, "", err
+	}
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, "", err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, string(content)); err != nil {
+		return nil, "", fmt.Errorf("load %s: %v", ddl, err)
+	}
+	tables, err := readTables(ctx, dbx.NewClient(db))
+	return tables, db.DriverName(), err
+}
+
+//readTables returns the tables of the connected database
+func readTables(ctx context.Context, client *dbx.Client) ([]*dbx.Table, error) {
+	schema, err := client.Schema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Tables, nil
+}
+
+//excludeTables returns tables whose name is not excluded
+func excludeTables(tables []*dbx.Table, excluded []string) []*dbx.Table {
+	filtered := []*dbx.Table{}
+	for _, t := range tables {
+		skip := false
+		for _, name := range excluded {
+			if strings.TrimSpace(name) == t.Name {
+				skip = true
+			}
+		}
+		if !skip {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,110 @@
+//Command dbx manages migrations, generates entities and runs ad-hoc statements.
+//
+//	dbx migrate up|down|status|to <version> [-dir migrations]
+//	dbx gen entities [-out entities] [-package entities] [-ddl schema.sql]
+//	dbx exec -file statement.sql [-param name=value]... [-params '{"name": "value"}']
+//	dbx diff [-entities entities]
+//
+//Every command accepts -driver and -dsn flags, which default to DBX_DRIVER and DBX_DSN environment variables.
+//
+//diff compares the schema with the entity structs of the package, those with a TableName method, db tagged fields
+//or a repository struct named after them with the Repository suffix. Fields without db tag map to snake case columns.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	_ "github.com/lib/pq"           //postgres driver
+	_ "github.com/mattn/go-sqlite3" //sqlite driver
+	"github.com/supendi/dbx"
+)
+
+const usage = `usage: dbx <command> [arguments]
+
+commands:
+  migrate up|down|status|to <version>   apply, revert or list migrations
+  gen entities                          generate entities and repositories from the schema
+  exec                                  run a statement file with named parameters
+  diff                                  compare the schema with entity definitions
+
+run "dbx <command> -h" for the command flags
+`
+
+//errUsage is returned when the command line is invalid, usage has been printed already
+var errUsage = errors.New("invalid usage")
+
+//config represent the connection settings shared by every command
+type config struct {
+	driver string
+	dsn    string
+}
+
+//register adds the connection flags to the flag set
+func (me *config) register(flags *flag.FlagSet) {
+	flags.StringVar(&me.driver, "driver", os.Getenv("DBX_DRIVER"), "database driver: postgres or sqlite3 (env DBX_DRIVER), guessed from the dsn if empty")
+	flags.StringVar(&me.dsn, "dsn", os.Getenv("DBX_DSN"), "data source name (env DBX_DSN)")
+}
+
+//open connects to the configured database
+func (me *config) open(ctx context.Context) (*dbx.Client, error) {
+	if me.dsn == "" {
+		return nil, errors.New("data source name is required, set -dsn flag or DBX_DSN environment variable")
+	}
+	return dbx.Open(ctx, dbx.Config{Driver: me.driver, DSN: me.dsn, ConnectAttempts: 1})
+}
+
+//newFlagSet returns a flag set with connection flags which writes its usage to stderr
+func newFlagSet(name string, cfg *config, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	cfg.register(flags)
+	return flags
+}
+
+//run executes the command line and returns the process exit code
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "migrate":
+		err = runMigrate(ctx, args[1:], stdout, stderr)
+	case "gen":
+		err = runGen(ctx, args[1:], stdout, stderr)
+	case "exec":
+		err = runExec(ctx, args[1:], stdout, stderr)
+	case "diff":
+		var differences int
+		differences, err = runDiff(ctx, args[1:], stdout, stderr)
+		if err == nil && differences > 0 {
+			return 1
+		}
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "dbx: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "dbx %s: %s\n", args[0], err.Error())
+		return 1
+	}
+	return 0
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//runCommand runs the command line against the sqlite database of dsn
+func runCommand(t *testing.T, dsn string, args ...string) (string, int) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), append(args, "-dsn", dsn), &stdout, &stderr)
+	if stderr.Len() > 0 {
+		t.Logf("dbx %s: %s", strings.Join(args, " "), stderr.String())
+	}
+	return stdout.String(), code
+}
+
+//writeFiles writes the files into dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Write %s error: %s", name, err.Error())
+		}
+	}
+}
+
+func newTestWorkspace(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "migrations"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, filepath.Join(dir, "migrations"), map[string]string{
+		"0001_create_order.up.sql":   `CREATE TABLE "order" (id TEXT PRIMARY KEY, order_number TEXT NOT NULL, order_date TIMESTAMP NOT NULL, total NUMERIC NOT NULL, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NULL);`,
+		"0001_create_order.down.sql": `DROP TABLE "order";`,
+		"0002_create_users.up.sql":   `CREATE TABLE users (id INTEGER PRIMARY KEY, full_name TEXT NOT NULL, email TEXT);`,
+		"0002_create_users.down.sql": `DROP TABLE users;`,
+	})
+	return dir, filepath.Join(dir, "dbx.db")
+}
+
+func Test_Run_Migrate(t *testing.T) {
+	dir, dsn := newTestWorkspace(t)
+	migrations := filepath.Join(dir, "migrations")
+
+	output, code := runCommand(t, dsn, "migrate", "up", "-dir", migrations)
+	if code != 0 {
+		t.Fatalf("migrate up exit code %d", code)
+	}
+	if strings.Count(output, "applied") != 2 {
+		t.Errorf("Both migrations expected to be applied, got:\n%s", output)
+	}
+
+	output, code = runCommand(t, dsn, "migrate", "down", "-dir", migrations)
+	if code != 0 || !strings.Contains(output, "create_users  pending") {
+		t.Errorf("migrate down expected to revert create_users, exit code %d:\n%s", code, output)
+	}
+
+	output, code = runCommand(t, dsn, "migrate", "to", "0", "-dir", migrations)
+	if code != 0 || strings.Contains(output, "applied") {
+		t.Errorf("migrate to 0 expected to revert all migrations, exit code %d:\n%s", code, output)
+	}
+
+	if _, code = runCommand(t, dsn, "migrate", "sideways", "-dir", migrations); code != 2 {
+		t.Errorf("Unknown migrate action expected to exit with 2, got %d", code)
+	}
+	unreachable := filepath.Join(dir, "missing", "dbx.db")
+	if _, code = runCommand(t, unreachable, "migrate", "sideways", "-dir", migrations); code != 2 {
+		t.Errorf("Unknown migrate action expected to be rejected before connecting, got %d", code)
+	}
+}
+
+func Test_Run_Exec(t *testing.T) {
+	dir, dsn := newTestWorkspace(t)
+	if _, code := runCommand(t, dsn, "migrate", "up", "-dir", filepath.Join(dir, "migrations")); code != 0 {
+		t.Fatalf("migrate up exit code %d", code)
+	}
+	writeFiles(t, dir, map[string]string{
+		"insert.sql": "INSERT INTO users (id, full_name, email) VALUES (:id, :full_name, :email)",
+		"select.sql": "SELECT id, full_name, email FROM users WHERE full_name = :full_name",
+	})
+
+	output, code := runCommand(t, dsn, "exec", "-file", filepath.Join(dir, "insert.sql"), "-params", `{"id": 1, "email": null}`, "-param", "full_name=Andi Setiawan")
+	if code != 0 || output != "1 row(s) affected\n" {
+		t.Fatalf("exec insert exit code %d:\n%s", code, output)
+	}
+
+	output, code = runCommand(t, dsn, "exec", "-file", filepath.Join(dir, "select.sql"), "-param", "full_name=Andi Setiawan")
+	if code != 0 || output != `{"id":1,"full_name":"Andi Setiawan","email":null}`+"\n" {
+		t.Errorf("exec select exit code %d:\n%s", code, output)
+	}
+}
+
+func Test_Run_GenEntitiesAndDiff(t *testing.T) {
+	dir, dsn := newTestWorkspace(t)
+	if _, code := runCommand(t, dsn, "migrate", "up", "-dir", filepath.Join(dir, "migrations")); code != 0 {
+		t.Fatalf("migrate up exit code %d", code)
+	}
+	out := filepath.Join(dir, "entities")
+
+	if _, code := runCommand(t, dsn, "gen", "entities", "-out", out); code != 0 {
+		t.Fatalf("gen entities exit code %d", code)
+	}
+	order, err := os.ReadFile(filepath.Join(out, "order.go"))
+	if err != nil {
+		t.Fatalf("order.go expected to be generated: %s", err.Error())
+	}
+	for _, expected := range []string{
+		"package entities",
+		"OrderNumber string     `db:\"order_number\"`",
+		"UpdatedAt   *time.Time `db:\"updated_at\"`",
+		"func (me *OrderRepository) Delete(id string)",
+		"`DELETE FROM \"order\" WHERE id = :id`",
+	} {
+		if !strings.Contains(string(order), expected) {
+			t.Errorf("order.go expected to contain %q:\n%s", expected, order)
+		}
+	}
+	dbContext, err := os.ReadFile(filepath.Join(out, "dbcontext.go"))
+	if err != nil || !strings.Contains(string(dbContext), "User:    NewUserRepository(dbContext)") {
+		t.Errorf("dbcontext.go expected to aggregate the repositories:\n%s", dbContext)
+	}
+
+	writeFiles(t, out, map[string]string{
+		"fixture_test.go": "package entities\n\ntype Fixture struct {\n\tName string `db:\"name\"`\n}\n",
+	})
+	output, code := runCommand(t, dsn, "diff", "-entities", out)
+	if code != 0 || output != "" {
+		t.Errorf("diff of freshly generated entities expected to be empty, exit code %d:\n%s", code, output)
+	}
+
+	writeFiles(t, dir, map[string]string{"alter.sql": "ALTER TABLE users ADD COLUMN phone TEXT"})
+	if _, code := runCommand(t, dsn, "exec", "-file", filepath.Join(dir, "alter.sql")); code != 0 {
+		t.Fatalf("exec alter exit code %d", code)
+	}
+	output, code = runCommand(t, dsn, "diff", "-entities", out)
+	if code != 1 || output != `table "users": column "phone" is not mapped by entity User`+"\n" {
+		t.Errorf("diff expected to report the new column, exit code %d:\n%s", code, output)
+	}
+}
+
+func Test_Run_DiffExampleEntities(t *testing.T) {
+	dir, dsn := newTestWorkspace(t)
+	if _, code := runCommand(t, dsn, "migrate", "up", "-dir", filepath.Join(dir, "migrations")); code != 0 {
+		t.Fatalf("migrate up exit code %d", code)
+	}
+
+	output, code := runCommand(t, dsn, "diff", "-entities", filepath.Join("..", "..", "examples", "entities"))
+	if code != 1 || output != `table "users": no entity`+"\n" {
+		t.Errorf("diff expected to map the example order entity by its repository, exit code %d:\n%s", code, output)
+	}
+}
+
+func Test_Run_GenEntitiesFromDDL(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"schema.sql": `CREATE TABLE categories (id INTEGER PRIMARY KEY, name TEXT NOT NULL, parent_id INTEGER);`,
+	})
+	out := filepath.Join(dir, "model")
+
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"gen", "entities", "-out", out, "-ddl", filepath.Join(dir, "schema.sql")}, &stdout, &stderr); code != 0 {
+		t.Fatalf("gen entities from ddl exit code %d: %s", code, stderr.String())
+	}
+	source, err := os.ReadFile(filepath.Join(out, "categories.go"))
+	if err != nil {
+		t.Fatalf("categories.go expected to be generated: %s", err.Error())
+	}
+	if !strings.Contains(string(source), "package model") || !strings.Contains(string(source), "type Category struct") || !strings.Contains(string(source), "ParentID *int64 `db:\"parent_id\"`") {
+		t.Errorf("Unexpected generated source:\n%s", source)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/supendi/dbx/migrate"
+)
+
+//runMigrate executes dbx migrate up|down|status|to <version>
+func runMigrate(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: dbx migrate up|down|status|to <version> [flags]")
+		return errUsage
+	}
+	action, args := args[0], args[1:]
+	switch action {
+	case "up", "down", "to", "status":
+	default:
+		fmt.Fprintf(stderr, "dbx migrate: unknown action %q\n", action)
+		return errUsage
+	}
+
+	var version int64
+	if action == "to" {
+		if len(args) == 0 {
+			fmt.Fprintln(stderr, "usage: dbx migrate to <version> [flags]")
+			return errUsage
+		}
+		parsed, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		version, args = parsed, args[1:]
+	}
+
+	cfg := &config{}
+	flags := newFlagSet("migrate "+action, cfg, stderr)
+	dir := flags.String("dir", "migrations", "directory containing <version>_<name>.up.sql and .down.sql files")
+	table := flags.String("table", migrate.DefaultTable, "table recording applied migrations")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	migrations, err := migrate.Load(os.DirFS(*dir), ".")
+	if err != nil {
+		return err
+	}
+	client, err := cfg.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	migrator := migrate.NewMigrator(client, migrations).WithTable(*table)
+
+	switch action {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "to":
+		err = migrator.To(ctx, version)
+	case "status":
+		return printStatus(ctx, migrator, stdout)
+	}
+	if err != nil {
+		return err
+	}
+	return printStatus(ctx, migrator, stdout)
+}
+
+//printStatus writes the migration status table
+func printStatus(ctx context.Context, migrator *migrate.Migrator, stdout io.Writer) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.Applied {
+			state = "applied"
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if status.Modified {
+			state = "modified"
+		}
+		if status.Missing {
+			state = "missing"
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	return writer.Flush()
+}
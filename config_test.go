@@ -0,0 +1,165 @@
+package dbx
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_Config_DataSourceName(t *testing.T) {
+	tests := []struct {
+		config   Config
+		expected string
+	}{
+		{Config{DSN: "host=db"}, "host=db"},
+		{Config{Host: "db", Port: 5432, User: "app", Password: "it's secret", Database: "shop", SSLMode: "verify-full", SSLRootCert: "/etc/ca.pem", Params: map[string]string{"application_name": "api"}},
+			`host=db port=5432 user=app password='it\'s secret' dbname=shop sslmode=verify-full sslrootcert=/etc/ca.pem application_name=api`},
+		{Config{Driver: "mysql", Host: "db", Port: 3306, User: "app", Password: "secret", Database: "shop", Params: map[string]string{"parseTime": "true", "tls": "custom"}},
+			"app:secret@tcp(db:3306)/shop?parseTime=true&tls=custom"},
+		{Config{Driver: "sqlite3", Database: "file:shop.db", Params: map[string]string{"_busy_timeout": "5000"}}, "file:shop.db?_busy_timeout=5000"},
+	}
+	for _, test := range tests {
+		dsn, err := test.config.DataSourceName()
+		if err != nil {
+			t.Fatalf("DataSourceName error: %s", err.Error())
+		}
+		if dsn != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, dsn)
+		}
+	}
+
+	if _, err := (Config{Driver: "mysql", SSLCert: "/etc/cert.pem"}).DataSourceName(); err == nil {
+		t.Error("Expected TLS files to be rejected by mysql")
+	}
+	if driver := (Config{DSN: "/var/lib/shop.sqlite"}).DriverName(); driver != "sqlite3" {
+		t.Errorf("Expected sqlite3 driver, got %s", driver)
+	}
+}
+
+func Test_ParseURL(t *testing.T) {
+	config, err := ParseURL("postgres://app:secret@db:5432/shop?sslmode=require&dbx_max_open_conns=20&dbx_conn_max_lifetime=30m&dbx_replicas=host=r1,host=r2")
+	if err != nil {
+		t.Fatalf("ParseURL error: %s", err.Error())
+	}
+	if config.Driver != "postgres" || config.DSN != "postgres://app:secret@db:5432/shop?sslmode=require" {
+		t.Errorf("Expected the postgres URL without dbx parameters, got %s %s", config.Driver, config.DSN)
+	}
+	if config.MaxOpenConns != 20 || config.ConnMaxLifetime != 30*time.Minute || len(config.Replicas) != 2 {
+		t.Errorf("Expected the dbx parameters to be set, got %+v", config)
+	}
+
+	config, err = ParseURL("mysql://app:secret@db:3306/shop?parseTime=true")
+	if err != nil {
+		t.Fatalf("ParseURL error: %s", err.Error())
+	}
+	if dsn, _ := config.DataSourceName(); dsn != "app:secret@tcp(db:3306)/shop?parseTime=true" {
+		t.Errorf("Expected the mysql data source name, got %s", dsn)
+	}
+
+	config, err = ParseURL("sqlite3:///tmp/shop.db?_busy_timeout=5000")
+	if err != nil {
+		t.Fatalf("ParseURL error: %s", err.Error())
+	}
+	if dsn, _ := config.DataSourceName(); config.Driver != "sqlite3" || dsn != "/tmp/shop.db?_busy_timeout=5000" {
+		t.Errorf("Expected the sqlite data source name, got %s", dsn)
+	}
+
+	if _, err := ParseURL("redis://db"); err == nil {
+		t.Error("Expected an unsupported scheme error")
+	}
+	if _, err := ParseURL("postgres://db?dbx_max_open_conns=many"); err == nil {
+		t.Error("Expected an invalid setting error")
+	}
+}
+
+func Test_ConfigFromEnv(t *testing.T) {
+	t.Setenv("SHOPDB_URL", "postgres://app@db/shop?dbx_statement_timeout=5s")
+	t.Setenv("SHOPDB_MAX_IDLE_CONNS", "4")
+	t.Setenv("SHOPDB_STATEMENT_TIMEOUT", "2s")
+	t.Setenv("SHOPDB_RETRY_ATTEMPTS", "5")
+	t.Setenv("SHOPDB_LOCAL_STATEMENT_TIMEOUT", "true")
+
+	config, err := ConfigFromEnv("SHOPDB")
+	if err != nil {
+		t.Fatalf("ConfigFromEnv error: %s", err.Error())
+	}
+	if config.DSN != "postgres://app@db/shop" || config.MaxIdleConns != 4 || config.StatementTimeout != 2*time.Second || !config.LocalStatementTimeout {
+		t.Errorf("Expected the variables to override the URL, got %+v", config)
+	}
+	if config.Retry == nil || config.Retry.Attempts != 5 {
+		t.Errorf("Expected 5 retry attempts, got %+v", config.Retry)
+	}
+
+	t.Setenv("SHOPDB_PORT", "fifty")
+	if _, err := ConfigFromEnv("SHOPDB"); err == nil || err.Error() != `SHOPDB_PORT: setting port: strconv.Atoi: parsing "fifty": invalid syntax` {
+		t.Errorf("Expected the variable to be named by the error, got %v", err)
+	}
+}
+
+func Test_Open(t *testing.T) {
+	dir := t.TempDir()
+	replica := filepath.Join(dir, "replica.db")
+	config := Config{
+		Driver:           "sqlite3",
+		Database:         filepath.Join(dir, "primary.db"),
+		Replicas:         []string{replica},
+		MaxOpenConns:     4,
+		StatementTimeout: time.Second,
+		BreakerThreshold: 3,
+	}
+	client, err := Open(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Open error: %s", err.Error())
+	}
+	defer client.Close()
+	if client.DB.Stats().MaxOpenConnections != 4 || client.timeout != time.Second || client.breaker == nil {
+		t.Errorf("Expected the client to be configured, got %+v", client)
+	}
+
+	if _, err := client.ExecStatement(NewStatement("CREATE TABLE origin (name TEXT)")); err != nil {
+		t.Fatalf("Create table error: %s", err.Error())
+	}
+	client.replicas[0].MustExec("CREATE TABLE origin (name TEXT)")
+	client.replicas[0].MustExec("INSERT INTO origin VALUES ('replica')")
+
+	names, err := Select[struct{ Name string }](context.Background(), client, NewStatement("SELECT name FROM origin").FromReplica())
+	if err != nil {
+		t.Fatalf("Select error: %s", err.Error())
+	}
+	if len(names) != 1 || names[0].Name != "replica" {
+		t.Errorf("Expected to read from the replica, got %+v", names)
+	}
+	names, err = Select[struct{ Name string }](context.Background(), client, NewStatement("SELECT name FROM origin"))
+	if err != nil {
+		t.Fatalf("Select error: %s", err.Error())
+	}
+	if len(names) != 0 {
+		t.Errorf("Expected to read from the primary, got %+v", names)
+	}
+}
+
+func Test_Open_PingBackoff(t *testing.T) {
+	attempts := 0
+	config := Config{
+		DSN:             "host=127.0.0.1 port=1 user=dbx sslmode=disable connect_timeout=1",
+		ConnectAttempts: 3,
+		ConnectBackoff: func(attempt int) time.Duration {
+			attempts = attempt
+			return time.Millisecond
+		},
+	}
+	if _, err := Open(context.Background(), config); err == nil {
+		t.Fatal("Expected the unreachable database to fail")
+	}
+	if attempts != 2 {
+		t.Errorf("Expected to back off twice, got %d", attempts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	config.ConnectBackoff = func(attempt int) time.Duration { return time.Hour }
+	if _, err := Open(ctx, config); err == nil {
+		t.Fatal("Expected the canceled context to fail")
+	}
+}
@@ -3,59 +3,185 @@ package dbx
 import (
 	"context"
 	"database/sql"
-
-	"github.com/jmoiron/sqlx"
+	"errors"
+	"sync"
 )
 
 type txContextKey string
 
-const contextKey txContextKey = "transaction"
+const contextKey txContextKey = "scope"
 
 //Transactioner TODO: must be added some more signatures
 //Transactioner interface for dbclient
 type Transactioner interface {
 	ExecStatement(statement *Statement) (sql.Result, error)
-	QueryStatement(statement *Statement) (*sqlx.Rows, error)
+	QueryStatement(statement *Statement) (*Rows, error)
 	ExecStatementContext(ctx context.Context, statement *Statement) (sql.Result, error)
-	QueryStatementContext(ctx context.Context, statement *Statement) (*sqlx.Rows, error)
+	QueryStatementContext(ctx context.Context, statement *Statement) (*Rows, error)
 	Rollback() error
 	Commit() error
 }
 
-//Context is dbclient too but wrap on or more statements.
+//Querier is implemented by Client, Context and Transaction
+type Querier interface {
+	QueryStatementContext(ctx context.Context, statement *Statement) (*Rows, error)
+}
+
+//Context is a unit of work, it defers statements until SaveChanges and holds the transaction they run in.
+//A context is meant to be used by a single request or job, though its methods are safe for concurrent use.
+//Create one per unit of work with Client.NewContext or Client.NewScope
 type Context struct {
-	Client
-	Statements []*Statement
+	*Client
+	mutex          sync.Mutex
+	statements     []*Statement
+	transaction    *Transaction
+	multipleShards bool
 }
 
 //AddStatement add new statement to context
 func (me *Context) AddStatement(statement *Statement) {
-	me.Statements = append(me.Statements, statement)
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.statements = append(me.statements, statement)
 }
 
 //AddStatements add statements to context
 func (me *Context) AddStatements(statements ...*Statement) {
-	for _, statement := range statements {
-		me.Statements = append(me.Statements, statement)
-	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.statements = append(me.statements, statements...)
+}
+
+//Statements returns the deferred statements
+func (me *Context) Statements() []*Statement {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return append([]*Statement{}, me.statements...)
 }
 
 //ClearStatements clear current statements
 func (me *Context) ClearStatements() {
-	me.Statements = nil
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.statements = nil
 }
 
 //MustUseTransaction check if the context should use transaction or not
 func (me *Context) MustUseTransaction() bool {
-	return len(me.Statements) > 1 || me.transaction != nil
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return len(me.statements) > 1 || me.transaction != nil
+}
+
+//BeginTransaction begin a new transaction, statements of the context run in it until it's completed
+func (me *Context) BeginTransaction() (*Transaction, error) {
+	return me.BeginTransactionContext(context.Background())
+}
+
+//BeginTransactionContext begin a new transaction applying the tenant of ctx according to the tenancy strategy,
+//statements of the context run in it until it's completed. It fails with ErrClientClosed once the client is shut down
+func (me *Context) BeginTransactionContext(ctx context.Context) (*Transaction, error) {
+	if err := me.lifecycle.enter(); err != nil {
+		return nil, err
+	}
+	defer me.lifecycle.leave()
+	newTransaction, err := me.newTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	me.SetTransaction(newTransaction)
+	return newTransaction, nil
+}
+
+//CompleteTransaction commit and reset current transaction
+func (me *Context) CompleteTransaction() error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	defer me.resetTransaction()
+
+	if me.transaction != nil && !me.transaction.IsComplete() {
+		err := me.transaction.Commit()
+		if err != nil {
+			if rollbackError := me.transaction.Rollback(); rollbackError != nil {
+				return rollbackError
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+//GetTransaction return current transaction
+func (me *Context) GetTransaction() *Transaction {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.transaction
+}
+
+//SetTransaction set the transaction statements of the context run in
+func (me *Context) SetTransaction(transaction *Transaction) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.transaction = transaction
+}
+
+//ResetTransaction set current transaction to nil
+func (me *Context) ResetTransaction() {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.resetTransaction()
+}
+
+//resetTransaction set current transaction to nil, the mutex must be held
+func (me *Context) resetTransaction() {
+	me.transaction = nil
+}
+
+//ExecStatement create, update or delete statement, in the transaction of the context if there is one
+func (me *Context) ExecStatement(statement *Statement) (sql.Result, error) {
+	if transaction := me.GetTransaction(); transaction != nil {
+		return transaction.ExecStatement(statement)
+	}
+	return me.Client.ExecStatement(statement)
+}
+
+//ExecStatementContext create, update or delete statement, in the transaction of the context if there is one
+func (me *Context) ExecStatementContext(ctx context.Context, statement *Statement) (sql.Result, error) {
+	if transaction := me.GetTransaction(); transaction != nil {
+		return transaction.ExecStatementContext(ctx, statement)
+	}
+	return me.Client.ExecStatementContext(ctx, statement)
+}
+
+//QueryStatement records on database and return it as Rows, in the transaction of the context if there is one
+func (me *Context) QueryStatement(statement *Statement) (*Rows, error) {
+	if transaction := me.GetTransaction(); transaction != nil && !transaction.IsComplete() {
+		return transaction.QueryStatement(statement)
+	}
+	return me.Client.QueryStatement(statement)
 }
 
-//execUseTransaction execute all deferred statements by using transaction
+//QueryStatementContext records on database and return it as Rows, in the transaction of the context if there is one
+func (me *Context) QueryStatementContext(ctx context.Context, statement *Statement) (*Rows, error) {
+	if transaction := me.GetTransaction(); transaction != nil && !transaction.IsComplete() {
+		return transaction.QueryStatementContext(ctx, statement)
+	}
+	return me.Client.QueryStatementContext(ctx, statement)
+}
+
+//execUseTransaction execute all deferred statements by using transaction, the transaction is rolled back on failure.
+//Changes of audited statements are recorded in the transaction
 func (me *Context) execUseTransaction(ctx context.Context, transactioner Transactioner, statements []*Statement) ([]sql.Result, error) {
 	var saveResults []sql.Result
 
 	for _, statement := range statements {
-		result, err := transactioner.ExecStatementContext(ctx, statement)
+		var result sql.Result
+		var err error
+		if me.isAudited(statement) {
+			result, err = me.execAudited(ctx, transactioner, statement)
+		} else {
+			result, err = transactioner.ExecStatementContext(ctx, statement)
+		}
 		if err != nil {
 			if rollbackError := transactioner.Rollback(); rollbackError != nil {
 				return nil, rollbackError
@@ -64,9 +190,6 @@ func (me *Context) execUseTransaction(ctx context.Context, transactioner Transac
 		}
 		saveResults = append(saveResults, result)
 	}
-	if !me.IsUserDefinedTransaction {
-		me.CompleteTransaction()
-	}
 
 	return saveResults, nil
 }
@@ -75,8 +198,14 @@ func (me *Context) execUseTransaction(ctx context.Context, transactioner Transac
 func (me *Context) execWithoutTransaction(ctx context.Context, statements []*Statement) ([]sql.Result, error) {
 	var saveResults []sql.Result
 
+	if me.DB == nil && len(statements) > 0 {
+		return nil, errors.New("DB instance of type (*sql.DB) is nil")
+	}
 	for _, statement := range statements {
-		result, err := me.ExecStatementContext(ctx, statement)
+		if statement.IsLocking() {
+			return nil, ErrLockRequiresTransaction
+		}
+		result, err := me.exec(ctx, statement)
 		if err != nil {
 			return nil, err
 		}
@@ -86,50 +215,113 @@ func (me *Context) execWithoutTransaction(ctx context.Context, statements []*Sta
 	return saveResults, nil
 }
 
-//SaveChanges execute all defered statements to database
+//SaveChanges execute all defered statements to database, with their stamped parameters filled. See Statement.Stamp.
+//Statements run in the transaction of the context, or of the scope of ctx, which is left open.
+//Without one, more than one statement, an audited statement or statements the tenant of ctx is applied to,
+//run in a new transaction committed on success. Cached results of the tags of the statements are invalidated
+//once they are committed, see Statement.Invalidates. Without a transaction, it fails with ErrClientClosed and keeps
+//the statements once the client is shut down, see Client.Shutdown.
+//A sharded client saves the statements on the shard of their key, it fails with ErrMultipleShards and keeps them
+//if they belong to several shards, or to another shard than the transaction. See AllowMultipleShards
 func (me *Context) SaveChanges(ctx context.Context) ([]sql.Result, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	if me.MustUseTransaction() {
-		txFromContext := me.GetTransactionScope(ctx)
-		if txFromContext != nil {
-			me.SetTransaction(txFromContext)
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	transaction := me.transaction
+	if transaction == nil {
+		if scope := ScopeFrom(ctx); scope != nil && scope != me {
+			transaction = scopeTransaction(ctx)
 		}
-		if me.GetTransaction() == nil {
-			me.IsUserDefinedTransaction = false //this flag is used in execUseTransaction(). if false, execUseTransaction will complete the transaction
-
-			newTransaction, err := NewTransaction(me.DB)
-			if err != nil {
-				return nil, err
-			}
+	}
+	now := me.Now()
+	statements := make([]*Statement, len(me.statements))
+	for i, statement := range me.statements {
+		statements[i] = statement.stamp(ctx, now)
+	}
+	var groups []*shardStatements
+	var err error
+	if transaction != nil {
+		err = me.checkTransactionShard(ctx, transaction, statements)
+	} else if groups, err = me.groupByShard(ctx, statements); err == nil && len(groups) > 1 && !me.multipleShards {
+		err = ErrMultipleShards
+	}
+	if err != nil {
+		return nil, err
+	}
 
-			results, err := me.execUseTransaction(ctx, newTransaction, me.Statements)
-			me.ClearStatements()
-			if err != nil {
-				return nil, err
-			}
+	if transaction != nil {
+		me.statements = nil
+		results, err := me.execUseTransaction(ctx, transaction, statements)
+		tags := invalidatedTags(statements)
+		if err == nil && me.cache != nil && len(tags) > 0 {
+			transaction.AfterCommit(func() { me.Invalidate(context.Background(), tags...) })
+		}
+		return results, err
+	}
+	if err := me.lifecycle.enter(); err != nil {
+		return nil, err
+	}
+	defer me.lifecycle.leave()
+	me.statements = nil
+	if len(groups) == 0 {
+		return me.execWithoutTransaction(ctx, nil)
+	}
+	var results []sql.Result
+	for _, group := range groups {
+		groupResults, err := me.save(ctx, group.shard, group.statements)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, groupResults...)
+	}
+	return results, nil
+}
 
-			err = newTransaction.Commit()
-			if err != nil {
-				return nil, err
-			}
-			return results, nil
+//save executes the statements on the shard outside a transaction of the context, in a new transaction committed
+//on success unless it's a single statement which needs none
+func (me *Context) save(ctx context.Context, shard int, statements []*Statement) ([]sql.Result, error) {
+	tags := invalidatedTags(statements)
+	setup := me.tenantSetup(ctx)
+	if len(statements) <= 1 && setup == nil && !me.anyAudited(statements) {
+		results, err := me.execWithoutTransaction(ctx, statements)
+		if err == nil {
+			me.Invalidate(ctx, tags...)
 		}
-		results, err := me.execUseTransaction(ctx, me.GetTransaction(), me.Statements)
-		me.ClearStatements()
 		return results, err
 	}
 
-	results, err := me.execWithoutTransaction(ctx, me.Statements)
-	me.ClearStatements()
-	return results, err
+	newTransaction, err := me.newTransactionOn(ctx, shard)
+	if err != nil {
+		return nil, err
+	}
+	results, err := me.execUseTransaction(ctx, newTransaction, statements)
+	if err != nil {
+		return nil, err
+	}
+	if me.cache != nil && len(tags) > 0 {
+		newTransaction.AfterCommit(func() { me.Invalidate(context.Background(), tags...) })
+	}
+	err = newTransaction.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+//anyAudited determine if the change of any of the statements is recorded
+func (me *Context) anyAudited(statements []*Statement) bool {
+	for _, statement := range statements {
+		if me.isAudited(statement) {
+			return true
+		}
+	}
+	return false
 }
 
-//NewContext create new dbContext instance
+//NewContext create new dbContext instance, it's the same as dbClient.NewContext()
 func NewContext(dbClient *Client) *Context {
-	return &Context{
-		Client: *dbClient,
-	}
+	return dbClient.NewContext()
 }
@@ -1,7 +1,9 @@
 package dbx
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -9,7 +11,7 @@ import (
 )
 
 func Test_Context_AddStatement(t *testing.T) {
-	db, err := getSqlxDb()
+	db, err := getSqlxDb(t)
 	defer db.Close()
 	if err != nil {
 		t.Errorf(err.Error())
@@ -20,15 +22,15 @@ func Test_Context_AddStatement(t *testing.T) {
 	statement := NewStatement("SELECT * FROM person")
 	dbContext.AddStatement(statement)
 
-	if len(dbContext.Statements) != 1 {
-		t.Errorf("Statements length must be one, but got %d", len(dbContext.Statements))
+	if len(dbContext.Statements()) != 1 {
+		t.Errorf("Statements length must be one, but got %d", len(dbContext.Statements()))
 	}
 
 	fmt.Println("AddStatement test succedd")
 }
 
 func Test_Context_ClearStatements(t *testing.T) {
-	db, err := getSqlxDb()
+	db, err := getSqlxDb(t)
 	defer db.Close()
 	if err != nil {
 		t.Errorf(err.Error())
@@ -40,15 +42,15 @@ func Test_Context_ClearStatements(t *testing.T) {
 
 	dbContext.ClearStatements()
 
-	if len(dbContext.Statements) != 0 {
-		t.Errorf("statements length must be 0, but got %d", len(dbContext.Statements))
+	if len(dbContext.Statements()) != 0 {
+		t.Errorf("statements length must be 0, but got %d", len(dbContext.Statements()))
 	}
 
 	fmt.Println("ClearStatements test succedd")
 }
 
 func Test_Context_ShouldUseTransaction1(t *testing.T) {
-	db, err := getSqlxDb()
+	db, err := getSqlxDb(t)
 	defer db.Close()
 	if err != nil {
 		t.Errorf(err.Error())
@@ -68,7 +70,7 @@ func Test_Context_ShouldUseTransaction1(t *testing.T) {
 }
 
 func Test_Context_ShouldUseTransaction2(t *testing.T) {
-	db, err := getSqlxDb()
+	db, err := getSqlxDb(t)
 	defer db.Close()
 	if err != nil {
 		t.Errorf(err.Error())
@@ -83,7 +85,7 @@ func Test_Context_ShouldUseTransaction2(t *testing.T) {
 		t.Errorf(err.Error())
 	}
 
-	dbContext.Client.SetTransaction(tx)
+	dbContext.SetTransaction(tx)
 
 	if !dbContext.MustUseTransaction() {
 		t.Errorf("Context should use transaction = true even if its statements length is only 1, but got = %v", dbContext.MustUseTransaction())
@@ -93,7 +95,7 @@ func Test_Context_ShouldUseTransaction2(t *testing.T) {
 }
 
 func Test_Context_SaveChanges_SingleStatement(t *testing.T) {
-	db, err := getSqlxDb()
+	db, err := getSqlxDb(t)
 	defer db.Close()
 	if err != nil {
 		t.Errorf(err.Error())
@@ -146,7 +148,7 @@ func Test_Context_SaveChanges_SingleStatement(t *testing.T) {
 }
 
 func Test_Context_SaveChanges_MultiStatement_UseDefaultTransaction(t *testing.T) {
-	db, err := getSqlxDb()
+	db, err := getSqlxDb(t)
 	defer db.Close()
 	if err != nil {
 		t.Errorf(err.Error())
@@ -207,7 +209,7 @@ func Test_Context_SaveChanges_MultiStatement_UseDefaultTransaction(t *testing.T)
 }
 
 func Test_Context_SaveChanges_MultiStatement_UseExternalTransaction(t *testing.T) {
-	db, err := getSqlxDb()
+	db, err := getSqlxDb(t)
 	defer db.Close()
 	if err != nil {
 		t.Errorf(err.Error())
@@ -278,7 +280,7 @@ func Test_Context_SaveChanges_MultiStatement_UseExternalTransaction(t *testing.T
 }
 
 func Test_Context_SaveChanges_UseExternalTransaction_MustRolledBack(t *testing.T) {
-	db, err := getSqlxDb()
+	db, err := getSqlxDb(t)
 	defer db.Close()
 	if err != nil {
 		t.Errorf(err.Error())
@@ -344,3 +346,171 @@ func Test_Context_SaveChanges_UseExternalTransaction_MustRolledBack(t *testing.T
 	}
 	fmt.Println("SaveChanges rollback test succeed")
 }
+
+func Test_Context_BeginTransaction(t *testing.T) {
+	db, err := getSqlxDb(t)
+	defer db.Close()
+	if err != nil {
+		t.Errorf("Fatal create db error: %s", err.Error())
+	}
+	dbContext := NewClient(db).NewContext()
+	tx, err := dbContext.BeginTransaction()
+
+	if err != nil {
+		t.Errorf("Fatal begin transaction error: %s", err.Error())
+	}
+
+	if tx == nil {
+		t.Errorf("Transaction expected to be not nil")
+	}
+
+	if dbContext.transaction == nil {
+		t.Errorf("Fatal transaction error: transaction expected to be not <nil>")
+	}
+	dbContext.CompleteTransaction()
+}
+
+func Test_Context_GetTransaction(t *testing.T) {
+	db, err := getSqlxDb(t)
+	defer db.Close()
+	if err != nil {
+		t.Errorf("Fatal create db error: %s", err.Error())
+	}
+	dbContext := NewClient(db).NewContext()
+	tx, err := dbContext.BeginTransaction()
+
+	if err != nil {
+		t.Errorf("Fatal begin transaction error: %s", err.Error())
+	}
+
+	if tx == nil {
+		t.Errorf("Fatal transaction error: transaction expected to be not <nil>")
+	}
+
+	if dbContext.GetTransaction() == nil {
+		t.Errorf("Fatal transaction error: transaction expected to be not <nil>")
+	}
+
+	fmt.Println("Test GetTransaction success")
+}
+
+func Test_Context_SetTransaction(t *testing.T) {
+	db, err := getSqlxDb(t)
+	defer db.Close()
+	if err != nil {
+		t.Errorf("Fatal create db error: %s", err.Error())
+	}
+	dbContext := NewClient(db).NewContext()
+	tx, err := NewTransaction(db)
+
+	if err != nil {
+		t.Errorf("Fatal begin transaction error: %s", err.Error())
+	}
+
+	if tx == nil {
+		t.Errorf("Fatal transaction error: transaction expected to be not <nil>")
+	}
+
+	dbContext.SetTransaction(tx)
+
+	if dbContext.GetTransaction() == nil {
+		t.Errorf("Fatal transaction error: transaction expected to be not <nil>")
+	}
+
+	fmt.Println("Test SetTransaction success")
+}
+
+func Test_Context_ConcurrentAddStatement(t *testing.T) {
+	db := getSQLiteFileDb(t)
+	dbContext := NewClient(db).NewContext()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dbContext.AddStatement(newPersonStatement(fmt.Sprintf("Person %d", i)))
+			dbContext.MustUseTransaction()
+		}(i)
+	}
+	wg.Wait()
+
+	if len(dbContext.Statements()) != 50 {
+		t.Fatalf("Expected 50 statements, got %d", len(dbContext.Statements()))
+	}
+	if _, err := dbContext.SaveChanges(context.Background()); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	if count := countPersons(t, db); count != 50 {
+		t.Errorf("Expected 50 persons, got %d", count)
+	}
+}
+
+func Test_Context_ConcurrentSaveChanges(t *testing.T) {
+	db := getSQLiteFileDb(t)
+	dbContext := NewClient(db).NewContext()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dbContext.AddStatements(newPersonStatement(fmt.Sprintf("Person %d", i)), newPersonStatement(fmt.Sprintf("Person %d'", i)))
+			if _, err := dbContext.SaveChanges(context.Background()); err != nil {
+				t.Errorf("SaveChanges error: %s", err.Error())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if count := countPersons(t, db); count != 20 {
+		t.Errorf("Expected 20 persons, got %d", count)
+	}
+	if len(dbContext.Statements()) != 0 {
+		t.Errorf("Expected every statement to be saved")
+	}
+}
+
+func Test_Context_SaveChangesStamps(t *testing.T) {
+	db, err := getSqlxDb(t)
+	if err != nil {
+		t.Fatalf("Fatal create db error: %s", err.Error())
+	}
+	db.MustExec("CREATE TABLE IF NOT EXISTS ticket (id INTEGER PRIMARY KEY, title VARCHAR(255) NOT NULL, created_at TIMESTAMP NOT NULL, created_by VARCHAR(255), updated_at TIMESTAMP, updated_by VARCHAR(255))")
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := created
+	client := NewClient(db).WithClock(func() time.Time { return now })
+	dbContext := client.NewContext()
+
+	insert := NewStatement("INSERT INTO ticket (id, title, created_at, created_by) VALUES (1, 'Printer', :created_at, :created_by)").Stamp(CreatedAtColumn, CreatedByColumn)
+	dbContext.AddStatement(insert)
+	if _, err := dbContext.SaveChanges(WithActor(context.Background(), "dadang")); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	if len(insert.Parameters) != 0 {
+		t.Errorf("SaveChanges must not change the stamped statement, got %v", insert.Parameters)
+	}
+
+	now = created.Add(time.Hour)
+	dbContext.AddStatement(NewStatement("UPDATE ticket SET title = 'Scanner', updated_at = :updated_at, updated_by = :updated_by WHERE id = 1").Stamp(UpdatedAtColumn, UpdatedByColumn))
+	if _, err := dbContext.SaveChanges(context.Background()); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+
+	ticket := struct {
+		CreatedAt time.Time  `db:"created_at"`
+		CreatedBy *string    `db:"created_by"`
+		UpdatedAt *time.Time `db:"updated_at"`
+		UpdatedBy *string    `db:"updated_by"`
+	}{}
+	if err := db.Get(&ticket, "SELECT created_at, created_by, updated_at, updated_by FROM ticket WHERE id = 1"); err != nil {
+		t.Fatalf("Get ticket error: %s", err.Error())
+	}
+	if !ticket.CreatedAt.Equal(created) || ticket.CreatedBy == nil || *ticket.CreatedBy != "dadang" {
+		t.Errorf("Expected ticket created by dadang at %s, got %+v", created, ticket)
+	}
+	if ticket.UpdatedAt == nil || !ticket.UpdatedAt.Equal(now) || ticket.UpdatedBy != nil {
+		t.Errorf("Expected ticket updated without actor at %s, got %+v", now, ticket)
+	}
+}
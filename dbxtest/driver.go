@@ -0,0 +1,160 @@
+package dbxtest
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/supendi/dbx/internal/driverutil"
+)
+
+//fakeDriver is the driver of fake connections, it can't be opened by name
+type fakeDriver struct{}
+
+//Open implements driver.Driver
+func (me fakeDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("dbxtest driver can only be opened through Fake.Client")
+}
+
+//connector creates connections to the fake
+type connector struct {
+	fake *Fake
+}
+
+//Connect implements driver.Connector
+func (me *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &conn{fake: me.fake}, nil
+}
+
+//Driver implements driver.Connector
+func (me *connector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+//conn is a connection to the fake, it tracks the transaction it's in
+type conn struct {
+	fake *Fake
+	tx   int
+}
+
+//Prepare implements driver.Conn
+func (me *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: me, query: query}, nil
+}
+
+//Close implements driver.Conn
+func (me *conn) Close() error {
+	return nil
+}
+
+//Begin implements driver.Conn
+func (me *conn) Begin() (driver.Tx, error) {
+	return me.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+//BeginTx implements driver.ConnBeginTx
+func (me *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := me.fake.begin()
+	if err != nil {
+		return nil, err
+	}
+	me.tx = tx
+	return &transaction{conn: me}, nil
+}
+
+//ExecContext implements driver.ExecerContext
+func (me *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return me.fake.exec(&Call{Kind: EventExec, SQL: query, Args: values(args), Tx: me.tx})
+}
+
+//QueryContext implements driver.QueryerContext
+func (me *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if rows, ok := ctx.Value(recordedCallKey).(*Rows); ok {
+		return rows.cursor(), nil
+	}
+	rows, err := me.fake.query(&Call{Kind: EventQuery, SQL: query, Args: values(args), Tx: me.tx})
+	if err != nil {
+		return nil, err
+	}
+	return rows.cursor(), nil
+}
+
+//transaction is a driver transaction of a fake connection
+type transaction struct {
+	conn *conn
+}
+
+//Commit implements driver.Tx
+func (me *transaction) Commit() error {
+	tx := me.conn.tx
+	me.conn.tx = 0
+	return me.conn.fake.complete(EventCommit, tx)
+}
+
+//Rollback implements driver.Tx
+func (me *transaction) Rollback() error {
+	tx := me.conn.tx
+	me.conn.tx = 0
+	return me.conn.fake.complete(EventRollback, tx)
+}
+
+//stmt is a prepared statement of a fake connection
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+//Close implements driver.Stmt
+func (me *stmt) Close() error {
+	return nil
+}
+
+//NumInput implements driver.Stmt, the number of arguments is not checked
+func (me *stmt) NumInput() int {
+	return -1
+}
+
+//Exec implements driver.Stmt
+func (me *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return me.conn.ExecContext(context.Background(), me.query, driverutil.NamedValues(args))
+}
+
+//Query implements driver.Stmt
+func (me *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return me.conn.QueryContext(context.Background(), me.query, driverutil.NamedValues(args))
+}
+
+//ExecContext implements driver.StmtExecContext
+func (me *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return me.conn.ExecContext(ctx, me.query, args)
+}
+
+//QueryContext implements driver.StmtQueryContext
+func (me *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return me.conn.QueryContext(ctx, me.query, args)
+}
+
+//result is the programmed result of an exec statement
+type result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+//LastInsertId implements driver.Result
+func (me *result) LastInsertId() (int64, error) {
+	return me.lastInsertID, nil
+}
+
+//RowsAffected implements driver.Result
+func (me *result) RowsAffected() (int64, error) {
+	return me.rowsAffected, nil
+}
+
+//values returns the values of named arguments
+func values(args []driver.NamedValue) []interface{} {
+	result := make([]interface{}, len(args))
+	for i, arg := range args {
+		result[i] = arg.Value
+	}
+	return result
+}
@@ -0,0 +1,419 @@
+//Package dbxtest provides an in-memory fake database for unit testing code built on dbx without a database server.
+//
+//A Fake serves dbx clients through a database/sql driver, so repositories and services using *dbx.Client,
+//*dbx.Context or dbx.Transactioner run unchanged. Every statement is recorded, results and errors are programmed
+//per SQL pattern and the transaction sequence can be asserted:
+//
+//	fake := dbxtest.NewFake()
+//	fake.OnExec(`^INSERT INTO "order"`).ReturnResult(0, 1)
+//	fake.OnQuery(`FROM "order" WHERE id`).ReturnRows(dbxtest.NewRows("id", "total").AddRow("1", 10.5))
+//
+//	repository := NewOrderRepository(fake.Client().NewContext())
+//	...
+//	fake.AssertSequence(t, dbxtest.EventBegin, dbxtest.EventExec, dbxtest.EventExec, dbxtest.EventCommit)
+package dbxtest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/supendi/dbx"
+)
+
+//DriverName is the default driver name reported by clients of a fake, it uses question mark bind variables
+const DriverName = "dbxtest"
+
+//ErrUnexpectedStatement is returned by a strict fake when a statement matches no programmed rule
+var ErrUnexpectedStatement = errors.New("Statement is not expected")
+
+//Event kinds recorded by a fake
+const (
+	EventBegin    = "begin"
+	EventExec     = "exec"
+	EventQuery    = "query"
+	EventCommit   = "commit"
+	EventRollback = "rollback"
+)
+
+//Event represent a single interaction with the fake, in the order it happened
+type Event struct {
+	Kind string
+	//Tx is the sequence number of the transaction the event belongs to, 0 when executed outside a transaction
+	Tx int
+	//Call is the recorded statement of exec and query events
+	Call *Call
+}
+
+//Call represent an executed statement
+type Call struct {
+	Kind string
+	//SQL is the statement SQL. Statements executed through a Client are received with their parameters bound,
+	//so SQL contains the bind variables of the driver and Args the parameter values in order
+	SQL  string
+	Args []interface{}
+	//Statement is the statement as it was passed to a fake Transaction, its parameters are named so Args is nil.
+	//It's nil for statements executed through a Client
+	Statement *dbx.Statement
+	Tx        int
+}
+
+//Fake is an in-memory database backend. It's safe for concurrent use
+type Fake struct {
+	mutex      sync.Mutex
+	db         *sql.DB
+	driverName string
+	strict     bool
+	rules      []*Rule
+	events     []*Event
+	lastTx     int
+}
+
+//WithDriverName set the driver name reported by clients, it selects the bind variables sqlx uses and the
+//driver specific behaviour of dbx. Use "postgres" to exercise Postgres code paths
+func (me *Fake) WithDriverName(driverName string) *Fake {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.driverName = driverName
+	return me
+}
+
+//Strict makes statements matching no rule fail with ErrUnexpectedStatement.
+//By default exec returns a result with no affected row and query returns no row
+func (me *Fake) Strict() *Fake {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.strict = true
+	return me
+}
+
+//Client returns a new dbx client backed by the fake
+func (me *Fake) Client() *dbx.Client {
+	me.mutex.Lock()
+	driverName := me.driverName
+	me.mutex.Unlock()
+	return dbx.NewClient(sqlx.NewDb(me.db, driverName))
+}
+
+//Context returns a new dbx context backed by the fake
+func (me *Fake) Context() *dbx.Context {
+	return dbx.NewContext(me.Client())
+}
+
+//OnExec programs the result of exec statements whose SQL matches the regular expression pattern
+func (me *Fake) OnExec(pattern string) *Rule {
+	return me.addRule(&Rule{kind: EventExec, pattern: regexp.MustCompile(pattern)})
+}
+
+//OnQuery programs the rows of query statements whose SQL matches the regular expression pattern
+func (me *Fake) OnQuery(pattern string) *Rule {
+	return me.addRule(&Rule{kind: EventQuery, pattern: regexp.MustCompile(pattern)})
+}
+
+//OnBegin programs the outcome of beginning a transaction
+func (me *Fake) OnBegin() *Rule {
+	return me.addRule(&Rule{kind: EventBegin})
+}
+
+//OnCommit programs the outcome of committing a transaction
+func (me *Fake) OnCommit() *Rule {
+	return me.addRule(&Rule{kind: EventCommit})
+}
+
+//OnRollback programs the outcome of rolling back a transaction
+func (me *Fake) OnRollback() *Rule {
+	return me.addRule(&Rule{kind: EventRollback})
+}
+
+//addRule appends the rule, rules are matched in the order they are added
+func (me *Fake) addRule(rule *Rule) *Rule {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.rules = append(me.rules, rule)
+	return rule
+}
+
+//Events returns every recorded event in order
+func (me *Fake) Events() []*Event {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return append([]*Event{}, me.events...)
+}
+
+//Calls returns every executed statement in order
+func (me *Fake) Calls() []*Call {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	calls := []*Call{}
+	for _, event := range me.events {
+		if event.Call != nil {
+			calls = append(calls, event.Call)
+		}
+	}
+	return calls
+}
+
+//CallsMatching returns the executed statements whose SQL matches the regular expression pattern
+func (me *Fake) CallsMatching(pattern string) []*Call {
+	re := regexp.MustCompile(pattern)
+	calls := []*Call{}
+	for _, call := range me.Calls() {
+		if re.MatchString(call.SQL) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+//Reset forgets recorded events and programmed rules
+func (me *Fake) Reset() {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.rules = nil
+	me.events = nil
+}
+
+//Close closes the underlying database handle
+func (me *Fake) Close() error {
+	return me.db.Close()
+}
+
+//begin records the beginning of a transaction and returns its sequence number
+func (me *Fake) begin() (int, error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.lastTx++
+	if rule := me.match(EventBegin, ""); rule != nil && rule.err != nil {
+		return 0, rule.err
+	}
+	me.events = append(me.events, &Event{Kind: EventBegin, Tx: me.lastTx})
+	return me.lastTx, nil
+}
+
+//complete records the commit or rollback of the transaction
+func (me *Fake) complete(kind string, tx int) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.events = append(me.events, &Event{Kind: kind, Tx: tx})
+	if rule := me.match(kind, ""); rule != nil {
+		return rule.err
+	}
+	return nil
+}
+
+//exec records the exec call and returns its programmed result
+func (me *Fake) exec(call *Call) (driver.Result, error) {
+	rule, err := me.record(call)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return &result{}, nil
+	}
+	return &result{lastInsertID: rule.lastInsertID, rowsAffected: rule.rowsAffected}, nil
+}
+
+//query records the query call and returns its programmed rows
+func (me *Fake) query(call *Call) (*Rows, error) {
+	rule, err := me.record(call)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil || rule.rows == nil {
+		return NewRows(), nil
+	}
+	return rule.rows, nil
+}
+
+//record appends the call to the events and returns the matching rule
+func (me *Fake) record(call *Call) (*Rule, error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.events = append(me.events, &Event{Kind: call.Kind, Tx: call.Tx, Call: call})
+	rule := me.match(call.Kind, call.SQL)
+	if rule == nil {
+		if me.strict {
+			return nil, fmt.Errorf("%w: %s %s", ErrUnexpectedStatement, call.Kind, call.SQL)
+		}
+		return nil, nil
+	}
+	return rule, rule.err
+}
+
+//match returns the first rule of the kind matching the SQL which has uses left, and consumes one use
+func (me *Fake) match(kind string, sql string) *Rule {
+	for _, rule := range me.rules {
+		if rule.kind != kind || (rule.times > 0 && rule.used >= rule.times) {
+			continue
+		}
+		if rule.pattern != nil && !rule.pattern.MatchString(sql) {
+			continue
+		}
+		rule.used++
+		return rule
+	}
+	return nil
+}
+
+//AssertSequence checks that the kinds of the recorded events are exactly kinds
+func (me *Fake) AssertSequence(t testing.TB, kinds ...string) {
+	t.Helper()
+	actual := []string{}
+	for _, event := range me.Events() {
+		actual = append(actual, event.Kind)
+	}
+	if strings.Join(actual, ",") != strings.Join(kinds, ",") {
+		t.Errorf("dbxtest: expected events [%s], got [%s]", strings.Join(kinds, ", "), strings.Join(actual, ", "))
+	}
+}
+
+//AssertCommitted checks that at least one transaction began and every transaction was committed
+func (me *Fake) AssertCommitted(t testing.TB) {
+	t.Helper()
+	me.assertCompleted(t, EventCommit)
+}
+
+//AssertRolledBack checks that at least one transaction began and every transaction was rolled back
+func (me *Fake) AssertRolledBack(t testing.TB) {
+	t.Helper()
+	me.assertCompleted(t, EventRollback)
+}
+
+//AssertNoTransaction checks that no transaction began
+func (me *Fake) AssertNoTransaction(t testing.TB) {
+	t.Helper()
+	for _, event := range me.Events() {
+		if event.Kind == EventBegin {
+			t.Errorf("dbxtest: expected no transaction, transaction %d began", event.Tx)
+			return
+		}
+	}
+}
+
+//assertCompleted checks that every transaction ended with the completion kind
+func (me *Fake) assertCompleted(t testing.TB, kind string) {
+	t.Helper()
+	outcomes := map[int]string{}
+	order := []int{}
+	for _, event := range me.Events() {
+		switch event.Kind {
+		case EventBegin:
+			outcomes[event.Tx] = ""
+			order = append(order, event.Tx)
+		case EventCommit, EventRollback:
+			if outcomes[event.Tx] == "" {
+				outcomes[event.Tx] = event.Kind
+			}
+		}
+	}
+	if len(order) == 0 {
+		t.Errorf("dbxtest: expected a transaction to %s, no transaction began", kind)
+		return
+	}
+	for _, tx := range order {
+		if outcomes[tx] == "" {
+			t.Errorf("dbxtest: expected transaction %d to %s, it's still open", tx, kind)
+		} else if outcomes[tx] != kind {
+			t.Errorf("dbxtest: expected transaction %d to %s, got %s", tx, kind, outcomes[tx])
+		}
+	}
+}
+
+//AssertExecuted checks that at least one statement matching the regular expression pattern was executed
+func (me *Fake) AssertExecuted(t testing.TB, pattern string) {
+	t.Helper()
+	if len(me.CallsMatching(pattern)) == 0 {
+		t.Errorf("dbxtest: expected a statement matching %q to be executed", pattern)
+	}
+}
+
+//AssertNotExecuted checks that no statement matching the regular expression pattern was executed
+func (me *Fake) AssertNotExecuted(t testing.TB, pattern string) {
+	t.Helper()
+	if calls := me.CallsMatching(pattern); len(calls) > 0 {
+		t.Errorf("dbxtest: expected no statement matching %q, got %s", pattern, calls[0].SQL)
+	}
+}
+
+//AssertExpectations checks that every rule limited by Times was used that many times
+func (me *Fake) AssertExpectations(t testing.TB) {
+	t.Helper()
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	for _, rule := range me.rules {
+		if rule.times > 0 && rule.used != rule.times {
+			t.Errorf("dbxtest: expected %s to be used %d time(s), used %d time(s)", rule, rule.times, rule.used)
+		}
+	}
+}
+
+//Rule represent a programmed outcome
+type Rule struct {
+	kind         string
+	pattern      *regexp.Regexp
+	err          error
+	rows         *Rows
+	lastInsertID int64
+	rowsAffected int64
+	times        int
+	used         int
+}
+
+//ReturnResult set the result of matching exec statements
+func (me *Rule) ReturnResult(lastInsertID int64, rowsAffected int64) *Rule {
+	me.lastInsertID = lastInsertID
+	me.rowsAffected = rowsAffected
+	return me
+}
+
+//ReturnRows set the rows of matching query statements
+func (me *Rule) ReturnRows(rows *Rows) *Rule {
+	me.rows = rows
+	return me
+}
+
+//ReturnError makes matching statements, begin, commit or rollback fail with err
+func (me *Rule) ReturnError(err error) *Rule {
+	me.err = err
+	return me
+}
+
+//Times limits the rule to match n times, the next matches fall through to later rules. 0 means unlimited
+func (me *Rule) Times(n int) *Rule {
+	me.times = n
+	return me
+}
+
+//Once limits the rule to match a single time
+func (me *Rule) Once() *Rule {
+	return me.Times(1)
+}
+
+//String returns the description of the rule
+func (me *Rule) String() string {
+	if me.pattern == nil {
+		return me.kind
+	}
+	return fmt.Sprintf("%s %q", me.kind, me.pattern.String())
+}
+
+//contextKey is the type of the context keys of the package
+type contextKey string
+
+//recordedCallKey marks a driver query whose call has been recorded already, its value is the rows to return
+const recordedCallKey contextKey = "recordedCall"
+
+//NewFake create new fake instance
+func NewFake() *Fake {
+	fake := &Fake{
+		driverName: DriverName,
+	}
+	fake.db = sql.OpenDB(&connector{fake: fake})
+	return fake
+}
@@ -0,0 +1,264 @@
+package dbxtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/supendi/dbx"
+)
+
+//recorder captures assertion failures instead of failing the test
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (me *recorder) Helper() {}
+
+func (me *recorder) Errorf(format string, args ...interface{}) {
+	me.failures = append(me.failures, fmt.Sprintf(format, args...))
+}
+
+func Test_Fake_SaveChangesCommits(t *testing.T) {
+	fake := NewFake()
+	fake.OnExec(`^INSERT INTO person`).ReturnResult(7, 1)
+	dbContext := fake.Context()
+
+	insert := dbx.NewStatement("INSERT INTO person (id, name) VALUES (:id, :name)")
+	insert.AddParameter("id", "1")
+	insert.AddParameter("name", "Dadang")
+	dbContext.AddStatements(insert, dbx.NewStatement("UPDATE person SET name = 'Asep'"))
+
+	results, err := dbContext.SaveChanges(context.Background())
+	if err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	rowsAffected, _ := results[0].RowsAffected()
+	lastInsertID, _ := results[0].LastInsertId()
+	if rowsAffected != 1 || lastInsertID != 7 {
+		t.Errorf("Expected programmed result (7, 1), got (%d, %d)", lastInsertID, rowsAffected)
+	}
+
+	fake.AssertSequence(t, EventBegin, EventExec, EventExec, EventCommit)
+	fake.AssertCommitted(t)
+	fake.AssertExecuted(t, `^UPDATE person`)
+
+	calls := fake.CallsMatching(`^INSERT`)
+	if len(calls) != 1 || calls[0].SQL != "INSERT INTO person (id, name) VALUES (?, ?)" {
+		t.Fatalf("Unexpected recorded insert: %+v", calls)
+	}
+	if calls[0].Args[0] != "1" || calls[0].Args[1] != "Dadang" || calls[0].Tx != 1 {
+		t.Errorf("Unexpected recorded arguments %v in transaction %d", calls[0].Args, calls[0].Tx)
+	}
+}
+
+func Test_Fake_SaveChangesRollsBack(t *testing.T) {
+	fake := NewFake()
+	errDuplicate := errors.New("duplicate key")
+	fake.OnExec(`^INSERT`).ReturnError(errDuplicate)
+	dbContext := fake.Context()
+	dbContext.AddStatements(dbx.NewStatement("UPDATE person SET name = 'Asep'"), dbx.NewStatement("INSERT INTO person (id) VALUES ('1')"))
+
+	_, err := dbContext.SaveChanges(context.Background())
+	if !errors.Is(err, errDuplicate) {
+		t.Fatalf("Expected programmed error, got %v", err)
+	}
+	fake.AssertSequence(t, EventBegin, EventExec, EventExec, EventRollback)
+	fake.AssertRolledBack(t)
+}
+
+func Test_Fake_SingleStatementWithoutTransaction(t *testing.T) {
+	fake := NewFake()
+	dbContext := fake.Context()
+	dbContext.AddStatement(dbx.NewStatement("DELETE FROM person"))
+
+	if _, err := dbContext.SaveChanges(context.Background()); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	fake.AssertSequence(t, EventExec)
+	fake.AssertNoTransaction(t)
+}
+
+func Test_Fake_QueryRows(t *testing.T) {
+	fake := NewFake().WithDriverName("postgres")
+	fake.OnQuery(`FROM person WHERE id`).ReturnRows(NewRows("id", "name", "age").AddRow("1", "Dadang", 30))
+
+	statement := dbx.NewStatement("SELECT id, name, age FROM person WHERE id = :id")
+	statement.AddParameter("id", "1")
+	rows, err := fake.Client().QueryStatementContext(context.Background(), statement)
+	if err != nil {
+		t.Fatalf("QueryStatement error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	person := struct {
+		ID   string `db:"id"`
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}{}
+	if !rows.Next() {
+		t.Fatalf("Expected a row")
+	}
+	if err := rows.StructScan(&person); err != nil {
+		t.Fatalf("StructScan error: %s", err.Error())
+	}
+	if person.ID != "1" || person.Name != "Dadang" || person.Age != 30 {
+		t.Errorf("Unexpected person %+v", person)
+	}
+	if rows.Next() {
+		t.Errorf("Expected a single row")
+	}
+	if sql := fake.Calls()[0].SQL; sql != "SELECT id, name, age FROM person WHERE id = $1" {
+		t.Errorf("Expected postgres bind variables, got %s", sql)
+	}
+}
+
+func Test_Fake_Strict(t *testing.T) {
+	fake := NewFake().Strict()
+	fake.OnExec(`^DELETE`)
+
+	client := fake.Client()
+	if _, err := client.ExecStatement(dbx.NewStatement("DELETE FROM person")); err != nil {
+		t.Errorf("Programmed statement failed: %s", err.Error())
+	}
+	_, err := client.ExecStatement(dbx.NewStatement("DROP TABLE person"))
+	if !errors.Is(err, ErrUnexpectedStatement) {
+		t.Errorf("Expected ErrUnexpectedStatement, got %v", err)
+	}
+}
+
+func Test_Fake_CommitError(t *testing.T) {
+	fake := NewFake()
+	errSerialization := errors.New("could not serialize access")
+	fake.OnCommit().ReturnError(errSerialization).Once()
+	dbContext := fake.Context()
+	dbContext.AddStatements(dbx.NewStatement("UPDATE a SET x = 1"), dbx.NewStatement("UPDATE b SET x = 1"))
+
+	_, err := dbContext.SaveChanges(context.Background())
+	if !errors.Is(err, errSerialization) {
+		t.Fatalf("Expected commit error, got %v", err)
+	}
+	fake.AssertExpectations(t)
+}
+
+func Test_Fake_Times(t *testing.T) {
+	fake := NewFake()
+	fake.OnExec(`^UPDATE`).ReturnResult(0, 1).Once()
+	fake.OnExec(`^UPDATE`).ReturnResult(0, 0)
+	fake.OnExec(`^DELETE`).Times(2)
+
+	client := fake.Client()
+	first, _ := client.ExecStatement(dbx.NewStatement("UPDATE person SET age = 1"))
+	second, _ := client.ExecStatement(dbx.NewStatement("UPDATE person SET age = 1"))
+	if affected, _ := first.RowsAffected(); affected != 1 {
+		t.Errorf("Expected first update to affect 1 row, got %d", affected)
+	}
+	if affected, _ := second.RowsAffected(); affected != 0 {
+		t.Errorf("Expected second update to affect no row, got %d", affected)
+	}
+
+	tb := &recorder{TB: t}
+	fake.AssertExpectations(tb)
+	if len(tb.failures) != 1 || !strings.Contains(tb.failures[0], `exec "^DELETE"`) {
+		t.Errorf("Expected the unused delete rule to be reported, got %v", tb.failures)
+	}
+}
+
+func Test_Fake_AssertCommittedFailures(t *testing.T) {
+	fake := NewFake()
+	tb := &recorder{TB: t}
+	fake.AssertCommitted(tb)
+	if len(tb.failures) != 1 {
+		t.Errorf("Expected a failure without transaction, got %v", tb.failures)
+	}
+
+	tx, err := fake.Context().BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+	tb = &recorder{TB: t}
+	fake.AssertCommitted(tb)
+	if len(tb.failures) != 1 || !strings.Contains(tb.failures[0], "still open") {
+		t.Errorf("Expected open transaction failure, got %v", tb.failures)
+	}
+
+	tx.Rollback()
+	tb = &recorder{TB: t}
+	fake.AssertCommitted(tb)
+	if len(tb.failures) != 1 || !strings.Contains(tb.failures[0], "got rollback") {
+		t.Errorf("Expected rollback failure, got %v", tb.failures)
+	}
+}
+
+func Test_Transaction_RecordsStatements(t *testing.T) {
+	fake := NewFake()
+	fake.OnQuery(`^SELECT`).ReturnRows(NewRows("count").AddRow(2))
+
+	tx, err := fake.BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+	var transactioner dbx.Transactioner = tx
+
+	statement := dbx.NewStatement("UPDATE person SET name = :name WHERE id = :id")
+	statement.AddParameter("name", "Asep")
+	statement.AddParameter("id", "1")
+	if _, err := transactioner.ExecStatement(statement); err != nil {
+		t.Fatalf("ExecStatement error: %s", err.Error())
+	}
+	rows, err := transactioner.QueryStatement(dbx.NewStatement("SELECT COUNT(*) AS count FROM person"))
+	if err != nil {
+		t.Fatalf("QueryStatement error: %s", err.Error())
+	}
+	var count int
+	for rows.Next() {
+		rows.Scan(&count)
+	}
+	rows.Close()
+	if count != 2 {
+		t.Errorf("Expected programmed count 2, got %d", count)
+	}
+	if err := transactioner.Commit(); err != nil {
+		t.Fatalf("Commit error: %s", err.Error())
+	}
+	if _, err := transactioner.ExecStatement(statement); err == nil {
+		t.Errorf("Expected an error executing on a completed transaction")
+	}
+
+	fake.AssertSequence(t, EventBegin, EventExec, EventQuery, EventCommit)
+	recorded := fake.Calls()[0].Statement
+	if recorded != statement || recorded.Parameters["name"] != "Asep" {
+		t.Errorf("Expected the named statement to be recorded, got %+v", recorded)
+	}
+}
+
+func Test_CheckTransactionLeaks(t *testing.T) {
+	fake := NewFake()
+	client := fake.Client()
+	rec := &recorder{}
+	t.Run("leak", func(t *testing.T) {
+		rec.TB = t
+		CheckTransactionLeaks(rec, client)
+		committed := client.NewContext()
+		if _, err := committed.BeginTransaction(); err != nil {
+			t.Fatalf("BeginTransaction error: %s", err.Error())
+		}
+		if err := committed.CompleteTransaction(); err != nil {
+			t.Fatalf("CompleteTransaction error: %s", err.Error())
+		}
+		if _, err := client.NewContext().BeginTransaction(); err != nil {
+			t.Fatalf("BeginTransaction error: %s", err.Error())
+		}
+	})
+
+	if len(rec.failures) != 1 || !strings.Contains(rec.failures[0], "Test_CheckTransactionLeaks") {
+		t.Errorf("Expected the leaked transaction to be reported where it began, got %v", rec.failures)
+	}
+	if len(client.OpenTransactions()) != 0 {
+		t.Error("Expected the leaked transaction to be rolled back")
+	}
+	fake.AssertSequence(t, EventBegin, EventCommit, EventBegin, EventRollback)
+}
@@ -0,0 +1,120 @@
+package dbxtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/supendi/dbx"
+	"gopkg.in/yaml.v3"
+)
+
+//fixtureTable represent the rows of a table in a fixture file
+type fixtureTable struct {
+	name string
+	rows []map[string]interface{}
+}
+
+//LoadFixtures inserts the rows of YAML or JSON fixture files, tables are filled in the order they appear.
+//A fixture file maps table names to lists of rows:
+//
+//	person:
+//	  - id: 1
+//	    name: Dadang
+//	order:
+//	  - id: 10
+//	    person_id: 1
+//	    items: [{"sku": "A1"}]
+//
+//Nested objects and lists are inserted as JSON text. With NewTestClient the rows are rolled back at cleanup
+func LoadFixtures(t testing.TB, client *dbx.Client, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("dbxtest: read fixtures: %s", err.Error())
+		}
+		tables, err := parseFixtures(content)
+		if err != nil {
+			t.Fatalf("dbxtest: parse fixtures %s: %s", path, err.Error())
+		}
+		for _, table := range tables {
+			for i, row := range table.rows {
+				statement, err := fixtureStatement(client.DriverName(), table.name, row)
+				if err == nil {
+					_, err = client.ExecStatementContext(context.Background(), statement)
+				}
+				if err != nil {
+					t.Fatalf("dbxtest: load fixtures %s: table %s row %d: %s", path, table.name, i+1, err.Error())
+				}
+			}
+		}
+	}
+}
+
+//parseFixtures returns the tables of a fixture file in document order. JSON is parsed as YAML
+func parseFixtures(content []byte) ([]*fixtureTable, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping of table names to rows", root.Line)
+	}
+
+	tables := []*fixtureTable{}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		table := &fixtureTable{name: root.Content[i].Value}
+		if err := root.Content[i+1].Decode(&table.rows); err != nil {
+			return nil, fmt.Errorf("table %s: %w", table.name, err)
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+//fixtureStatement returns the insert statement of a fixture row
+func fixtureStatement(driverName string, table string, row map[string]interface{}) (*dbx.Statement, error) {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	quoted := make([]string, len(columns))
+	params := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(driverName, column)
+		params[i] = ":" + column
+	}
+	statement := dbx.NewStatement("INSERT INTO " + quoteIdentifier(driverName, table) + " (" + strings.Join(quoted, ", ") + ") VALUES (" + strings.Join(params, ", ") + ")")
+	for _, column := range columns {
+		value := row[column]
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", column, err)
+			}
+			value = string(encoded)
+		}
+		statement.AddParameter(column, value)
+	}
+	return statement, nil
+}
+
+//quoteIdentifier quotes the identifier for the driver
+func quoteIdentifier(driverName string, identifier string) string {
+	if driverName == "mysql" {
+		return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
@@ -0,0 +1,613 @@
+package dbxtest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/supendi/dbx"
+	"github.com/supendi/dbx/internal/driverutil"
+)
+
+//RecordEnv is the environment variable switching recorders to record mode when set to 1 or true
+const RecordEnv = "DBXTEST_RECORD"
+
+//ErrReplayDiverged is returned in replay mode when an interaction differs from the golden file
+var ErrReplayDiverged = errors.New("Replay diverged from the golden file")
+
+//uuidPattern matches UUID strings
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+//Mode represent the mode of a recorder
+type Mode int
+
+const (
+	//ModeReplay serves the interactions of the golden file without a database
+	ModeReplay Mode = iota
+	//ModeRecord runs the interactions against the database and writes them to the golden file
+	ModeRecord
+)
+
+//interaction represent a recorded round trip to the database
+type interaction struct {
+	Kind              string        `json:"kind"`
+	SQL               string        `json:"sql,omitempty"`
+	Args              []*value      `json:"args,omitempty"`
+	Columns           []string      `json:"columns,omitempty"`
+	Rows              [][]*value    `json:"rows,omitempty"`
+	RowsError         string        `json:"rowsError,omitempty"`
+	LastInsertID      int64         `json:"lastInsertId,omitempty"`
+	LastInsertIDError string        `json:"lastInsertIdError,omitempty"`
+	RowsAffected      int64         `json:"rowsAffected,omitempty"`
+	RowsAffectedError string        `json:"rowsAffectedError,omitempty"`
+	Error             string        `json:"error,omitempty"`
+	values            []interface{} //decoded arguments
+}
+
+//value represent a typed driver value of the golden file
+type value struct {
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+}
+
+//Recorder is a database/sql driver wrapper. In record mode it runs every statement through the real driver and
+//writes queries, arguments and results to a golden file when the test ends. In replay mode it serves the golden
+//file without a database and fails the test on any divergence.
+//
+//Errors are replayed by message only, so driver specific error types are lost
+type Recorder struct {
+	t             testing.TB
+	path          string
+	mode          Mode
+	ignoreTimes   bool
+	ignoreUUIDs   bool
+	mutex         sync.Mutex
+	interactions  []*interaction
+	position      int
+	substitutions map[string]string
+	diverged      bool
+}
+
+//WithMode overrides the mode read from the DBXTEST_RECORD environment variable
+func (me *Recorder) WithMode(mode Mode) *Recorder {
+	me.mode = mode
+	return me
+}
+
+//IgnoreTimes makes any time argument match any recorded time argument, for statements using the current time
+func (me *Recorder) IgnoreTimes() *Recorder {
+	me.ignoreTimes = true
+	return me
+}
+
+//IgnoreUUIDs makes any UUID argument match any recorded UUID argument, for randomly generated keys.
+//The recorded UUID is then replaced by the matching one in replayed results
+func (me *Recorder) IgnoreUUIDs() *Recorder {
+	me.ignoreUUIDs = true
+	return me
+}
+
+//Recording determine if the recorder runs against the database
+func (me *Recorder) Recording() bool {
+	return me.mode == ModeRecord
+}
+
+//Open returns a database handle recording or replaying the golden file. In record mode the driver is opened
+//with the data source name, in replay mode the test is skipped if the golden file does not exist yet
+func (me *Recorder) Open(driverName string, dataSourceName string) *sqlx.DB {
+	me.t.Helper()
+	var inner driver.Connector
+	if me.mode == ModeRecord {
+		var err error
+		inner, err = driverutil.Connector(driverName, dataSourceName)
+		if err != nil {
+			me.t.Fatalf("dbxtest: open %s: %s", driverName, err.Error())
+		}
+	} else {
+		me.load()
+	}
+
+	db := sql.OpenDB(&recordingConnector{recorder: me, inner: inner})
+	me.t.Cleanup(func() {
+		db.Close()
+		me.finish()
+	})
+	return sqlx.NewDb(db, driverName)
+}
+
+//Client returns a dbx client recording or replaying the golden file
+func (me *Recorder) Client(driverName string, dataSourceName string) *dbx.Client {
+	me.t.Helper()
+	return dbx.NewClient(me.Open(driverName, dataSourceName))
+}
+
+//load reads the golden file
+func (me *Recorder) load() {
+	me.t.Helper()
+	content, err := os.ReadFile(me.path)
+	if errors.Is(err, os.ErrNotExist) {
+		me.t.Skipf("dbxtest: golden file %s does not exist, record it against a database with %s=1", me.path, RecordEnv)
+	}
+	if err != nil {
+		me.t.Fatalf("dbxtest: read golden file: %s", err.Error())
+	}
+	if err := json.Unmarshal(content, &me.interactions); err != nil {
+		me.t.Fatalf("dbxtest: parse golden file %s: %s", me.path, err.Error())
+	}
+	for _, recorded := range me.interactions {
+		recorded.values = make([]interface{}, len(recorded.Args))
+		for i, arg := range recorded.Args {
+			if recorded.values[i], err = arg.decode(); err != nil {
+				me.t.Fatalf("dbxtest: parse golden file %s: %s", me.path, err.Error())
+			}
+		}
+	}
+}
+
+//finish writes the golden file in record mode, in replay mode it checks every interaction has been replayed
+func (me *Recorder) finish() {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.mode == ModeRecord {
+		if me.t.Failed() {
+			return
+		}
+		content, err := json.MarshalIndent(me.interactions, "", "  ")
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(me.path), 0755)
+		}
+		if err == nil {
+			err = os.WriteFile(me.path, append(content, '\n'), 0644)
+		}
+		if err != nil {
+			me.t.Errorf("dbxtest: write golden file: %s", err.Error())
+		}
+		return
+	}
+	if !me.diverged && me.position < len(me.interactions) {
+		next := me.interactions[me.position]
+		me.t.Errorf("dbxtest: %d recorded interaction(s) were not replayed, next is %s %s", len(me.interactions)-me.position, next.Kind, next.SQL)
+	}
+}
+
+//record appends an interaction in record mode
+func (me *Recorder) record(recorded *interaction) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.interactions = append(me.interactions, recorded)
+}
+
+//replay returns the next recorded interaction if it matches kind, SQL and arguments
+func (me *Recorder) replay(kind string, query string, args []driver.NamedValue) (*interaction, error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.diverged {
+		return nil, ErrReplayDiverged
+	}
+	if me.position >= len(me.interactions) {
+		return nil, me.diverge("unexpected %s %s, every recorded interaction has been replayed", kind, query)
+	}
+	recorded := me.interactions[me.position]
+	if recorded.Kind != kind || recorded.SQL != query {
+		return nil, me.diverge("interaction %d: expected %s %s, got %s %s", me.position+1, recorded.Kind, recorded.SQL, kind, query)
+	}
+	if len(recorded.values) != len(args) {
+		return nil, me.diverge("interaction %d: %s: expected %d argument(s), got %d", me.position+1, query, len(recorded.values), len(args))
+	}
+	substitutions := map[string]string{}
+	for i, arg := range args {
+		expected := recorded.values[i]
+		if me.ignoreTimes && isTime(expected) && isTime(arg.Value) {
+			continue
+		}
+		if me.ignoreUUIDs && isUUID(expected) && isUUID(arg.Value) {
+			substitutions[expected.(string)] = arg.Value.(string)
+			continue
+		}
+		if !equalValues(expected, normalize(arg.Value)) {
+			return nil, me.diverge("interaction %d: %s: argument %d: expected %v, got %v", me.position+1, query, i+1, expected, arg.Value)
+		}
+	}
+	for recordedUUID, actualUUID := range substitutions {
+		me.substitutions[recordedUUID] = actualUUID
+	}
+	me.position++
+	return recorded, nil
+}
+
+//diverge marks the replay as diverged and fails the test
+func (me *Recorder) diverge(format string, args ...interface{}) error {
+	me.diverged = true
+	message := fmt.Sprintf(format, args...)
+	me.t.Errorf("dbxtest: %s: %s", me.path, message)
+	return fmt.Errorf("%w: %s", ErrReplayDiverged, message)
+}
+
+//substitute returns the replayed value of a recorded result value
+func (me *Recorder) substitute(recorded interface{}) interface{} {
+	text, ok := recorded.(string)
+	if !ok {
+		return recorded
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if actual, ok := me.substitutions[text]; ok {
+		return actual
+	}
+	return recorded
+}
+
+//recordingConnector creates recording or replaying connections
+type recordingConnector struct {
+	recorder *Recorder
+	inner    driver.Connector
+}
+
+//Connect implements driver.Connector
+func (me *recordingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if me.inner == nil {
+		return &recordingConn{recorder: me.recorder}, nil
+	}
+	inner, err := me.inner.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingConn{recorder: me.recorder, inner: inner}, nil
+}
+
+//Driver implements driver.Connector
+func (me *recordingConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+//recordingConn records the interactions of the inner connection, it replays them when there is no inner connection
+type recordingConn struct {
+	recorder *Recorder
+	inner    driver.Conn
+}
+
+//Prepare implements driver.Conn, statements are executed through the connection
+func (me *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{conn: me, query: query}, nil
+}
+
+//Close implements driver.Conn
+func (me *recordingConn) Close() error {
+	if me.inner == nil {
+		return nil
+	}
+	return me.inner.Close()
+}
+
+//Begin implements driver.Conn
+func (me *recordingConn) Begin() (driver.Tx, error) {
+	return me.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+//BeginTx implements driver.ConnBeginTx
+func (me *recordingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if me.inner == nil {
+		recorded, err := me.recorder.replay(EventBegin, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		if recorded.Error != "" {
+			return nil, recordedError(recorded.Error)
+		}
+		return &recordingTx{conn: me}, nil
+	}
+
+	var tx driver.Tx
+	var err error
+	if beginner, ok := me.inner.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(ctx, opts)
+	} else {
+		tx, err = me.inner.Begin()
+	}
+	me.recorder.record(&interaction{Kind: EventBegin, Error: errorMessage(err)})
+	if err != nil {
+		return nil, err
+	}
+	return &recordingTx{conn: me, inner: tx}, nil
+}
+
+//Ping implements driver.Pinger, it's not recorded
+func (me *recordingConn) Ping(ctx context.Context) error {
+	if pinger, ok := me.inner.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+//ExecContext implements driver.ExecerContext
+func (me *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if me.inner == nil {
+		recorded, err := me.recorder.replay(EventExec, query, args)
+		if err != nil {
+			return nil, err
+		}
+		if recorded.Error != "" {
+			return nil, recordedError(recorded.Error)
+		}
+		return &recordedResult{recorded: recorded}, nil
+	}
+
+	result, err := driverutil.Exec(ctx, me.inner, query, args)
+	recorded := &interaction{Kind: EventExec, SQL: query, Args: encodeArgs(args), Error: errorMessage(err)}
+	if err == nil {
+		recorded.LastInsertID, err = result.LastInsertId()
+		recorded.LastInsertIDError = errorMessage(err)
+		recorded.RowsAffected, err = result.RowsAffected()
+		recorded.RowsAffectedError = errorMessage(err)
+		err = nil
+	}
+	me.recorder.record(recorded)
+	return result, err
+}
+
+//QueryContext implements driver.QueryerContext, rows are read entirely so they can be recorded
+func (me *recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if me.inner == nil {
+		recorded, err := me.recorder.replay(EventQuery, query, args)
+		if err != nil {
+			return nil, err
+		}
+		if recorded.Error != "" {
+			return nil, recordedError(recorded.Error)
+		}
+		return me.replayRows(recorded)
+	}
+
+	rows, err := driverutil.Query(ctx, me.inner, query, args)
+	recorded := &interaction{Kind: EventQuery, SQL: query, Args: encodeArgs(args), Error: errorMessage(err)}
+	if err != nil {
+		me.recorder.record(recorded)
+		return nil, err
+	}
+
+	recorded.Columns = rows.Columns()
+	recorded.RowsError = errorMessage(rows.Err())
+	for _, values := range rows.Values() {
+		row := make([]*value, len(values))
+		for i, column := range values {
+			row[i] = encodeValue(column)
+		}
+		recorded.Rows = append(recorded.Rows, row)
+	}
+	me.recorder.record(recorded)
+	return me.replayRows(recorded)
+}
+
+//replayRows returns the rows of the recorded query
+func (me *recordingConn) replayRows(recorded *interaction) (driver.Rows, error) {
+	values := [][]driver.Value{}
+	for _, recordedRow := range recorded.Rows {
+		row := make([]driver.Value, len(recordedRow))
+		for i, column := range recordedRow {
+			decoded, err := column.decode()
+			if err != nil {
+				return nil, err
+			}
+			row[i] = me.recorder.substitute(decoded)
+		}
+		values = append(values, row)
+	}
+	return driverutil.NewRows(recorded.Columns, values, recordedError(recorded.RowsError)), nil
+}
+
+//recordingTx records the completion of the inner transaction
+type recordingTx struct {
+	conn  *recordingConn
+	inner driver.Tx
+}
+
+//Commit implements driver.Tx
+func (me *recordingTx) Commit() error {
+	return me.complete(EventCommit)
+}
+
+//Rollback implements driver.Tx
+func (me *recordingTx) Rollback() error {
+	return me.complete(EventRollback)
+}
+
+//complete commits or rolls back the transaction
+func (me *recordingTx) complete(kind string) error {
+	if me.inner == nil {
+		recorded, err := me.conn.recorder.replay(kind, "", nil)
+		if err != nil {
+			return err
+		}
+		return recordedError(recorded.Error)
+	}
+
+	var err error
+	if kind == EventCommit {
+		err = me.inner.Commit()
+	} else {
+		err = me.inner.Rollback()
+	}
+	me.conn.recorder.record(&interaction{Kind: kind, Error: errorMessage(err)})
+	return err
+}
+
+//recordingStmt executes prepared statements through the connection
+type recordingStmt struct {
+	conn  *recordingConn
+	query string
+}
+
+//Close implements driver.Stmt
+func (me *recordingStmt) Close() error {
+	return nil
+}
+
+//NumInput implements driver.Stmt, the number of arguments is not checked
+func (me *recordingStmt) NumInput() int {
+	return -1
+}
+
+//Exec implements driver.Stmt
+func (me *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return me.conn.ExecContext(context.Background(), me.query, driverutil.NamedValues(args))
+}
+
+//Query implements driver.Stmt
+func (me *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return me.conn.QueryContext(context.Background(), me.query, driverutil.NamedValues(args))
+}
+
+//ExecContext implements driver.StmtExecContext
+func (me *recordingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return me.conn.ExecContext(ctx, me.query, args)
+}
+
+//QueryContext implements driver.StmtQueryContext
+func (me *recordingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return me.conn.QueryContext(ctx, me.query, args)
+}
+
+//recordedResult is the recorded result of an exec statement
+type recordedResult struct {
+	recorded *interaction
+}
+
+//LastInsertId implements driver.Result
+func (me *recordedResult) LastInsertId() (int64, error) {
+	return me.recorded.LastInsertID, recordedError(me.recorded.LastInsertIDError)
+}
+
+//RowsAffected implements driver.Result
+func (me *recordedResult) RowsAffected() (int64, error) {
+	return me.recorded.RowsAffected, recordedError(me.recorded.RowsAffectedError)
+}
+
+//encodeArgs returns the golden values of the arguments
+func encodeArgs(args []driver.NamedValue) []*value {
+	values := make([]*value, len(args))
+	for i, arg := range args {
+		values[i] = encodeValue(arg.Value)
+	}
+	return values
+}
+
+//encodeValue returns the golden value of a driver value
+func encodeValue(v driver.Value) *value {
+	switch typed := v.(type) {
+	case nil:
+		return &value{Type: "null"}
+	case int64:
+		return &value{Type: "int64", Value: strconv.FormatInt(typed, 10)}
+	case float64:
+		return &value{Type: "float64", Value: strconv.FormatFloat(typed, 'g', -1, 64)}
+	case bool:
+		return &value{Type: "bool", Value: strconv.FormatBool(typed)}
+	case []byte:
+		return &value{Type: "bytes", Value: base64.StdEncoding.EncodeToString(typed)}
+	case string:
+		return &value{Type: "string", Value: typed}
+	case time.Time:
+		return &value{Type: "time", Value: typed.Format(time.RFC3339Nano)}
+	}
+	return &value{Type: "string", Value: fmt.Sprint(v)}
+}
+
+//decode returns the driver value of the golden value
+func (me *value) decode() (driver.Value, error) {
+	switch me.Type {
+	case "null":
+		return nil, nil
+	case "int64":
+		return strconv.ParseInt(me.Value, 10, 64)
+	case "float64":
+		return strconv.ParseFloat(me.Value, 64)
+	case "bool":
+		return strconv.ParseBool(me.Value)
+	case "bytes":
+		return base64.StdEncoding.DecodeString(me.Value)
+	case "string":
+		return me.Value, nil
+	case "time":
+		return time.Parse(time.RFC3339Nano, me.Value)
+	}
+	return nil, fmt.Errorf("unknown value type %s", me.Type)
+}
+
+//normalize returns the value as it's compared with a decoded golden value
+func normalize(v driver.Value) driver.Value {
+	decoded, err := encodeValue(v).decode()
+	if err != nil {
+		return v
+	}
+	return decoded
+}
+
+//equalValues determine if two decoded values are equal, times are equal if they represent the same instant
+func equalValues(a interface{}, b interface{}) bool {
+	if aTime, ok := a.(time.Time); ok {
+		bTime, ok := b.(time.Time)
+		return ok && aTime.Equal(bTime)
+	}
+	if aBytes, ok := a.([]byte); ok {
+		bBytes, ok := b.([]byte)
+		return ok && bytes.Equal(aBytes, bBytes)
+	}
+	return a == b
+}
+
+//isTime determine if the value is a time
+func isTime(v interface{}) bool {
+	_, ok := v.(time.Time)
+	return ok
+}
+
+//isUUID determine if the value is a UUID string
+func isUUID(v interface{}) bool {
+	text, ok := v.(string)
+	return ok && uuidPattern.MatchString(text)
+}
+
+//errorMessage returns the message of the error, empty if nil
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+//recordedError returns the error of a recorded message, nil if empty
+func recordedError(message string) error {
+	if message == "" {
+		return nil
+	}
+	return errors.New(message)
+}
+
+//NewRecorder create new recorder instance of the golden file. The mode is record when the DBXTEST_RECORD
+//environment variable is 1 or true, replay otherwise
+func NewRecorder(t testing.TB, golden string) *Recorder {
+	mode := ModeReplay
+	if record, _ := strconv.ParseBool(strings.TrimSpace(os.Getenv(RecordEnv))); record {
+		mode = ModeRecord
+	}
+	return &Recorder{
+		t:             t,
+		path:          golden,
+		mode:          mode,
+		substitutions: map[string]string{},
+	}
+}
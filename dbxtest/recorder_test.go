@@ -0,0 +1,130 @@
+package dbxtest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3" //needed
+	"github.com/supendi/dbx"
+)
+
+type recorderTestPerson struct {
+	ID        string    `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+//runRecorderScenario creates a person with a random id then reads it back
+func runRecorderScenario(t *testing.T, client *dbx.Client) (string, *recorderTestPerson) {
+	ctx := context.Background()
+	id := uuid.New().String()
+	dbContext := dbx.NewContext(client)
+	dbContext.AddStatement(dbx.NewStatement("CREATE TABLE IF NOT EXISTS person (id TEXT PRIMARY KEY, name TEXT NOT NULL, created_at TIMESTAMP NOT NULL)"))
+	insert := dbx.NewStatement("INSERT INTO person (id, name, created_at) VALUES (:id, :name, :created_at)")
+	insert.AddParameter("id", id)
+	insert.AddParameter("name", "Dadang")
+	insert.AddParameter("created_at", time.Now().UTC())
+	dbContext.AddStatement(insert)
+	if _, err := dbContext.SaveChanges(ctx); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+
+	statement := dbx.NewStatement("SELECT id, name, created_at FROM person WHERE id = :id")
+	statement.AddParameter("id", id)
+	rows, err := client.QueryStatementContext(ctx, statement)
+	if err != nil {
+		t.Fatalf("QueryStatement error: %s", err.Error())
+	}
+	defer rows.Close()
+	var person *recorderTestPerson
+	for rows.Next() {
+		person = &recorderTestPerson{}
+		if err := rows.StructScan(person); err != nil {
+			t.Fatalf("StructScan error: %s", err.Error())
+		}
+	}
+	return id, person
+}
+
+func Test_Recorder_RecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	golden := filepath.Join(dir, "testdata", "person.json")
+	dsn := filepath.Join(dir, "record.db")
+
+	var recordedPerson *recorderTestPerson
+	t.Run("record", func(t *testing.T) {
+		recorder := NewRecorder(t, golden).WithMode(ModeRecord).IgnoreTimes().IgnoreUUIDs()
+		id, person := runRecorderScenario(t, recorder.Client("sqlite3", dsn))
+		if person == nil || person.ID != id {
+			t.Fatalf("Expected recorded person %s, got %+v", id, person)
+		}
+		recordedPerson = person
+	})
+	if _, err := os.Stat(golden); err != nil {
+		t.Fatalf("Golden file is not written: %s", err.Error())
+	}
+	os.Remove(dsn)
+
+	t.Run("replay", func(t *testing.T) {
+		recorder := NewRecorder(t, golden).WithMode(ModeReplay).IgnoreTimes().IgnoreUUIDs()
+		id, person := runRecorderScenario(t, recorder.Client("sqlite3", "unused"))
+		if person == nil || person.ID != id {
+			t.Fatalf("Expected replayed person with the new id %s, got %+v", id, person)
+		}
+		if person.Name != "Dadang" || !person.CreatedAt.Equal(recordedPerson.CreatedAt) {
+			t.Errorf("Expected recorded values, got %+v", person)
+		}
+	})
+	if _, err := os.Stat(dsn); err == nil {
+		t.Errorf("Replay must not open the database")
+	}
+}
+
+func Test_Recorder_ReplayDivergence(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "golden.json")
+	content := `[
+  {"kind": "exec", "sql": "DELETE FROM person WHERE id = ?", "args": [{"type": "string", "value": "1"}], "rowsAffected": 1},
+  {"kind": "exec", "sql": "DELETE FROM person"}
+]`
+	if err := os.WriteFile(golden, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tb := &recorder{TB: t}
+	client := NewRecorder(tb, golden).WithMode(ModeReplay).Client("sqlite3", "unused")
+	statement := dbx.NewStatement("DELETE FROM person WHERE id = :id")
+	statement.AddParameter("id", "1")
+	result, err := client.ExecStatement(statement)
+	if err != nil {
+		t.Fatalf("Replay error: %s", err.Error())
+	}
+	if affected, _ := result.RowsAffected(); affected != 1 {
+		t.Errorf("Expected recorded rows affected, got %d", affected)
+	}
+
+	statement.AddParameter("id", "2")
+	_, err = client.ExecStatement(statement)
+	if !errors.Is(err, ErrReplayDiverged) {
+		t.Fatalf("Expected ErrReplayDiverged, got %v", err)
+	}
+	if len(tb.failures) != 1 || !strings.Contains(tb.failures[0], "expected exec DELETE FROM person, got exec DELETE FROM person WHERE id = ?") {
+		t.Errorf("Expected divergence to fail the test, got %v", tb.failures)
+	}
+}
+
+func Test_Recorder_MissingGoldenSkips(t *testing.T) {
+	skipped := false
+	t.Run("replay", func(t *testing.T) {
+		defer func() { skipped = t.Skipped() }()
+		NewRecorder(t, filepath.Join(t.TempDir(), "missing.json")).WithMode(ModeReplay).Open("sqlite3", "unused")
+	})
+	if !skipped {
+		t.Errorf("Expected the test to be skipped without a golden file")
+	}
+}
@@ -0,0 +1,44 @@
+package dbxtest
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/supendi/dbx/internal/driverutil"
+)
+
+//Rows represent the programmed rows of a query
+type Rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+//AddRow appends a row, values are given in column order and converted like query parameters,
+//so int, pointers and driver.Valuer values are accepted. It panics if a value can't be converted
+func (me *Rows) AddRow(values ...interface{}) *Rows {
+	if len(values) != len(me.columns) {
+		panic(fmt.Sprintf("dbxtest: row has %d values, expected %d", len(values), len(me.columns)))
+	}
+	row := make([]driver.Value, len(values))
+	for i, value := range values {
+		converted, err := driver.DefaultParameterConverter.ConvertValue(value)
+		if err != nil {
+			panic(fmt.Sprintf("dbxtest: column %s: %s", me.columns[i], err.Error()))
+		}
+		row[i] = converted
+	}
+	me.values = append(me.values, row)
+	return me
+}
+
+//cursor returns a new iterator over the rows
+func (me *Rows) cursor() driver.Rows {
+	return driverutil.NewRows(me.columns, me.values, nil)
+}
+
+//NewRows create new rows instance with the columns
+func NewRows(columns ...string) *Rows {
+	return &Rows{
+		columns: columns,
+	}
+}
@@ -0,0 +1,61 @@
+package dbxtest
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/supendi/dbx"
+	"github.com/supendi/dbx/internal/driverutil"
+)
+
+//Environment variables of the database used by NewTestClient
+const (
+	DriverEnv = driverutil.DriverEnv
+	DSNEnv    = driverutil.DSNEnv
+)
+
+//NewTestClient returns a client of the database configured by the DBXTEST_DRIVER and DBXTEST_DSN environment
+//variables, the driver defaults to postgres. The test is skipped when DBXTEST_DSN is not set.
+//
+//Everything the test does runs in a single transaction rolled back at cleanup, so tests do not need to clean
+//tables and parallel tests do not see each other's data. Transactions begun by the code under test, including
+//those of SaveChanges and CompleteTransaction, are savepoints inside it: their commit releases the savepoint
+//and their rollback rolls back to it.
+//
+//Transactions begun concurrently within the same test share the test transaction, so they are not isolated
+//from each other
+func NewTestClient(t testing.TB) *dbx.Client {
+	t.Helper()
+	driverName, dataSourceName := driverutil.Environment()
+	if dataSourceName == "" {
+		t.Skipf("dbxtest: %s is not set, skipping test which needs a database", DSNEnv)
+	}
+
+	isolated, err := driverutil.OpenIsolated(context.Background(), driverName, dataSourceName)
+	if err != nil {
+		t.Fatalf("dbxtest: connect to %s database: %s", driverName, err.Error())
+	}
+	db := sql.OpenDB(isolated)
+	t.Cleanup(func() {
+		db.Close()
+		if err := isolated.Close(); err != nil {
+			t.Errorf("dbxtest: rollback test transaction: %s", err.Error())
+		}
+	})
+	return dbx.NewClient(sqlx.NewDb(db, driverName))
+}
+
+//CheckTransactionLeaks fails the test at cleanup for each transaction begun by the client which is neither committed
+//nor rolled back, with the stack trace of where it began, and rolls it back. Call it once the client is created so
+//its cleanup runs before the ones closing the database
+func CheckTransactionLeaks(t testing.TB, client *dbx.Client) {
+	t.Helper()
+	t.Cleanup(func() {
+		for _, transaction := range client.OpenTransactions() {
+			transaction.Transaction.Rollback()
+			t.Errorf("dbxtest: transaction is still open at cleanup, it began at:\n%s", transaction.Stack)
+		}
+	})
+}
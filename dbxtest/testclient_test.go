@@ -0,0 +1,166 @@
+package dbxtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/supendi/dbx"
+)
+
+//setTestDatabase points NewTestClient to a SQLite database file with the schema created and returns a direct handle
+func setTestDatabase(t *testing.T) *sqlx.DB {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	t.Setenv(DriverEnv, "sqlite3")
+	t.Setenv(DSNEnv, dsn)
+
+	db, err := sqlx.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("Fatal create db error: %s", err.Error())
+	}
+	t.Cleanup(func() { db.Close() })
+	db.MustExec(`CREATE TABLE person (id INTEGER PRIMARY KEY, name TEXT NOT NULL, tags TEXT);
+		CREATE TABLE "order" (id INTEGER PRIMARY KEY, person_id INTEGER NOT NULL REFERENCES person (id), total REAL NOT NULL)`)
+	return db
+}
+
+func countRows(t *testing.T, db sqlx.Queryer, table string) int {
+	var count int
+	if err := sqlx.Get(db, &count, "SELECT COUNT(*) FROM "+table); err != nil {
+		t.Fatalf("Count %s error: %s", table, err.Error())
+	}
+	return count
+}
+
+func Test_NewTestClient_SkipsWithoutDatabase(t *testing.T) {
+	t.Setenv(DSNEnv, "")
+	skipped := false
+	t.Run("client", func(t *testing.T) {
+		defer func() { skipped = t.Skipped() }()
+		NewTestClient(t)
+	})
+	if !skipped {
+		t.Errorf("Expected the test to be skipped without %s", DSNEnv)
+	}
+}
+
+func Test_NewTestClient_RollsBackAtCleanup(t *testing.T) {
+	db := setTestDatabase(t)
+
+	t.Run("client", func(t *testing.T) {
+		client := NewTestClient(t)
+		dbContext := dbx.NewContext(client)
+		dbContext.AddStatements(
+			dbx.NewStatement("INSERT INTO person (id, name) VALUES (1, 'Dadang')"),
+			dbx.NewStatement("INSERT INTO person (id, name) VALUES (2, 'Asep')"),
+		)
+		if _, err := dbContext.SaveChanges(context.Background()); err != nil {
+			t.Fatalf("SaveChanges error: %s", err.Error())
+		}
+
+		dbContext.AddStatements(
+			dbx.NewStatement("INSERT INTO person (id, name) VALUES (3, 'Bowo')"),
+			dbx.NewStatement("INSERT INTO person (id, name) VALUES (1, 'Duplicate')"),
+		)
+		if _, err := dbContext.SaveChanges(context.Background()); err == nil {
+			t.Fatalf("Expected duplicate key error")
+		}
+
+		if _, err := client.ExecStatement(dbx.NewStatement("INSERT INTO person (id) VALUES (4)")); err == nil {
+			t.Fatalf("Expected not null error")
+		}
+
+		if count := countRows(t, client, "person"); count != 2 {
+			t.Errorf("Expected committed rows only, got %d rows", count)
+		}
+	})
+
+	if count := countRows(t, db, "person"); count != 0 {
+		t.Errorf("Expected test transaction to be rolled back, got %d rows", count)
+	}
+}
+
+func Test_NewTestClient_CompleteTransaction(t *testing.T) {
+	setTestDatabase(t)
+	client := NewTestClient(t)
+	dbContext := client.NewContext()
+
+	if _, err := dbContext.BeginTransaction(); err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+	if _, err := dbContext.ExecStatement(dbx.NewStatement("INSERT INTO person (id, name) VALUES (1, 'Dadang')")); err != nil {
+		t.Fatalf("ExecStatement error: %s", err.Error())
+	}
+	if err := dbContext.CompleteTransaction(); err != nil {
+		t.Fatalf("CompleteTransaction error: %s", err.Error())
+	}
+
+	tx, err := dbx.NewTransaction(client.DB)
+	if err != nil {
+		t.Fatalf("NewTransaction error: %s", err.Error())
+	}
+	if _, err := tx.ExecStatement(dbx.NewStatement("INSERT INTO person (id, name) VALUES (2, 'Asep')")); err != nil {
+		t.Fatalf("ExecStatement error: %s", err.Error())
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback error: %s", err.Error())
+	}
+
+	if count := countRows(t, client, "person"); count != 1 {
+		t.Errorf("Expected the committed row only, got %d rows", count)
+	}
+}
+
+func Test_LoadFixtures(t *testing.T) {
+	db := setTestDatabase(t)
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "person.yaml")
+	jsonPath := filepath.Join(dir, "order.json")
+	os.WriteFile(yamlPath, []byte(`person:
+  - id: 1
+    name: Dadang
+    tags: [admin, "sales"]
+  - id: 2
+    name: Asep
+`), 0644)
+	os.WriteFile(jsonPath, []byte(`{"order": [{"id": 10, "person_id": 2, "total": 1000.5}]}`), 0644)
+
+	t.Run("client", func(t *testing.T) {
+		client := NewTestClient(t)
+		LoadFixtures(t, client, yamlPath, jsonPath)
+
+		var tags string
+		if err := client.Get(&tags, "SELECT tags FROM person WHERE id = 1"); err != nil {
+			t.Fatalf("Get error: %s", err.Error())
+		}
+		if tags != `["admin","sales"]` {
+			t.Errorf("Expected list as JSON text, got %s", tags)
+		}
+		var total float64
+		if err := client.Get(&total, `SELECT total FROM "order" WHERE person_id = 2`); err != nil {
+			t.Fatalf("Get error: %s", err.Error())
+		}
+		if total != 1000.5 {
+			t.Errorf("Expected order total 1000.5, got %v", total)
+		}
+	})
+
+	if count := countRows(t, db, "person"); count != 0 {
+		t.Errorf("Expected fixtures to be rolled back, got %d rows", count)
+	}
+}
+
+func Test_parseFixtures_KeepsTableOrder(t *testing.T) {
+	tables, err := parseFixtures([]byte(`{"person": [{"id": 1}], "order": [], "address": [{"id": 2}, {"id": 3}]}`))
+	if err != nil {
+		t.Fatalf("parseFixtures error: %s", err.Error())
+	}
+	if len(tables) != 3 || tables[0].name != "person" || tables[1].name != "order" || tables[2].name != "address" || len(tables[2].rows) != 2 {
+		t.Errorf("Unexpected tables %+v", tables)
+	}
+	if _, err := parseFixtures([]byte(`[1, 2]`)); err == nil {
+		t.Errorf("Expected an error for a list document")
+	}
+}
@@ -0,0 +1,107 @@
+package dbxtest
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/supendi/dbx"
+)
+
+//Transaction is a fake transaction implementing dbx.Transactioner. Unlike transactions of a fake Client,
+//it records statements as they are given, with their named parameters
+type Transaction struct {
+	fake       *Fake
+	tx         int
+	isComplete bool
+}
+
+var _ dbx.Transactioner = &Transaction{}
+
+//IsComplete determine if the transaction is already committed or rolledback
+func (me *Transaction) IsComplete() bool {
+	return me.isComplete
+}
+
+//ExecStatement records the statement and returns its programmed result
+func (me *Transaction) ExecStatement(statement *dbx.Statement) (sql.Result, error) {
+	return me.ExecStatementContext(context.Background(), statement)
+}
+
+//ExecStatementContext records the statement and returns its programmed result
+func (me *Transaction) ExecStatementContext(ctx context.Context, statement *dbx.Statement) (sql.Result, error) {
+	if me.isComplete {
+		return nil, sql.ErrTxDone
+	}
+	statement, err := statement.Bind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return me.fake.exec(me.call(EventExec, statement))
+}
+
+//QueryStatement records the statement and returns its programmed rows
+func (me *Transaction) QueryStatement(statement *dbx.Statement) (*dbx.Rows, error) {
+	return me.QueryStatementContext(context.Background(), statement)
+}
+
+//QueryStatementContext records the statement and returns its programmed rows
+func (me *Transaction) QueryStatementContext(ctx context.Context, statement *dbx.Statement) (*dbx.Rows, error) {
+	if me.isComplete {
+		return nil, sql.ErrTxDone
+	}
+	statement, err := statement.Bind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := me.fake.query(me.call(EventQuery, statement))
+	if err != nil {
+		return nil, err
+	}
+	db := sqlx.NewDb(me.fake.db, DriverName)
+	queried, err := db.QueryxContext(context.WithValue(ctx, recordedCallKey, rows), statement.SQL)
+	if err != nil {
+		return nil, err
+	}
+	return &dbx.Rows{Rows: queried}, nil
+}
+
+//Commit records the commit of the transaction
+func (me *Transaction) Commit() error {
+	if me.isComplete {
+		return sql.ErrTxDone
+	}
+	me.isComplete = true
+	return me.fake.complete(EventCommit, me.tx)
+}
+
+//Rollback records the rollback of the transaction
+func (me *Transaction) Rollback() error {
+	if me.isComplete {
+		return sql.ErrTxDone
+	}
+	me.isComplete = true
+	return me.fake.complete(EventRollback, me.tx)
+}
+
+//call returns the call of the statement
+func (me *Transaction) call(kind string, statement *dbx.Statement) *Call {
+	return &Call{
+		Kind:      kind,
+		SQL:       statement.SQL,
+		Statement: statement,
+		Tx:        me.tx,
+	}
+}
+
+//BeginTransaction begins a fake transaction recording named statements
+func (me *Fake) BeginTransaction() (*Transaction, error) {
+	tx, err := me.begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Transaction{
+		fake: me,
+		tx:   tx,
+	}, nil
+}
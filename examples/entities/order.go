@@ -32,17 +32,17 @@ func (me *OrderRepository) setStatementParam(statement *dbx.Statement, order *Or
 	statement.AddParameter(`updated_at`, order.UpdatedAt)
 }
 
-//Add adds new order
+//Add adds new order, created_at is stamped by SaveChanges
 func (me *OrderRepository) Add(order *Order) {
-	statement := dbx.NewStatement(`INSERT INTO "order" (id, order_number, order_date, total, created_at, updated_at) VALUES (:id, :order_number, :order_date, :total, :created_at, :updated_at)`)
+	statement := dbx.NewStatement(`INSERT INTO "order" (id, order_number, order_date, total, created_at, updated_at) VALUES (:id, :order_number, :order_date, :total, :created_at, :updated_at)`).Stamp(dbx.CreatedAtColumn)
 	me.setStatementParam(statement, order)
 
 	me.dbContext.AddStatement(statement)
 }
 
-//Update updates existing order
+//Update updates existing order, updated_at is stamped by SaveChanges
 func (me *OrderRepository) Update(order *Order) {
-	statement := dbx.NewStatement(`UPDATE "order" SET order_number = :order_number, order_date = :order_date, total = :total, created_at = :created_at, updated_at = :updated_at WHERE id=:id`)
+	statement := dbx.NewStatement(`UPDATE "order" SET order_number = :order_number, order_date = :order_date, total = :total, updated_at = :updated_at WHERE id=:id`).Stamp(dbx.UpdatedAtColumn)
 	me.setStatementParam(statement, order)
 
 	me.dbContext.AddStatement(statement)
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "order" (
+    id UUID PRIMARY KEY,
+    order_number VARCHAR(50) NOT NULL,
+    order_date TIMESTAMP NOT NULL,
+    total NUMERIC(18, 2) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NULL
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS person (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NULL
+);
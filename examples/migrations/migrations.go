@@ -0,0 +1,17 @@
+package migrations
+
+import (
+	"embed"
+
+	"github.com/supendi/dbx/migrate"
+)
+
+//files contains the SQL migrations of the example schema
+//
+//go:embed *.sql
+var files embed.FS
+
+//Load returns the migrations of the example schema
+func Load() ([]*migrate.Migration, error) {
+	return migrate.Load(files, ".")
+}
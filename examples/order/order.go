@@ -70,7 +70,6 @@ func (me *OrderService) CreateOrder(ctx context.Context, request *OrderCreateReq
 		OrderNumber: &request.OrderNumber,
 		OrderDate:   request.OrderDate,
 		Total:       request.Total,
-		CreatedAt:   time.Now(),
 	}
 	return me.orderRepository.Add(ctx, newOrder)
 }
@@ -86,13 +85,9 @@ func (me *OrderService) UpdateOrder(ctx context.Context, request *OrderUpdateReq
 		return nil, ErrOrderNotFound
 	}
 
-	updatedDate := &time.Time{}
-	*updatedDate = time.Now()
-
 	existingOrder.OrderNumber = request.OrderNumber
 	existingOrder.OrderDate = request.OrderDate
 	existingOrder.Total = request.Total
-	existingOrder.UpdatedAt = updatedDate
 
 	return me.orderRepository.Update(ctx, existingOrder)
 }
@@ -3,26 +3,51 @@ package order_test
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/supendi/dbx"
+	"github.com/supendi/dbx/dbxtest"
 
 	"github.com/supendi/dbx/examples/entities"
+	"github.com/supendi/dbx/examples/migrations"
 	"github.com/supendi/dbx/examples/order"
 	"github.com/supendi/dbx/examples/order/postgres"
+	"github.com/supendi/dbx/migrate"
 )
 
-func getSqlxDb() (*sqlx.DB, error) {
-	db, err := sqlx.Open("postgres", "host=localhost dbname=dbx user=postgres password=irpan123 sslmode=disable")
+//getSqlxDb returns a database handle replaying testdata/<test name>.json, run the tests with DBXTEST_RECORD=1
+//against Postgres to record the golden files. The database is configured by DBXTEST_ environment variables,
+//see dbx.ConfigFromEnv
+func getSqlxDb(t *testing.T) (*sqlx.DB, error) {
+	config, err := dbx.ConfigFromEnv("DBXTEST")
 	if err != nil {
-		return db, err
+		return nil, err
+	}
+	config.Driver = "postgres"
+	if config.DSN == "" && config.Host == "" {
+		config.Host, config.Database, config.User, config.SSLMode = "localhost", "dbx", "postgres", "disable"
 	}
+	dsn, err := config.DataSourceName()
+	if err != nil {
+		return nil, err
+	}
+	recorder := dbxtest.NewRecorder(t, filepath.Join("testdata", t.Name()+".json")).IgnoreTimes().IgnoreUUIDs()
+	db := recorder.Open("postgres", dsn)
 
 	err = db.Ping()
 
+	if err != nil {
+		return nil, err
+	}
+	schemaMigrations, err := migrations.Load()
+	if err != nil {
+		return nil, err
+	}
+	err = migrate.NewMigrator(dbx.NewClient(db), schemaMigrations).Up(context.Background())
 	if err != nil {
 		return nil, err
 	}
@@ -33,8 +58,8 @@ func getSqlxDb() (*sqlx.DB, error) {
 	return db, nil
 }
 
-func initDBContext() (*entities.DBContext, error) {
-	db, err := getSqlxDb()
+func initDBContext(t *testing.T) (*entities.DBContext, error) {
+	db, err := getSqlxDb(t)
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +73,7 @@ func initDBContext() (*entities.DBContext, error) {
 }
 
 func TestCreateOrder(t *testing.T) {
-	dbContext, err := initDBContext()
+	dbContext, err := initDBContext(t)
 	defer dbContext.Close()
 
 	if err != nil {
@@ -67,10 +92,13 @@ func TestCreateOrder(t *testing.T) {
 	if err != nil {
 		t.Error(err.Error())
 	}
+	if newOrder.CreatedAt.IsZero() {
+		t.Error("Created order should have its created at stamped")
+	}
 }
 
 func TestUpdateOrder(t *testing.T) {
-	dbContext, err := initDBContext()
+	dbContext, err := initDBContext(t)
 
 	dbContext.BeginTransaction()
 	defer dbContext.Close()
@@ -103,6 +131,9 @@ func TestUpdateOrder(t *testing.T) {
 	if err != nil {
 		t.Error("Update error: " + err.Error())
 	}
+	if updatedOrder.UpdatedAt == nil || !updatedOrder.CreatedAt.Equal(createdOrder.CreatedAt) {
+		t.Error("Updated order should have its updated at stamped and keep its created at")
+	}
 	err = dbContext.CompleteTransaction()
 	if err != nil {
 		t.Error("Transaction complete error: " + err.Error())
@@ -112,7 +143,7 @@ func TestUpdateOrder(t *testing.T) {
 }
 
 func TestUpdateOrderMustError(t *testing.T) {
-	dbContext, err := initDBContext()
+	dbContext, err := initDBContext(t)
 
 	dbContext.BeginTransaction()
 	defer dbContext.Close()
@@ -164,7 +195,7 @@ func TestUpdateOrderMustError(t *testing.T) {
 }
 
 func TestGetOrder(t *testing.T) {
-	dbContext, err := initDBContext()
+	dbContext, err := initDBContext(t)
 
 	defer dbContext.Close()
 
@@ -204,7 +235,7 @@ func TestGetOrder(t *testing.T) {
 }
 
 func TestListOrder(t *testing.T) {
-	dbContext, err := initDBContext()
+	dbContext, err := initDBContext(t)
 
 	defer dbContext.Close()
 
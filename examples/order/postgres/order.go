@@ -14,7 +14,7 @@ type OrderRepository struct {
 	dbContext *entities.DBContext
 }
 
-//Add adds a new order into database
+//Add adds a new order into database and returns it as saved, with its created_at stamped by SaveChanges
 func (me *OrderRepository) Add(ctx context.Context, order *order.Order) (*order.Order, error) {
 	order.ID = uuid.New().String()
 	me.dbContext.Order.Add(&entities.Order{
@@ -24,11 +24,13 @@ func (me *OrderRepository) Add(ctx context.Context, order *order.Order) (*order.
 		Total:       order.Total,
 		CreatedAt:   order.CreatedAt,
 	})
-	_, err := me.dbContext.SaveChanges(ctx)
-	return order, err
+	if _, err := me.dbContext.SaveChanges(ctx); err != nil {
+		return order, err
+	}
+	return me.GetByID(ctx, order.ID)
 }
 
-//Update updates existing order in database
+//Update updates existing order in database and returns it as saved, with its updated_at stamped by SaveChanges
 func (me *OrderRepository) Update(ctx context.Context, order *order.Order) (*order.Order, error) {
 	me.dbContext.Order.Update(&entities.Order{
 		ID:          order.ID,
@@ -38,8 +40,10 @@ func (me *OrderRepository) Update(ctx context.Context, order *order.Order) (*ord
 		CreatedAt:   order.CreatedAt,
 		UpdatedAt:   order.UpdatedAt,
 	})
-	_, err := me.dbContext.SaveChanges(ctx)
-	return order, err
+	if _, err := me.dbContext.SaveChanges(ctx); err != nil {
+		return order, err
+	}
+	return me.GetByID(ctx, order.ID)
 }
 
 //Delete deletes existing order
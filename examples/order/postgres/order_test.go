@@ -0,0 +1,84 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/supendi/dbx/dbxtest"
+	"github.com/supendi/dbx/examples/entities"
+	"github.com/supendi/dbx/examples/order"
+	"github.com/supendi/dbx/examples/order/postgres"
+)
+
+func newRepository() (*postgres.OrderRepository, *dbxtest.Fake) {
+	fake := dbxtest.NewFake().WithDriverName("postgres")
+	return postgres.NewOrderRepository(entities.NewDBContext(fake.Context())), fake
+}
+
+func TestOrderRepository_Add(t *testing.T) {
+	repository, fake := newRepository()
+	fake.OnExec(`^INSERT INTO "order"`).ReturnResult(0, 1).Once()
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	fake.OnQuery(`FROM "order" WHERE id = \$1`).ReturnRows(
+		dbxtest.NewRows("id", "order_number", "order_date", "total", "created_at", "updated_at").
+			AddRow("order-1", "SO-001", createdAt, 1000.0, createdAt, nil),
+	).Once()
+
+	orderNumber := "SO-001"
+	newOrder, err := repository.Add(context.Background(), &order.Order{OrderNumber: &orderNumber, Total: 1000})
+	if err != nil {
+		t.Fatalf("Add error: %s", err.Error())
+	}
+	if newOrder == nil || !newOrder.CreatedAt.Equal(createdAt) {
+		t.Fatalf("Expected the order as saved, got %+v", newOrder)
+	}
+
+	calls := fake.CallsMatching(`^INSERT INTO "order"`)
+	if len(calls) != 1 || calls[0].Args[0] == "" || calls[0].Args[1] != orderNumber {
+		t.Errorf("Unexpected insert statement %+v", calls)
+	}
+	reads := fake.CallsMatching(`FROM "order" WHERE id = \$1`)
+	if len(reads) != 1 || len(calls) != 1 || reads[0].Args[0] != calls[0].Args[0] {
+		t.Errorf("Expected the inserted order to be read back, got %+v", reads)
+	}
+	fake.AssertNoTransaction(t)
+	fake.AssertExpectations(t)
+}
+
+func TestOrderRepository_AddError(t *testing.T) {
+	repository, fake := newRepository()
+	errNotNull := errors.New("null value in column order_number")
+	fake.OnExec(`^INSERT INTO "order"`).ReturnError(errNotNull)
+
+	_, err := repository.Add(context.Background(), &order.Order{})
+	if !errors.Is(err, errNotNull) {
+		t.Errorf("Expected programmed error, got %v", err)
+	}
+}
+
+func TestOrderRepository_GetByID(t *testing.T) {
+	repository, fake := newRepository()
+	orderDate := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	fake.OnQuery(`FROM "order" WHERE id = \$1`).ReturnRows(
+		dbxtest.NewRows("id", "order_number", "order_date", "total", "created_at", "updated_at").
+			AddRow("order-1", "SO-001", orderDate, 1000.5, orderDate, nil),
+	).Once()
+
+	existingOrder, err := repository.GetByID(context.Background(), "order-1")
+	if err != nil {
+		t.Fatalf("GetByID error: %s", err.Error())
+	}
+	if existingOrder == nil || existingOrder.ID != "order-1" || *existingOrder.OrderNumber != "SO-001" || existingOrder.Total != 1000.5 {
+		t.Fatalf("Unexpected order %+v", existingOrder)
+	}
+	if !existingOrder.OrderDate.Equal(orderDate) || existingOrder.UpdatedAt != nil {
+		t.Errorf("Unexpected order dates %+v", existingOrder)
+	}
+
+	missingOrder, err := repository.GetByID(context.Background(), "order-2")
+	if err != nil || missingOrder != nil {
+		t.Errorf("Expected no order, got %+v, %v", missingOrder, err)
+	}
+}
@@ -0,0 +1,327 @@
+package gen
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/supendi/dbx"
+)
+
+//DBContextFile is the name of the generated file aggregating every repository
+const DBContextFile = "dbcontext.go"
+
+var (
+	regionStartPattern = regexp.MustCompile(`^\s*//dbx:preserve\s+(\w+)\s*$`)
+	regionEndPattern   = regexp.MustCompile(`^\s*//dbx:end\s*$`)
+)
+
+//File represent a generated Go file
+type File struct {
+	Path    string
+	Source  []byte
+	Changed bool
+}
+
+//field represent a struct field mapped to a column
+type field struct {
+	Name       string
+	Type       string
+	FilterType string
+	Column     string
+	Predicate  string
+	Param      string
+}
+
+//entity represent the data used to generate an entity file
+type entity struct {
+	Package         string
+	Struct          string
+	Var             string
+	Table           string
+	Imports         []string //standard library imports
+	Fields          []*field
+	Keys            []*field
+	SoftDelete      bool //the table has a nullable deleted_at column
+	Tenant          bool //the table has a tenant_id column, its statements are tenant scoped
+	TenantPredicate string
+	QuotedTable     string
+	KeyWhere        string
+	InsertStamp     string //stamps created_at and created_by
+	UpdateStamp     string //stamps updated_at and updated_by
+	InsertSQL       string
+	UpdateSQL       string
+	DeleteSQL       string
+	SelectSQL       string
+	GetAllSQL       string
+	GetByIDSQL      string
+	Regions         map[string]string
+}
+
+//KeyParams returns the key parameters of the repository methods signature
+func (me *entity) KeyParams() string {
+	params := make([]string, len(me.Keys))
+	for i, key := range me.Keys {
+		params[i] = key.Param + " " + key.Type
+	}
+	return strings.Join(params, ", ")
+}
+
+//Affects returns the call declaring the record changed by a statement, empty if the table has no primary key
+func (me *entity) Affects() string {
+	if len(me.Keys) == 0 {
+		return ""
+	}
+	columns := make([]string, len(me.Keys))
+	for i, key := range me.Keys {
+		columns[i] = "`" + key.Column + "`"
+	}
+	return ".Affects(`" + me.Table + "`, " + strings.Join(columns, ", ") + ")"
+}
+
+//newEntity returns the generator data of a table
+func newEntity(packageName string, table *dbx.Table) *entity {
+	e := &entity{
+		Package: packageName,
+		Struct:  StructName(table.Name),
+		Table:   table.Name,
+	}
+	e.Var = paramName(e.Struct)
+
+	imports := map[string]bool{"context": true, "strings": true}
+	var columns, params, assignments, keyPredicates, insertStamps, updateStamps []string
+	for _, column := range table.Columns {
+		goType, importPath := GoType(column.Type, column.Nullable)
+		if importPath != "" {
+			imports[importPath] = true
+		}
+		f := &field{
+			Name:      FieldName(column.Name),
+			Type:      goType,
+			Column:    column.Name,
+			Predicate: quoteIdentifier(column.Name) + " = :" + column.Name,
+		}
+		f.Param = paramName(f.Name)
+		if f.Param == e.Var {
+			f.Param += "Value"
+		}
+		if goType != "[]byte" {
+			f.FilterType = "*" + strings.TrimPrefix(goType, "*")
+		}
+		e.Fields = append(e.Fields, f)
+
+		columns = append(columns, quoteIdentifier(column.Name))
+		if column.Name == dbx.TenantColumn && !table.IsPrimaryKey(column.Name) {
+			e.Tenant = true
+			e.TenantPredicate = quoteIdentifier(column.Name) + " = :" + dbx.TenantParameter
+			params = append(params, ":"+dbx.TenantParameter)
+			continue
+		}
+		params = append(params, ":"+column.Name)
+		if table.IsPrimaryKey(column.Name) {
+			keyPredicates = append(keyPredicates, f.Predicate)
+		} else if column.Name == dbx.DeletedAtColumn && column.Nullable {
+			e.SoftDelete = true
+		} else if column.Name == dbx.CreatedAtColumn || column.Name == dbx.CreatedByColumn {
+			insertStamps = append(insertStamps, "`"+column.Name+"`")
+		} else {
+			if column.Name == dbx.UpdatedAtColumn || column.Name == dbx.UpdatedByColumn {
+				updateStamps = append(updateStamps, "`"+column.Name+"`")
+			}
+			assignments = append(assignments, f.Predicate)
+		}
+	}
+	if len(insertStamps) > 0 {
+		e.InsertStamp = ".Stamp(" + strings.Join(insertStamps, ", ") + ")"
+	}
+	if len(updateStamps) > 0 {
+		e.UpdateStamp = ".Stamp(" + strings.Join(updateStamps, ", ") + ")"
+	}
+	for _, key := range table.PrimaryKey {
+		for _, f := range e.Fields {
+			if f.Column == key {
+				e.Keys = append(e.Keys, f)
+			}
+		}
+	}
+
+	quotedTable := `"` + table.Name + `"`
+	e.QuotedTable = quotedTable
+	e.InsertSQL = "INSERT INTO " + quotedTable + " (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(params, ", ") + ")"
+	e.SelectSQL = "SELECT " + strings.Join(columns, ", ") + " FROM " + quotedTable
+	e.GetAllSQL = e.SelectSQL
+	if e.Tenant {
+		e.GetAllSQL += " WHERE " + e.TenantPredicate
+		keyPredicates = append(keyPredicates, e.TenantPredicate)
+	}
+	if len(e.Keys) > 0 {
+		e.KeyWhere = strings.Join(keyPredicates, " AND ")
+		where := " WHERE " + e.KeyWhere
+		if len(assignments) > 0 {
+			e.UpdateSQL = "UPDATE " + quotedTable + " SET " + strings.Join(assignments, ", ") + where
+		}
+		e.DeleteSQL = "DELETE FROM " + quotedTable + where
+		e.GetByIDSQL = e.SelectSQL + where
+	}
+
+	for importPath := range imports {
+		e.Imports = append(e.Imports, importPath)
+	}
+	sort.Strings(e.Imports)
+	return e
+}
+
+//GoType returns the Go type of a database column type and the standard package it needs to import.
+//Nullable columns are mapped to pointer types, except []byte
+func GoType(dbType string, nullable bool) (string, string) {
+	dbType = strings.ToLower(dbType)
+	goType, importPath := "string", ""
+	switch {
+	case strings.Contains(dbType, "bool"):
+		goType = "bool"
+	case strings.Contains(dbType, "int") && !strings.Contains(dbType, "interval") && !strings.Contains(dbType, "point"):
+		goType = "int64"
+	case strings.Contains(dbType, "numeric"), strings.Contains(dbType, "decimal"), strings.Contains(dbType, "real"),
+		strings.Contains(dbType, "double"), strings.Contains(dbType, "float"), strings.Contains(dbType, "money"):
+		goType = "float64"
+	case strings.Contains(dbType, "timestamp"), strings.Contains(dbType, "date"), dbType == "time" || strings.HasPrefix(dbType, "time "):
+		goType, importPath = "time.Time", "time"
+	case strings.Contains(dbType, "bytea"), strings.Contains(dbType, "blob"), strings.Contains(dbType, "binary"):
+		return "[]byte", ""
+	}
+	if nullable {
+		goType = "*" + goType
+	}
+	return goType, importPath
+}
+
+//Generator generates entity structs, repositories and the DBContext aggregating them.
+//Code written between //dbx:preserve <name> and //dbx:end markers is kept on regeneration.
+//The generated SQL quotes identifiers with double quotes, so it's meant for Postgres and SQLite
+type Generator struct {
+	packageName string
+	driverName  string
+}
+
+//WithDriver set the driver of the database the generated SQL runs on, Generate fails if its identifiers are not
+//quoted with double quotes
+func (me *Generator) WithDriver(driverName string) *Generator {
+	me.driverName = driverName
+	return me
+}
+
+//Generate renders the files of the tables into dir. Existing files are read to keep their preserved regions,
+//nothing is written
+func (me *Generator) Generate(dir string, tables []*dbx.Table) ([]*File, error) {
+	switch me.driverName {
+	case "", "postgres", "pgx", "sqlite3", "sqlite":
+	default:
+		return nil, fmt.Errorf("Generating entities is not supported for driver %s, the generated SQL quotes identifiers with double quotes", me.driverName)
+	}
+	files := []*File{}
+	entities := []*entity{}
+	for _, table := range tables {
+		e := newEntity(me.packageName, table)
+		file, err := me.render(filepath.Join(dir, fileName(table.Name)), entityTemplate, e, &e.Regions)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+		entities = append(entities, e)
+	}
+
+	data := &struct {
+		Package  string
+		Entities []*entity
+		Regions  map[string]string
+	}{Package: me.packageName, Entities: entities}
+	file, err := me.render(filepath.Join(dir, DBContextFile), dbContextTemplate, data, &data.Regions)
+	if err != nil {
+		return nil, err
+	}
+	return append(files, file), nil
+}
+
+//Write generates the files of the tables into dir and writes those which changed
+func (me *Generator) Write(dir string, tables []*dbx.Table) ([]*File, error) {
+	files, err := me.Generate(dir, tables)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if !file.Changed {
+			continue
+		}
+		if err := os.WriteFile(file.Path, file.Source, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+//render executes the template with the preserved regions of the existing file and formats the result
+func (me *Generator) render(path string, tmpl *template.Template, data interface{}, regions *map[string]string) (*File, error) {
+	previous, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	*regions = extractRegions(previous)
+
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		return nil, err
+	}
+	source, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &File{
+		Path:    path,
+		Source:  source,
+		Changed: !bytes.Equal(previous, source),
+	}, nil
+}
+
+//extractRegions returns the content of every preserved region keyed by region name
+func extractRegions(source []byte) map[string]string {
+	regions := map[string]string{}
+	name := ""
+	var content strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(source))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name == "" {
+			if match := regionStartPattern.FindStringSubmatch(line); match != nil {
+				name = match[1]
+				content.Reset()
+			}
+			continue
+		}
+		if regionEndPattern.MatchString(line) {
+			regions[name] = content.String()
+			name = ""
+			continue
+		}
+		content.WriteString(line + "\n")
+	}
+	return regions
+}
+
+//NewGenerator create new generator instance writing files of the package
+func NewGenerator(packageName string) *Generator {
+	return &Generator{
+		packageName: packageName,
+	}
+}
@@ -0,0 +1,190 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/supendi/dbx"
+)
+
+func testTables() []*dbx.Table {
+	order := &dbx.Table{
+		Name:       "order",
+		PrimaryKey: []string{"id"},
+		Columns: []*dbx.Column{
+			{Name: "id", Type: "uuid"},
+			{Name: "order_number", Type: "character varying"},
+			{Name: "order_date", Type: "timestamp without time zone"},
+			{Name: "total", Type: "numeric"},
+			{Name: "created_at", Type: "timestamp without time zone"},
+			{Name: "updated_at", Type: "timestamp without time zone", Nullable: true},
+		},
+	}
+	return []*dbx.Table{order}
+}
+
+func Test_Generator_Generate(t *testing.T) {
+	files, err := NewGenerator("entities").Generate(t.TempDir(), testTables())
+	if err != nil {
+		t.Fatalf("Generate error: %s", err.Error())
+	}
+	if len(files) != 2 || filepath.Base(files[0].Path) != "order.go" || filepath.Base(files[1].Path) != DBContextFile {
+		t.Fatalf("Expected order.go and dbcontext.go, got %d files", len(files))
+	}
+
+	source := string(files[0].Source)
+	for _, expected := range []string{
+		"type Order struct {",
+		"UpdatedAt   *time.Time `db:\"updated_at\"`",
+		"UpdatedAt   *time.Time\n",
+		"func (me *OrderRepository) Add(order *Order) {",
+		"`UPDATE \"order\" SET order_number = :order_number, order_date = :order_date, total = :total, updated_at = :updated_at WHERE id = :id`",
+		"func (me *OrderRepository) Delete(id string) {",
+		"WHERE id = :id`).Affects(`order`, `id`).Stamp(`updated_at`)",
+		":created_at, :updated_at)`).Affects(`order`, `id`).Stamp(`created_at`)",
+		"func (me *OrderRepository) GetByID(ctx context.Context, id string) (*Order, error) {",
+		"func (me *OrderRepository) Find(ctx context.Context, filter *OrderFilter) ([]*Order, error) {\n\tif filter == nil {\n\t\tfilter = &OrderFilter{}\n\t}",
+	} {
+		if !strings.Contains(source, expected) {
+			t.Errorf("Generated entity expected to contain %q:\n%s", expected, source)
+		}
+	}
+	if !strings.Contains(string(files[1].Source), "Order:   NewOrderRepository(dbContext),") {
+		t.Errorf("Generated DBContext expected to aggregate OrderRepository:\n%s", files[1].Source)
+	}
+}
+
+func Test_Generator_RejectsMySQL(t *testing.T) {
+	if _, err := NewGenerator("entities").WithDriver("mysql").Generate(t.TempDir(), testTables()); err == nil {
+		t.Errorf("Expected the generator to reject a driver whose identifiers are not double quoted")
+	}
+	if _, err := NewGenerator("entities").WithDriver("sqlite3").Generate(t.TempDir(), testTables()); err != nil {
+		t.Errorf("Generate error: %s", err.Error())
+	}
+}
+
+func Test_Generator_WriteIsIdempotent(t *testing.T) {
+	dir := t.TempDir()
+	generator := NewGenerator("entities")
+	if _, err := generator.Write(dir, testTables()); err != nil {
+		t.Fatalf("Write error: %s", err.Error())
+	}
+
+	files, err := generator.Write(dir, testTables())
+	if err != nil {
+		t.Fatalf("Write error: %s", err.Error())
+	}
+	for _, file := range files {
+		if file.Changed {
+			t.Errorf("%s must not change when it is regenerated from the same schema", file.Path)
+		}
+	}
+}
+
+func Test_Generator_KeepsPreservedRegions(t *testing.T) {
+	dir := t.TempDir()
+	generator := NewGenerator("entities")
+	if _, err := generator.Write(dir, testTables()); err != nil {
+		t.Fatalf("Write error: %s", err.Error())
+	}
+
+	path := filepath.Join(dir, "order.go")
+	source, _ := os.ReadFile(path)
+	edited := strings.Replace(string(source), "//dbx:preserve imports\n", "//dbx:preserve imports\nimport \"errors\"\n", 1)
+	edited = strings.Replace(edited, "//dbx:preserve code\n", "//dbx:preserve code\n\n//ErrOrderNotFound is returned when order does not exist\nvar ErrOrderNotFound = errors.New(\"Order is not found\")\n", 1)
+	edited = strings.Replace(edited, "type Order struct {", "type Order struct {\n\tNote string", 1)
+	if err := os.WriteFile(path, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tables := testTables()
+	tables[0].Columns = append(tables[0].Columns, &dbx.Column{Name: "discount", Type: "numeric", Nullable: true})
+	if _, err := generator.Write(dir, tables); err != nil {
+		t.Fatalf("Write error: %s", err.Error())
+	}
+	regenerated, _ := os.ReadFile(path)
+	for _, expected := range []string{"import \"errors\"", "var ErrOrderNotFound = errors.New(\"Order is not found\")", "Discount    *float64"} {
+		if !strings.Contains(string(regenerated), expected) {
+			t.Errorf("Regenerated file expected to contain %q:\n%s", expected, regenerated)
+		}
+	}
+	if strings.Contains(string(regenerated), "Note string") {
+		t.Errorf("Edits outside preserved regions expected to be overwritten")
+	}
+}
+
+func Test_Naming(t *testing.T) {
+	cases := map[string]string{
+		StructName("order"):       "Order",
+		StructName("users"):       "User",
+		StructName("categories"):  "Category",
+		StructName("address"):     "Address",
+		FieldName("user_id"):      "UserID",
+		FieldName("api_url"):      "APIURL",
+		ColumnName("OrderNumber"): "order_number",
+		ColumnName("UserID"):      "user_id",
+		ColumnName("HTTPStatus"):  "http_status",
+		paramName("ID"):           "id",
+		paramName("OrderID"):      "orderID",
+		paramName("Type"):         "typeValue",
+	}
+	for actual, expected := range cases {
+		if actual != expected {
+			t.Errorf("Expected %q, but got %q", expected, actual)
+		}
+	}
+}
+
+func Test_Generator_SoftDelete(t *testing.T) {
+	tables := testTables()
+	tables[0].Columns = append(tables[0].Columns, &dbx.Column{Name: "deleted_at", Type: "timestamp without time zone", Nullable: true})
+	files, err := NewGenerator("entities").Generate(t.TempDir(), tables)
+	if err != nil {
+		t.Fatalf("Generate error: %s", err.Error())
+	}
+
+	source := string(files[0].Source)
+	for _, expected := range []string{
+		"var OrderSoftDelete = dbx.NewSoftDelete(`\"order\"`)",
+		"statement := me.softDelete.Delete(`id = :id`, me.dbContext.Now())",
+		"func (me *OrderRepository) Restore(id string) {",
+		"statement := dbx.NewStatement(`SELECT id, order_number, order_date, total, created_at, updated_at, deleted_at FROM \"order\"` + me.softDelete.Where(ctx, `id = :id`))",
+		"if predicate := me.softDelete.Predicate(ctx); predicate != \"\" {",
+		"func (me *OrderRepository) Unscoped() *OrderRepository {",
+		"softDelete: OrderSoftDelete,",
+	} {
+		if !strings.Contains(source, expected) {
+			t.Errorf("Generated entity expected to contain %q:\n%s", expected, source)
+		}
+	}
+	if !strings.Contains(source, "total = :total, updated_at = :updated_at WHERE id = :id`") {
+		t.Errorf("Update must not overwrite deleted_at:\n%s", source)
+	}
+}
+
+func Test_Generator_Tenant(t *testing.T) {
+	tables := testTables()
+	tables[0].Columns = append(tables[0].Columns,
+		&dbx.Column{Name: "tenant_id", Type: "character varying"},
+		&dbx.Column{Name: "deleted_at", Type: "timestamp without time zone", Nullable: true},
+	)
+	files, err := NewGenerator("entities").Generate(t.TempDir(), tables)
+	if err != nil {
+		t.Fatalf("Generate error: %s", err.Error())
+	}
+
+	source := string(files[0].Source)
+	for _, expected := range []string{
+		"VALUES (:id, :order_number, :order_date, :total, :created_at, :updated_at, :dbx_tenant, :deleted_at)`).ScopeTenant()",
+		"updated_at = :updated_at WHERE id = :id AND tenant_id = :dbx_tenant`).ScopeTenant()",
+		"statement := me.softDelete.Delete(`id = :id AND tenant_id = :dbx_tenant`, me.dbContext.Now()).ScopeTenant()",
+		"me.softDelete.Where(ctx, `tenant_id = :dbx_tenant`)).ScopeTenant())",
+		"predicates = append(predicates, `tenant_id = :dbx_tenant`)",
+	} {
+		if !strings.Contains(source, expected) {
+			t.Errorf("Generated entity expected to contain %q:\n%s", expected, source)
+		}
+	}
+}
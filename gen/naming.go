@@ -0,0 +1,95 @@
+package gen
+
+import (
+	"go/token"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+//initialisms are identifier parts written in upper case
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "HTML": true, "HTTP": true, "ID": true, "IP": true, "JSON": true,
+	"SQL": true, "URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+//plainIdentifier matches identifiers which do not need quoting
+var plainIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+//reservedWords are SQL keywords commonly used as table or column names, they are always quoted
+var reservedWords = map[string]bool{
+	"all": true, "column": true, "check": true, "default": true, "desc": true, "from": true, "group": true,
+	"limit": true, "offset": true, "order": true, "select": true, "table": true, "to": true, "user": true, "where": true,
+}
+
+//StructName returns the struct name of a table, plural table names are singularized
+func StructName(tableName string) string {
+	name := tableName
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		name = strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss"):
+		name = strings.TrimSuffix(name, "s")
+	}
+	return FieldName(name)
+}
+
+//FieldName converts snake case column name into exported Go identifier
+func FieldName(columnName string) string {
+	var builder strings.Builder
+	for _, part := range strings.FieldsFunc(columnName, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		upper := strings.ToUpper(part)
+		if initialisms[upper] {
+			builder.WriteString(upper)
+			continue
+		}
+		builder.WriteString(upper[:1] + part[1:])
+	}
+	name := builder.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "X" + name
+	}
+	return name
+}
+
+//ColumnName converts Go identifier into snake case column name
+func ColumnName(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			builder.WriteByte('_')
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+	return builder.String()
+}
+
+//paramName converts exported Go identifier into unexported one
+func paramName(name string) string {
+	prefix := 0
+	for prefix < len(name) && unicode.IsUpper(rune(name[prefix])) {
+		prefix++
+	}
+	if prefix > 1 && prefix < len(name) {
+		prefix--
+	}
+	param := strings.ToLower(name[:prefix]) + name[prefix:]
+	if token.IsKeyword(param) {
+		param += "Value"
+	}
+	return param
+}
+
+//fileName returns the generated file name of a table
+func fileName(tableName string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", ".", "_").Replace(tableName)) + ".go"
+}
+
+//quoteIdentifier quotes the identifier if needed
+func quoteIdentifier(name string) string {
+	if plainIdentifier.MatchString(name) && !reservedWords[name] {
+		return name
+	}
+	return `"` + name + `"`
+}
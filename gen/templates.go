@@ -0,0 +1,259 @@
+package gen
+
+import "text/template"
+
+var entityTemplate = template.Must(template.New("entity").Parse(`// Code generated by dbx gen. DO NOT EDIT.
+// Code between dbx:preserve and dbx:end markers is kept when the file is regenerated.
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+
+	"github.com/supendi/dbx"
+)
+
+//dbx:preserve imports
+{{index .Regions "imports"}}//dbx:end
+
+//{{.Struct}} represent {{.Table}} table
+type {{.Struct}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}} ` + "`db:\"{{.Column}}\"`" + `
+{{- end}}
+}
+
+//TableName returns the table name of {{.Struct}}
+func (me *{{.Struct}}) TableName() string {
+	return ` + "`{{.Table}}`" + `
+}
+
+//{{.Struct}}Filter represent the filter of {{.Struct}}Repository.Find, nil fields are ignored
+type {{.Struct}}Filter struct {
+{{- range .Fields}}
+{{- if .FilterType}}
+	{{.Name}} {{.FilterType}}
+{{- end}}
+{{- end}}
+	Limit  int
+	Offset int
+}
+
+{{- if .SoftDelete}}
+
+//{{.Struct}}SoftDelete is the soft delete of {{.Table}} table, records are marked as deleted by setting deleted_at
+var {{.Struct}}SoftDelete = dbx.NewSoftDelete(` + "`{{.QuotedTable}}`" + `)
+{{- end}}
+
+//{{.Struct}}Repository is repository of {{.Table}} table
+type {{.Struct}}Repository struct {
+	dbContext *dbx.Context
+{{- if .SoftDelete}}
+	softDelete *dbx.SoftDelete
+{{- end}}
+}
+
+//setStatementParam sets statement parameters
+func (me *{{.Struct}}Repository) setStatementParam(statement *dbx.Statement, {{.Var}} *{{.Struct}}) {
+{{- $var := .Var}}
+{{- range .Fields}}
+	statement.AddParameter(` + "`{{.Column}}`" + `, {{$var}}.{{.Name}})
+{{- end}}
+}
+
+//Add adds new {{.Var}}
+func (me *{{.Struct}}Repository) Add({{.Var}} *{{.Struct}}) {
+	statement := dbx.NewStatement(` + "`{{.InsertSQL}}`" + `){{if .Tenant}}.ScopeTenant(){{end}}{{.Affects}}{{.InsertStamp}}
+	me.setStatementParam(statement, {{.Var}})
+
+	me.dbContext.AddStatement(statement)
+}
+{{- if .UpdateSQL}}
+
+//Update updates existing {{.Var}}
+func (me *{{.Struct}}Repository) Update({{.Var}} *{{.Struct}}) {
+	statement := dbx.NewStatement(` + "`{{.UpdateSQL}}`" + `){{if .Tenant}}.ScopeTenant(){{end}}{{.Affects}}{{.UpdateStamp}}
+	me.setStatementParam(statement, {{.Var}})
+
+	me.dbContext.AddStatement(statement)
+}
+{{- end}}
+{{- if .DeleteSQL}}
+{{- if .SoftDelete}}
+
+//Delete soft deletes existing {{.Var}}, it's deleted if the repository is unscoped
+func (me *{{.Struct}}Repository) Delete({{.KeyParams}}) {
+	statement := me.softDelete.Delete(` + "`{{.KeyWhere}}`" + `, me.dbContext.Now()){{if .Tenant}}.ScopeTenant(){{end}}{{.Affects}}
+{{- range .Keys}}
+	statement.AddParameter(` + "`{{.Column}}`" + `, {{.Param}})
+{{- end}}
+
+	me.dbContext.AddStatement(statement)
+}
+
+//Restore restores soft deleted {{.Var}}
+func (me *{{.Struct}}Repository) Restore({{.KeyParams}}) {
+	statement := me.softDelete.Restore(` + "`{{.KeyWhere}}`" + `){{if .Tenant}}.ScopeTenant(){{end}}{{.Affects}}
+{{- range .Keys}}
+	statement.AddParameter(` + "`{{.Column}}`" + `, {{.Param}})
+{{- end}}
+
+	me.dbContext.AddStatement(statement)
+}
+{{- else}}
+
+//Delete deletes existing {{.Var}}
+func (me *{{.Struct}}Repository) Delete({{.KeyParams}}) {
+	statement := dbx.NewStatement(` + "`{{.DeleteSQL}}`" + `){{if .Tenant}}.ScopeTenant(){{end}}{{.Affects}}
+{{- range .Keys}}
+	statement.AddParameter(` + "`{{.Column}}`" + `, {{.Param}})
+{{- end}}
+
+	me.dbContext.AddStatement(statement)
+}
+{{- end}}
+
+//GetByID gets {{.Var}} by its primary key
+func (me *{{.Struct}}Repository) GetByID(ctx context.Context, {{.KeyParams}}) (*{{.Struct}}, error) {
+{{- if .SoftDelete}}
+	statement := dbx.NewStatement(` + "`{{.SelectSQL}}`" + ` + me.softDelete.Where(ctx, ` + "`{{.KeyWhere}}`" + `)){{if .Tenant}}.ScopeTenant(){{end}}
+{{- else}}
+	statement := dbx.NewStatement(` + "`{{.GetByIDSQL}}`" + `){{if .Tenant}}.ScopeTenant(){{end}}
+{{- end}}
+{{- range .Keys}}
+	statement.AddParameter(` + "`{{.Column}}`" + `, {{.Param}})
+{{- end}}
+
+	records, err := me.query(ctx, statement)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return records[0], nil
+}
+{{- end}}
+
+//GetAll gets all {{.Var}} records
+func (me *{{.Struct}}Repository) GetAll(ctx context.Context) ([]*{{.Struct}}, error) {
+{{- if .SoftDelete}}
+	return me.query(ctx, dbx.NewStatement(` + "`{{.SelectSQL}}`" + ` + me.softDelete.Where(ctx{{if .Tenant}}, ` + "`{{.TenantPredicate}}`" + `{{end}})){{if .Tenant}}.ScopeTenant(){{end}})
+{{- else}}
+	return me.query(ctx, dbx.NewStatement(` + "`{{.GetAllSQL}}`" + `){{if .Tenant}}.ScopeTenant(){{end}})
+{{- end}}
+}
+
+//Find gets {{.Var}} records matching every non nil field of the filter, a nil filter gets all of them
+func (me *{{.Struct}}Repository) Find(ctx context.Context, filter *{{.Struct}}Filter) ([]*{{.Struct}}, error) {
+	if filter == nil {
+		filter = &{{.Struct}}Filter{}
+	}
+	statement := dbx.NewStatement(` + "`{{.SelectSQL}}`" + `){{if .Tenant}}.ScopeTenant(){{end}}
+	predicates := []string{}
+{{- if .Tenant}}
+	predicates = append(predicates, ` + "`{{.TenantPredicate}}`" + `)
+{{- end}}
+{{- if .SoftDelete}}
+	if predicate := me.softDelete.Predicate(ctx); predicate != "" {
+		predicates = append(predicates, predicate)
+	}
+{{- end}}
+{{- range .Fields}}
+{{- if .FilterType}}
+	if filter.{{.Name}} != nil {
+		predicates = append(predicates, ` + "`{{.Predicate}}`" + `)
+		statement.AddParameter(` + "`{{.Column}}`" + `, *filter.{{.Name}})
+	}
+{{- end}}
+{{- end}}
+	if len(predicates) > 0 {
+		statement.SQL += " WHERE " + strings.Join(predicates, " AND ")
+	}
+	if filter.Limit > 0 {
+		statement.SQL += " LIMIT :dbx_limit"
+		statement.AddParameter("dbx_limit", filter.Limit)
+	}
+	if filter.Offset > 0 {
+		statement.SQL += " OFFSET :dbx_offset"
+		statement.AddParameter("dbx_offset", filter.Offset)
+	}
+	return me.query(ctx, statement)
+}
+
+//query executes the statement and scans every {{.Var}} record
+func (me *{{.Struct}}Repository) query(ctx context.Context, statement *dbx.Statement) ([]*{{.Struct}}, error) {
+	rows, err := me.dbContext.QueryStatementContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	records := []*{{.Struct}}{}
+	for rows.Next() {
+		{{.Var}} := &{{.Struct}}{}
+		err = rows.StructScan({{.Var}})
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, {{.Var}})
+	}
+	return records, rows.Err()
+}
+
+{{- if .SoftDelete}}
+
+//Unscoped returns a copy of the repository which includes soft deleted records and whose Delete deletes them
+func (me *{{.Struct}}Repository) Unscoped() *{{.Struct}}Repository {
+	return &{{.Struct}}Repository{
+		dbContext:  me.dbContext,
+		softDelete: me.softDelete.Unscoped(),
+	}
+}
+{{- end}}
+
+//New{{.Struct}}Repository create new {{.Var}} repository instance
+func New{{.Struct}}Repository(dbContext *dbx.Context) *{{.Struct}}Repository {
+	return &{{.Struct}}Repository{
+		dbContext: dbContext,
+{{- if .SoftDelete}}
+		softDelete: {{.Struct}}SoftDelete,
+{{- end}}
+	}
+}
+
+//dbx:preserve code
+{{index .Regions "code"}}//dbx:end
+`))
+
+var dbContextTemplate = template.Must(template.New("dbcontext").Parse(`// Code generated by dbx gen. DO NOT EDIT.
+// Code between dbx:preserve and dbx:end markers is kept when the file is regenerated.
+
+package {{.Package}}
+
+import (
+	"github.com/supendi/dbx"
+)
+
+//dbx:preserve imports
+{{index .Regions "imports"}}//dbx:end
+
+//DBContext represent database context
+type DBContext struct {
+	*dbx.Context
+{{- range .Entities}}
+	{{.Struct}} *{{.Struct}}Repository
+{{- end}}
+}
+
+//NewDBContext returns new dbcontext
+func NewDBContext(dbContext *dbx.Context) *DBContext {
+	return &DBContext{
+		Context: dbContext,
+{{- range .Entities}}
+		{{.Struct}}: New{{.Struct}}Repository(dbContext),
+{{- end}}
+	}
+}
+
+//dbx:preserve code
+{{index .Regions "code"}}//dbx:end
+`))
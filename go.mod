@@ -1,9 +1,11 @@
 module github.com/supendi/dbx
 
-go 1.12
+go 1.18
 
 require (
 	github.com/google/uuid v1.1.1
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/lib/pq v1.2.0
+	github.com/mattn/go-sqlite3 v1.14.33
+	gopkg.in/yaml.v3 v3.0.1
 )
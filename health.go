@@ -0,0 +1,167 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//Stats represent the statistics of the pools of a client. The embedded statistics are the sums over the database,
+//its replicas and its shards. There is no prepared statement cache to report, statements are bound and sent
+//by the driver on each call
+type Stats struct {
+	sql.DBStats
+	Primary  sql.DBStats
+	Replicas []sql.DBStats
+	//Shards are the statistics of the shards of a sharded client, the first one is the primary
+	Shards []sql.DBStats
+	//ActiveTransactions is the number of transactions begun by the client which are neither committed nor rolled back
+	ActiveTransactions int
+	//OpenRows is the number of rows returned by queries of the client which are neither closed nor read to the end
+	OpenRows int
+}
+
+//Stats returns the statistics of the pools of the database and its replicas, the number of active transactions
+//and of open rows
+func (me *Client) Stats() Stats {
+	stats := Stats{
+		Replicas:           []sql.DBStats{},
+		ActiveTransactions: me.transactions.count(),
+		OpenRows:           me.lifecycle.rows(),
+	}
+	if me.DB != nil {
+		stats.Primary = me.DB.Stats()
+	}
+	stats.DBStats = stats.Primary
+	for _, replica := range me.replicas {
+		replicaStats := replica.Stats()
+		stats.Replicas = append(stats.Replicas, replicaStats)
+		stats.add(replicaStats)
+	}
+	for i, shard := range me.shards {
+		shardStats := shard.Stats()
+		stats.Shards = append(stats.Shards, shardStats)
+		if i > 0 {
+			stats.add(shardStats)
+		}
+	}
+	return stats
+}
+
+//add adds the statistics of a pool to the sums
+func (me *Stats) add(pool sql.DBStats) {
+	me.MaxOpenConnections += pool.MaxOpenConnections
+	me.OpenConnections += pool.OpenConnections
+	me.InUse += pool.InUse
+	me.Idle += pool.Idle
+	me.WaitCount += pool.WaitCount
+	me.WaitDuration += pool.WaitDuration
+	me.MaxIdleClosed += pool.MaxIdleClosed
+	me.MaxIdleTimeClosed += pool.MaxIdleTimeClosed
+	me.MaxLifetimeClosed += pool.MaxLifetimeClosed
+}
+
+//DatabaseHealth represent the outcome of a round trip to a database of a client
+type DatabaseHealth struct {
+	//Name is primary, or replica followed by its position in the replicas starting at 1, or shard followed by its index
+	Name    string        `json:"name"`
+	Healthy bool          `json:"healthy"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+//Health represent the health of a client
+type Health struct {
+	//Healthy determine if the database and every replica and shard answered, and the client is not shut down
+	Healthy   bool             `json:"healthy"`
+	Closed    bool             `json:"closed"`
+	Circuit   CircuitState     `json:"circuit"`
+	Databases []DatabaseHealth `json:"databases"`
+}
+
+//Health pings the database, each replica and each shard concurrently and reports their latency. A failed replica makes
+//the client unhealthy, its queries reading from replicas may fail. So does a shutdown, see Shutdown, or having
+//no database such as a sharded client without shard
+func (me *Client) Health(ctx context.Context) *Health {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if me.DB == nil {
+		return &Health{
+			Closed:    me.lifecycle.isClosed(),
+			Circuit:   me.CircuitState(),
+			Databases: []DatabaseHealth{},
+		}
+	}
+	dbs := append([]*sqlx.DB{me.DB}, me.replicas...)
+	if len(me.shards) > 1 {
+		dbs = append(dbs, me.shards[1:]...)
+	}
+	health := &Health{
+		Healthy:   !me.lifecycle.isClosed(),
+		Closed:    me.lifecycle.isClosed(),
+		Circuit:   me.CircuitState(),
+		Databases: make([]DatabaseHealth, len(dbs)),
+	}
+	var wait sync.WaitGroup
+	for i, db := range dbs {
+		name := "primary"
+		if me.isSharded() && i == 0 {
+			name = "shard 0"
+		} else if i > len(me.replicas) {
+			name = fmt.Sprintf("shard %d", i-len(me.replicas))
+		} else if i > 0 {
+			name = fmt.Sprintf("replica %d", i)
+		}
+		wait.Add(1)
+		go func(i int, name string, db *sqlx.DB) {
+			defer wait.Done()
+			start := time.Now()
+			err := db.PingContext(ctx)
+			health.Databases[i] = DatabaseHealth{Name: name, Healthy: err == nil, Latency: time.Since(start)}
+			if err != nil {
+				health.Databases[i].Error = err.Error()
+			}
+		}(i, name, db)
+	}
+	wait.Wait()
+	for _, database := range health.Databases {
+		health.Healthy = health.Healthy && database.Healthy
+	}
+	return health
+}
+
+//ReadinessHandler returns the handler of readiness probes, it responds the JSON health of the client,
+//with status 503 if it's not healthy. See Health
+func (me *Client) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := me.Health(r.Context())
+		status := http.StatusOK
+		if !health.Healthy {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, health)
+	})
+}
+
+//LivenessHandler returns the handler of liveness probes, it responds the JSON statistics of the client
+//without a round trip to the databases, so a database outage doesn't restart the process. See Stats
+func (me *Client) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, me.Stats())
+	})
+}
+
+//writeJSON writes the JSON value as response with the status
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
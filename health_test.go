@@ -0,0 +1,110 @@
+package dbx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Client_Stats(t *testing.T) {
+	client := NewClient(getSQLiteFileDb(t))
+	dbContext := client.NewContext()
+	transaction, err := dbContext.BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+	if stats := client.Stats(); stats.ActiveTransactions != 1 {
+		t.Errorf("Expected 1 active transaction, got %d", stats.ActiveTransactions)
+	}
+
+	if err := transaction.CommitAndStartOver(); err != nil {
+		t.Fatalf("CommitAndStartOver error: %s", err.Error())
+	}
+	if stats := client.Stats(); stats.ActiveTransactions != 1 {
+		t.Errorf("Expected the transaction started over to be active, got %d", stats.ActiveTransactions)
+	}
+	if err := dbContext.CompleteTransaction(); err != nil {
+		t.Fatalf("CompleteTransaction error: %s", err.Error())
+	}
+	transaction.Rollback()
+	if stats := client.Stats(); stats.ActiveTransactions != 0 || stats.InUse != 0 {
+		t.Errorf("Expected no active transaction, got %+v", stats)
+	}
+}
+
+func Test_Client_Health(t *testing.T) {
+	dir := t.TempDir()
+	client, err := Open(context.Background(), Config{
+		Driver:       "sqlite3",
+		Database:     filepath.Join(dir, "primary.db"),
+		Replicas:     []string{filepath.Join(dir, "replica1.db"), filepath.Join(dir, "replica2.db")},
+		MaxOpenConns: 2,
+	})
+	if err != nil {
+		t.Fatalf("Open error: %s", err.Error())
+	}
+	defer client.Close()
+
+	stats := client.Stats()
+	if len(stats.Replicas) != 2 || stats.MaxOpenConnections != 6 || stats.Primary.MaxOpenConnections != 2 {
+		t.Errorf("Expected the statistics of 3 pools, got %+v", stats)
+	}
+
+	health := client.Health(context.Background())
+	if !health.Healthy || len(health.Databases) != 3 || health.Databases[2].Name != "replica 2" || health.Circuit != CircuitClosed {
+		t.Errorf("Expected a healthy client, got %+v", health)
+	}
+	response := httptest.NewRecorder()
+	client.ReadinessHandler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if response.Code != http.StatusOK || response.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected ready, got %d", response.Code)
+	}
+
+	client.replicas[1].Close()
+	health = client.Health(context.Background())
+	if health.Healthy || !health.Databases[0].Healthy || health.Databases[2].Healthy || health.Databases[2].Error == "" {
+		t.Errorf("Expected the closed replica to be unhealthy, got %+v", health)
+	}
+	response = httptest.NewRecorder()
+	client.ReadinessHandler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if response.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected not ready, got %d", response.Code)
+	}
+	decoded := Health{}
+	if err := json.NewDecoder(response.Body).Decode(&decoded); err != nil || len(decoded.Databases) != 3 {
+		t.Errorf("Expected the health as JSON, got %v", err)
+	}
+
+	response = httptest.NewRecorder()
+	client.LivenessHandler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/live", nil))
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected alive, got %d", response.Code)
+	}
+}
+
+func Test_Client_StatsOpenRows(t *testing.T) {
+	client := NewClient(getSQLiteFileDb(t))
+	rows, err := client.QueryStatement(NewStatement("SELECT 1"))
+	if err != nil {
+		t.Fatalf("QueryStatement error: %s", err.Error())
+	}
+	if stats := client.Stats(); stats.OpenRows != 1 {
+		t.Errorf("Expected 1 open rows, got %d", stats.OpenRows)
+	}
+	rows.Close()
+	rows.Close()
+	if stats := client.Stats(); stats.OpenRows != 0 {
+		t.Errorf("Expected the rows to be closed, got %d", stats.OpenRows)
+	}
+
+	empty := NewShardedClient(NewHashRouter())
+	if stats := empty.Stats(); stats.OpenConnections != 0 || len(stats.Shards) != 0 {
+		t.Errorf("Expected no statistics of a client without database, got %+v", stats)
+	}
+	if health := empty.Health(context.Background()); health.Healthy || len(health.Databases) != 0 {
+		t.Errorf("Expected a client without database to be unhealthy, got %+v", health)
+	}
+}
@@ -0,0 +1,67 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq" //needed
+
+	"github.com/supendi/dbx/internal/driverutil"
+)
+
+//getSqlxDb returns a database handle of the DBXTEST_DRIVER and DBXTEST_DSN environment variables, the test is
+//skipped if they are not set. The test runs in a transaction which is rolled back at cleanup
+func getSqlxDb(t *testing.T) (*sqlx.DB, error) {
+	driverName, dataSourceName := driverutil.Environment()
+	if dataSourceName == "" {
+		t.Skipf("%s is not set, skipping test which needs a database", driverutil.DSNEnv)
+	}
+	isolated, err := driverutil.OpenIsolated(context.Background(), driverName, dataSourceName)
+	if err != nil {
+		t.Fatalf("Fatal connect to database error: %s", err.Error())
+	}
+	db := sqlx.NewDb(sql.OpenDB(isolated), driverName)
+	t.Cleanup(func() {
+		db.Close()
+		isolated.Close()
+	})
+
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS person (id VARCHAR(36) PRIMARY KEY, name VARCHAR(255) NOT NULL, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP)")
+	return db, err
+}
+
+//getSQLiteFileDb returns a handle of a SQLite database file with the person table, its connections wait for
+//each other's write lock so it can be used concurrently
+func getSQLiteFileDb(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "dbx.db")+"?_busy_timeout=10000&_txlock=immediate")
+	if err != nil {
+		t.Fatalf("Fatal create db error: %s", err.Error())
+	}
+	t.Cleanup(func() { db.Close() })
+	db.MustExec("CREATE TABLE person (id VARCHAR(36) PRIMARY KEY, name VARCHAR(255) NOT NULL, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP)")
+	return db
+}
+
+//countPersons returns the number of rows of the person table
+func countPersons(t *testing.T, db *sqlx.DB) int {
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM person"); err != nil {
+		t.Fatalf("Count person error: %s", err.Error())
+	}
+	return count
+}
+
+//newPersonStatement returns a statement inserting a person with a new id
+func newPersonStatement(name string) *Statement {
+	statement := NewStatement("INSERT INTO person (id, name, created_at, updated_at) VALUES (:id, :name, :created_at, :updated_at)")
+	statement.AddParameter("id", uuid.New().String())
+	statement.AddParameter("name", name)
+	statement.AddParameter("created_at", time.Now())
+	statement.AddParameter("updated_at", nil)
+	return statement
+}
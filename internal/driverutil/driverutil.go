@@ -0,0 +1,205 @@
+//Package driverutil provides database/sql/driver helpers shared by dbx test utilities
+package driverutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+)
+
+//Environment variables of the database used by integration tests
+const (
+	DriverEnv = "DBXTEST_DRIVER"
+	DSNEnv    = "DBXTEST_DSN"
+)
+
+//Environment returns the driver name and data source name of the test database.
+//The driver defaults to postgres, the data source name is empty if not configured
+func Environment() (string, string) {
+	driverName := os.Getenv(DriverEnv)
+	if driverName == "" {
+		driverName = "postgres"
+	}
+	return driverName, os.Getenv(DSNEnv)
+}
+
+//Connector returns the connector of a registered driver to the data source
+func Connector(driverName string, dataSourceName string) (driver.Connector, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	inner := db.Driver()
+	db.Close()
+	if driverContext, ok := inner.(driver.DriverContext); ok {
+		return driverContext.OpenConnector(dataSourceName)
+	}
+	return &dsnConnector{driver: inner, dataSourceName: dataSourceName}, nil
+}
+
+//dsnConnector opens connections of a driver which does not implement driver.DriverContext
+type dsnConnector struct {
+	driver         driver.Driver
+	dataSourceName string
+}
+
+//Connect implements driver.Connector
+func (me *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return me.driver.Open(me.dataSourceName)
+}
+
+//Driver implements driver.Connector
+func (me *dsnConnector) Driver() driver.Driver {
+	return me.driver
+}
+
+//Exec executes the statement on the connection, through a prepared statement if the connection
+//does not implement driver.ExecerContext
+func Exec(ctx context.Context, conn driver.Conn, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		result, err := execer.ExecContext(ctx, query, args)
+		if err != driver.ErrSkip {
+			return result, err
+		}
+	}
+	stmt, err := prepare(ctx, conn, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, args)
+	}
+	return stmt.Exec(Values(args))
+}
+
+//Query executes the query on the connection and reads every row, through a prepared statement if the connection
+//does not implement driver.QueryerContext
+func Query(ctx context.Context, conn driver.Conn, query string, args []driver.NamedValue) (*Rows, error) {
+	if queryer, ok := conn.(driver.QueryerContext); ok {
+		rows, err := queryer.QueryContext(ctx, query, args)
+		if err != driver.ErrSkip {
+			if err != nil {
+				return nil, err
+			}
+			return ReadRows(rows), nil
+		}
+	}
+	stmt, err := prepare(ctx, conn, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	var rows driver.Rows
+	if queryer, ok := stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		rows, err = stmt.Query(Values(args))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ReadRows(rows), nil
+}
+
+//prepare prepares the statement on the connection
+func prepare(ctx context.Context, conn driver.Conn, query string) (driver.Stmt, error) {
+	if preparer, ok := conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return conn.Prepare(query)
+}
+
+//Rows represent rows read into memory, it implements driver.Rows
+type Rows struct {
+	columns []string
+	values  [][]driver.Value
+	err     error
+	index   int
+}
+
+//Columns implements driver.Rows
+func (me *Rows) Columns() []string {
+	return me.columns
+}
+
+//Values returns the values of every row
+func (me *Rows) Values() [][]driver.Value {
+	return me.values
+}
+
+//Err returns the error which stopped reading the rows, nil if every row was read
+func (me *Rows) Err() error {
+	return me.err
+}
+
+//Close implements driver.Rows
+func (me *Rows) Close() error {
+	return nil
+}
+
+//Next implements driver.Rows, the reading error is returned after the last row
+func (me *Rows) Next(dest []driver.Value) error {
+	if me.index >= len(me.values) {
+		if me.err != nil {
+			return me.err
+		}
+		return io.EOF
+	}
+	copy(dest, me.values[me.index])
+	me.index++
+	return nil
+}
+
+//ReadRows reads and closes the rows
+func ReadRows(rows driver.Rows) *Rows {
+	defer rows.Close()
+	result := NewRows(rows.Columns(), nil, nil)
+	for {
+		dest := make([]driver.Value, len(result.columns))
+		err := rows.Next(dest)
+		if errors.Is(err, io.EOF) {
+			return result
+		}
+		if err != nil {
+			result.err = err
+			return result
+		}
+		for i, value := range dest {
+			if bytes, ok := value.([]byte); ok {
+				dest[i] = append([]byte{}, bytes...) //drivers may reuse their buffers
+			}
+		}
+		result.values = append(result.values, dest)
+	}
+}
+
+//Values returns the driver values of named arguments
+func Values(args []driver.NamedValue) []driver.Value {
+	result := make([]driver.Value, len(args))
+	for i, arg := range args {
+		result[i] = arg.Value
+	}
+	return result
+}
+
+//NamedValues returns the ordinal arguments of values
+func NamedValues(args []driver.Value) []driver.NamedValue {
+	result := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		result[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return result
+}
+
+//NewRows create new in-memory rows instance
+func NewRows(columns []string, values [][]driver.Value, err error) *Rows {
+	return &Rows{
+		columns: columns,
+		values:  values,
+		err:     err,
+	}
+}
@@ -0,0 +1,253 @@
+package driverutil
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+//ErrIsolatedClosed is returned when using an isolated connector after it has been closed
+var ErrIsolatedClosed = errors.New("Isolated connection is closed")
+
+//Isolated is a connector whose connections share a single database connection holding a transaction,
+//which is rolled back on Close so nothing done through it is persisted.
+//
+//Transactions begun on its connections are savepoints, their commit releases the savepoint and their rollback
+//rolls back to it. Statements outside a transaction run in their own savepoint, so a failing statement does not
+//abort the transaction like it does on Postgres. Rows are read entirely before a query returns
+type Isolated struct {
+	mutex     sync.Mutex
+	conn      driver.Conn
+	tx        driver.Tx
+	savepoint int
+	closed    bool
+}
+
+//Connect implements driver.Connector
+func (me *Isolated) Connect(ctx context.Context) (driver.Conn, error) {
+	return &isolatedConn{isolated: me}, nil
+}
+
+//Driver implements driver.Connector
+func (me *Isolated) Driver() driver.Driver {
+	return isolatedDriver{}
+}
+
+//Close rolls back the transaction and closes the database connection
+func (me *Isolated) Close() error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.closed {
+		return nil
+	}
+	me.closed = true
+	err := me.tx.Rollback()
+	if closeError := me.conn.Close(); err == nil {
+		err = closeError
+	}
+	return err
+}
+
+//newSavepoint creates a new savepoint and returns its name, the mutex must be held
+func (me *Isolated) newSavepoint(ctx context.Context) (string, error) {
+	if me.closed {
+		return "", ErrIsolatedClosed
+	}
+	me.savepoint++
+	name := fmt.Sprintf("dbxtest_%d", me.savepoint)
+	_, err := Exec(ctx, me.conn, "SAVEPOINT "+name, nil)
+	return name, err
+}
+
+//releaseSavepoint releases the savepoint, or rolls back to it when rollback is true. The mutex must be held
+func (me *Isolated) releaseSavepoint(ctx context.Context, name string, rollback bool) error {
+	if me.closed {
+		return ErrIsolatedClosed
+	}
+	if rollback {
+		if _, err := Exec(ctx, me.conn, "ROLLBACK TO SAVEPOINT "+name, nil); err != nil {
+			return err
+		}
+	}
+	_, err := Exec(ctx, me.conn, "RELEASE SAVEPOINT "+name, nil)
+	return err
+}
+
+//run calls fn with the mutex held, inside a savepoint of its own unless the connection is in a transaction
+func (me *Isolated) run(ctx context.Context, transaction string, fn func() error) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.closed {
+		return ErrIsolatedClosed
+	}
+	if transaction != "" {
+		return fn()
+	}
+	name, err := me.newSavepoint(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn()
+	if releaseError := me.releaseSavepoint(ctx, name, err != nil); err == nil {
+		err = releaseError
+	}
+	return err
+}
+
+//isolatedDriver is the driver of isolated connections, it can't be opened by name
+type isolatedDriver struct{}
+
+//Open implements driver.Driver
+func (me isolatedDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("isolated driver can only be opened through its connector")
+}
+
+//isolatedConn is a connection of an isolated connector
+type isolatedConn struct {
+	isolated *Isolated
+	//savepoint is the savepoint of the current transaction, empty outside a transaction
+	savepoint string
+}
+
+//Prepare implements driver.Conn, statements are executed through the connection
+func (me *isolatedConn) Prepare(query string) (driver.Stmt, error) {
+	return &isolatedStmt{conn: me, query: query}, nil
+}
+
+//Close implements driver.Conn
+func (me *isolatedConn) Close() error {
+	return nil
+}
+
+//Begin implements driver.Conn
+func (me *isolatedConn) Begin() (driver.Tx, error) {
+	return me.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+//BeginTx implements driver.ConnBeginTx, the transaction is a savepoint
+func (me *isolatedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	me.isolated.mutex.Lock()
+	defer me.isolated.mutex.Unlock()
+	name, err := me.isolated.newSavepoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	me.savepoint = name
+	return &isolatedTx{conn: me}, nil
+}
+
+//Ping implements driver.Pinger
+func (me *isolatedConn) Ping(ctx context.Context) error {
+	me.isolated.mutex.Lock()
+	defer me.isolated.mutex.Unlock()
+	if me.isolated.closed {
+		return ErrIsolatedClosed
+	}
+	if pinger, ok := me.isolated.conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+//ExecContext implements driver.ExecerContext
+func (me *isolatedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	var result driver.Result
+	err := me.isolated.run(ctx, me.savepoint, func() error {
+		var err error
+		result, err = Exec(ctx, me.isolated.conn, query, args)
+		return err
+	})
+	return result, err
+}
+
+//QueryContext implements driver.QueryerContext
+func (me *isolatedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	var rows *Rows
+	err := me.isolated.run(ctx, me.savepoint, func() error {
+		var err error
+		rows, err = Query(ctx, me.isolated.conn, query, args)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+//isolatedTx is a transaction of an isolated connection
+type isolatedTx struct {
+	conn *isolatedConn
+}
+
+//Commit implements driver.Tx, it releases the savepoint
+func (me *isolatedTx) Commit() error {
+	return me.complete(false)
+}
+
+//Rollback implements driver.Tx, it rolls back to the savepoint
+func (me *isolatedTx) Rollback() error {
+	return me.complete(true)
+}
+
+//complete releases or rolls back to the savepoint of the transaction
+func (me *isolatedTx) complete(rollback bool) error {
+	me.conn.isolated.mutex.Lock()
+	defer me.conn.isolated.mutex.Unlock()
+	name := me.conn.savepoint
+	me.conn.savepoint = ""
+	return me.conn.isolated.releaseSavepoint(context.Background(), name, rollback)
+}
+
+//isolatedStmt executes prepared statements through the connection
+type isolatedStmt struct {
+	conn  *isolatedConn
+	query string
+}
+
+//Close implements driver.Stmt
+func (me *isolatedStmt) Close() error {
+	return nil
+}
+
+//NumInput implements driver.Stmt, the number of arguments is not checked
+func (me *isolatedStmt) NumInput() int {
+	return -1
+}
+
+//Exec implements driver.Stmt
+func (me *isolatedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return me.conn.ExecContext(context.Background(), me.query, NamedValues(args))
+}
+
+//Query implements driver.Stmt
+func (me *isolatedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return me.conn.QueryContext(context.Background(), me.query, NamedValues(args))
+}
+
+//OpenIsolated connects to the data source and begins the transaction of a new isolated connector
+func OpenIsolated(ctx context.Context, driverName string, dataSourceName string) (*Isolated, error) {
+	connector, err := Connector(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tx driver.Tx
+	if beginner, ok := conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(ctx, driver.TxOptions{})
+	} else {
+		tx, err = conn.Begin()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Isolated{
+		conn: conn,
+		tx:   tx,
+	}, nil
+}
@@ -0,0 +1,143 @@
+package dbx
+
+import (
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//OpenTransaction represent a transaction begun by a client which is neither committed nor rolled back
+type OpenTransaction struct {
+	Transaction *Transaction
+	BegunAt     time.Time
+	//Stack is the stack trace of the goroutine which began the transaction, or started it over
+	Stack string
+}
+
+//OpenTransactions returns the open transactions of the client, the oldest first. Transactions which are never
+//completed hold their connection forever, see WithTransactionMaxLifetime
+func (me *Client) OpenTransactions() []OpenTransaction {
+	return me.transactions.list()
+}
+
+//WithTransactionMaxLifetime makes the client roll back the transactions still open after maxLifetime, then call
+//onExpired with each of them if it's not nil. Statements of an expired transaction fail with sql.ErrTxDone.
+//A maxLifetime which is not positive disables it. It must be set before the client is used
+func (me *Client) WithTransactionMaxLifetime(maxLifetime time.Duration, onExpired func(transaction OpenTransaction)) *Client {
+	me.transactions.maxLifetime = maxLifetime
+	me.transactions.onExpired = onExpired
+	return me
+}
+
+//trackedTransaction is the record of an open transaction
+type trackedTransaction struct {
+	begunAt time.Time
+	callers []uintptr
+	timer   *time.Timer
+}
+
+//stack returns the stack trace of the callers
+func (me *trackedTransaction) stack() string {
+	builder := strings.Builder{}
+	frames := runtime.CallersFrames(me.callers)
+	for {
+		frame, more := frames.Next()
+		builder.WriteString(frame.Function + "\n\t" + frame.File + ":" + strconv.Itoa(frame.Line) + "\n")
+		if !more {
+			return builder.String()
+		}
+	}
+}
+
+//transactionTracker keeps the transactions begun by a client which are not complete, and rolls them back
+//once they expire
+type transactionTracker struct {
+	mutex       sync.Mutex
+	open        map[*Transaction]*trackedTransaction
+	maxLifetime time.Duration
+	onExpired   func(transaction OpenTransaction)
+}
+
+//add tracks the transaction with the stack trace of its caller
+func (me *transactionTracker) add(transaction *Transaction) {
+	if me == nil {
+		return
+	}
+	callers := make([]uintptr, 32)
+	tracked := &trackedTransaction{
+		begunAt: time.Now(),
+		callers: callers[:runtime.Callers(3, callers)],
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.maxLifetime > 0 {
+		tracked.timer = time.AfterFunc(me.maxLifetime, func() { me.expire(transaction, tracked) })
+	}
+	me.open[transaction] = tracked
+}
+
+//remove stops tracking the transaction
+func (me *transactionTracker) remove(transaction *Transaction) {
+	if me == nil {
+		return
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if tracked := me.open[transaction]; tracked != nil && tracked.timer != nil {
+		tracked.timer.Stop()
+	}
+	delete(me.open, transaction)
+}
+
+//tracked returns the record of the transaction, nil if it's not open
+func (me *transactionTracker) tracked(transaction *Transaction) *trackedTransaction {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.open[transaction]
+}
+
+//expire rolls back the transaction if the record is still the one of the transaction, then reports it
+func (me *transactionTracker) expire(transaction *Transaction, tracked *trackedTransaction) {
+	transaction.mutex.Lock()
+	expired := !transaction.isComplete && me.tracked(transaction) == tracked
+	if expired {
+		transaction.rollback()
+	}
+	transaction.mutex.Unlock()
+	if expired && me.onExpired != nil {
+		me.onExpired(OpenTransaction{Transaction: transaction, BegunAt: tracked.begunAt, Stack: tracked.stack()})
+	}
+}
+
+//count returns the number of tracked transactions
+func (me *transactionTracker) count() int {
+	if me == nil {
+		return 0
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return len(me.open)
+}
+
+//list returns the tracked transactions, the oldest first
+func (me *transactionTracker) list() []OpenTransaction {
+	transactions := []OpenTransaction{}
+	if me == nil {
+		return transactions
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	for transaction, tracked := range me.open {
+		transactions = append(transactions, OpenTransaction{Transaction: transaction, BegunAt: tracked.begunAt, Stack: tracked.stack()})
+	}
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].BegunAt.Before(transactions[j].BegunAt) })
+	return transactions
+}
+
+//newTransactionTracker create new transaction tracker instance
+func newTransactionTracker() *transactionTracker {
+	return &transactionTracker{open: map[*Transaction]*trackedTransaction{}}
+}
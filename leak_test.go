@@ -0,0 +1,82 @@
+package dbx
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func Test_Client_OpenTransactions(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", filepath.Join(t.TempDir(), "dbx.db"))
+	if err != nil {
+		t.Fatalf("Open error: %s", err.Error())
+	}
+	defer db.Close()
+	client := NewClient(db)
+	first, err := client.NewContext().BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+	second, err := client.NewContext().BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+
+	open := client.OpenTransactions()
+	if len(open) != 2 || open[0].Transaction != first || open[1].Transaction != second {
+		t.Fatalf("Expected 2 open transactions, the oldest first, got %+v", open)
+	}
+	if !strings.Contains(open[0].Stack, "Test_Client_OpenTransactions") || strings.Contains(open[0].Stack, "transactionTracker") {
+		t.Errorf("Expected the stack trace to start at the caller, got %s", open[0].Stack)
+	}
+
+	first.Rollback()
+	second.Commit()
+	if open := client.OpenTransactions(); len(open) != 0 {
+		t.Errorf("Expected no open transaction, got %d", len(open))
+	}
+}
+
+func Test_Client_TransactionMaxLifetime(t *testing.T) {
+	expired := make(chan OpenTransaction, 1)
+	client := NewClient(getSQLiteFileDb(t)).WithTransactionMaxLifetime(20*time.Millisecond, func(transaction OpenTransaction) {
+		expired <- transaction
+	})
+	dbContext := client.NewContext()
+	transaction, err := dbContext.BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+	if err := transaction.CommitAndStartOver(); err != nil {
+		t.Fatalf("CommitAndStartOver error: %s", err.Error())
+	}
+
+	select {
+	case report := <-expired:
+		if report.Transaction != transaction || report.Stack == "" {
+			t.Errorf("Expected the open transaction to be reported, got %+v", report)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the transaction to expire")
+	}
+	if !transaction.IsComplete() || len(client.OpenTransactions()) != 0 {
+		t.Error("Expected the expired transaction to be rolled back")
+	}
+	if _, err := transaction.ExecStatement(newPersonStatement("Expired")); !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("Expected sql.ErrTxDone, got %v", err)
+	}
+	if countPersons(t, client.DB) != 0 {
+		t.Error("Expected nothing to be saved")
+	}
+
+	select {
+	case report := <-expired:
+		t.Errorf("Expected the transaction to expire once, got %+v", report)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
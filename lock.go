@@ -0,0 +1,97 @@
+package dbx
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+//ErrLockRequiresTransaction is returned when a locking statement is executed outside a transaction
+var ErrLockRequiresTransaction = errors.New("Locking statement must be executed in a transaction")
+
+//ErrLockNotAvailable is returned when a NOWAIT statement cannot lock its rows immediately
+var ErrLockNotAvailable = errors.New("Lock is not available")
+
+//lockNotAvailableCode is the SQLSTATE of lock_not_available
+const lockNotAvailableCode = "55P03"
+
+//lockError is a driver error of a lock which is not available
+type lockError struct {
+	err error
+}
+
+func (me *lockError) Error() string {
+	return ErrLockNotAvailable.Error() + ": " + me.err.Error()
+}
+
+//Is determine if target is ErrLockNotAvailable
+func (me *lockError) Is(target error) bool {
+	return target == ErrLockNotAvailable
+}
+
+//Unwrap returns the driver error
+func (me *lockError) Unwrap() error {
+	return me.err
+}
+
+//ForUpdate locks the rows selected by the statement for update, see SkipLocked and NoWait.
+//The statement must be executed in a transaction
+func (me *Statement) ForUpdate() *Statement {
+	me.lock = "FOR UPDATE"
+	return me
+}
+
+//ForShare locks the rows selected by the statement in share mode, see SkipLocked and NoWait.
+//The statement must be executed in a transaction
+func (me *Statement) ForShare() *Statement {
+	me.lock = "FOR SHARE"
+	return me
+}
+
+//SkipLocked skips the rows which cannot be locked immediately, rows are locked for update if no lock is set
+func (me *Statement) SkipLocked() *Statement {
+	if me.lock == "" {
+		me.ForUpdate()
+	}
+	me.lockWait = "SKIP LOCKED"
+	return me
+}
+
+//NoWait fails with ErrLockNotAvailable if rows cannot be locked immediately, rows are locked for update if no lock is set
+func (me *Statement) NoWait() *Statement {
+	if me.lock == "" {
+		me.ForUpdate()
+	}
+	me.lockWait = "NOWAIT"
+	return me
+}
+
+//IsLocking determine if the statement locks the rows it selects
+func (me *Statement) IsLocking() bool {
+	return me.lock != ""
+}
+
+//lockClause returns the locking clause of the statement
+func (me *Statement) lockClause() string {
+	return strings.TrimSpace(me.lock + " " + me.lockWait)
+}
+
+//lockErr returns err as ErrLockNotAvailable if it reports a lock which is not available
+func lockErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == lockNotAvailableCode {
+		return &lockError{err: err}
+	}
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) && stateErr.SQLState() == lockNotAvailableCode {
+		return &lockError{err: err}
+	}
+	if strings.Contains(err.Error(), "NOWAIT is set") {
+		return &lockError{err: err}
+	}
+	return err
+}
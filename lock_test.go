@@ -0,0 +1,91 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+
+	"github.com/supendi/dbx/internal/driverutil"
+)
+
+func Test_Statement_LockClause(t *testing.T) {
+	cases := map[string]*Statement{
+		"SELECT * FROM job FOR UPDATE":             NewStatement("SELECT * FROM job;").ForUpdate(),
+		"SELECT * FROM job FOR SHARE NOWAIT":       NewStatement("SELECT * FROM job").ForShare().NoWait(),
+		"SELECT * FROM job FOR UPDATE SKIP LOCKED": NewStatement("SELECT * FROM job").SkipLocked(),
+	}
+	for expected, statement := range cases {
+		bound, err := statement.Bind(context.Background())
+		if err != nil {
+			t.Fatalf("Bind error: %s", err.Error())
+		}
+		if bound.SQL != expected {
+			t.Errorf("Expected %q, but got %q", expected, bound.SQL)
+		}
+		if rebound, _ := bound.Bind(context.Background()); rebound.SQL != expected {
+			t.Errorf("Expected the locking clause to be appended once, got %q", rebound.SQL)
+		}
+		if !statement.IsLocking() || bound.IsLocking() {
+			t.Errorf("Expected the statement to be locking and its bound copy not")
+		}
+	}
+}
+
+func Test_Client_LockOutsideTransaction(t *testing.T) {
+	client := NewClient(nil)
+	if _, err := client.QueryStatementContext(context.Background(), NewStatement("SELECT * FROM job").ForUpdate()); !errors.Is(err, ErrLockRequiresTransaction) {
+		t.Errorf("Expected ErrLockRequiresTransaction, got %v", err)
+	}
+	if _, err := client.QueryStatement(NewStatement("SELECT * FROM job").SkipLocked()); !errors.Is(err, ErrLockRequiresTransaction) {
+		t.Errorf("Expected ErrLockRequiresTransaction, got %v", err)
+	}
+}
+
+func Test_lockErr(t *testing.T) {
+	driverErr := fmt.Errorf("query: %w", &pq.Error{Code: "55P03", Message: "could not obtain lock on row"})
+	err := lockErr(driverErr)
+	var pqErr *pq.Error
+	if !errors.Is(err, ErrLockNotAvailable) || !errors.As(err, &pqErr) {
+		t.Errorf("Expected ErrLockNotAvailable wrapping the driver error, got %v", err)
+	}
+
+	other := &pq.Error{Code: "23505"}
+	if err := lockErr(other); err != other {
+		t.Errorf("Expected other errors to be returned as is, got %v", err)
+	}
+	if lockErr(nil) != nil {
+		t.Errorf("Expected nil")
+	}
+}
+
+func Test_Context_LockingQuery(t *testing.T) {
+	client, database := newScriptedClient(t, "postgres", func(call *scriptedCall) (*driverutil.Rows, error) {
+		if strings.HasSuffix(call.SQL, "FOR UPDATE NOWAIT") {
+			return nil, &pq.Error{Code: "55P03"}
+		}
+		return scriptedRows("id", int64(1)), nil
+	})
+
+	ctx, dbContext := client.NewScope(context.Background())
+	if _, err := dbContext.BeginTransactionContext(ctx); err != nil {
+		t.Fatalf("BeginTransactionContext error: %s", err.Error())
+	}
+	defer dbContext.CompleteTransaction()
+
+	_, err := dbContext.QueryStatementContext(ctx, NewStatement("SELECT id FROM job").NoWait())
+	if !errors.Is(err, ErrLockNotAvailable) {
+		t.Errorf("Expected ErrLockNotAvailable, got %v", err)
+	}
+	rows, err := dbContext.Client.QueryStatementContext(ctx, NewStatement("SELECT id FROM job").SkipLocked())
+	if err != nil {
+		t.Fatalf("QueryStatementContext error: %s", err.Error())
+	}
+	rows.Close()
+	if len(database.statements(`FOR UPDATE SKIP LOCKED$`)) != 1 {
+		t.Errorf("Expected the query to skip locked rows, got %+v", database.statements(""))
+	}
+}
@@ -0,0 +1,119 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/supendi/dbx"
+)
+
+//fileNamePattern matches migration file names like 0001_create_order.up.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+//Migration represent a single versioned schema change, written either as SQL scripts or as Go functions
+type Migration struct {
+	Version  int64
+	Name     string
+	UpSQL    string
+	DownSQL  string
+	UpFunc   func(ctx context.Context, tx *dbx.Transaction) error
+	DownFunc func(ctx context.Context, tx *dbx.Transaction) error
+}
+
+//Checksum returns the checksum of the up script. Go migrations do not have checksum
+func (me *Migration) Checksum() string {
+	if me.UpFunc != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(me.UpSQL))
+	return hex.EncodeToString(sum[:])
+}
+
+//hasDown determine if the migration can be reverted
+func (me *Migration) hasDown() bool {
+	return me.DownFunc != nil || me.DownSQL != ""
+}
+
+//up applies the migration inside the transaction
+func (me *Migration) up(ctx context.Context, tx *dbx.Transaction) error {
+	if me.UpFunc != nil {
+		return me.UpFunc(ctx, tx)
+	}
+	_, err := tx.Tx.ExecContext(ctx, me.UpSQL)
+	return err
+}
+
+//down reverts the migration inside the transaction
+func (me *Migration) down(ctx context.Context, tx *dbx.Transaction) error {
+	if me.DownFunc != nil {
+		return me.DownFunc(ctx, tx)
+	}
+	_, err := tx.Tx.ExecContext(ctx, me.DownSQL)
+	return err
+}
+
+//Load reads SQL migrations from dir of fsys. Each migration consists of a <version>_<name>.up.sql
+//file and an optional <version>_<name>.down.sql file. Other files are ignored
+func Load(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %v", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.UpSQL = string(content)
+		} else {
+			migration.DownSQL = string(content)
+		}
+	}
+
+	migrations := []*Migration{}
+	for _, migration := range byVersion {
+		if migration.UpSQL == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, migration)
+	}
+	sortMigrations(migrations)
+	return migrations, nil
+}
+
+//sortMigrations sorts migrations by version ascending
+func sortMigrations(migrations []*Migration) {
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
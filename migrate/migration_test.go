@@ -0,0 +1,48 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func Test_Load(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_total.up.sql":       {Data: []byte("ALTER TABLE person ADD COLUMN total INT")},
+		"migrations/0001_create_person.up.sql":   {Data: []byte("CREATE TABLE person (id TEXT)")},
+		"migrations/0001_create_person.down.sql": {Data: []byte("DROP TABLE person")},
+		"migrations/README.md":                   {Data: []byte("not a migration")},
+	}
+
+	migrations, err := Load(fsys, "migrations")
+	if err != nil {
+		t.Fatalf("Load migrations error: %s", err.Error())
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("Expected 2 migrations, but got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "create_person" || migrations[0].DownSQL != "DROP TABLE person" {
+		t.Errorf("Unexpected first migration %+v", migrations[0])
+	}
+	if migrations[1].Version != 2 || migrations[1].hasDown() {
+		t.Errorf("Unexpected second migration %+v", migrations[1])
+	}
+}
+
+func Test_Load_DuplicateVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create_person.up.sql": {Data: []byte("CREATE TABLE person (id TEXT)")},
+		"0001_create_order.up.sql":  {Data: []byte(`CREATE TABLE "order" (id TEXT)`)},
+	}
+	if _, err := Load(fsys, "."); err == nil {
+		t.Errorf("Load must fail when two migrations share a version")
+	}
+}
+
+func Test_Load_MissingUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_create_person.down.sql": {Data: []byte("DROP TABLE person")},
+	}
+	if _, err := Load(fsys, "."); err == nil {
+		t.Errorf("Load must fail when a migration has no up script")
+	}
+}
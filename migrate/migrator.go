@@ -0,0 +1,297 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/supendi/dbx"
+)
+
+//DefaultTable is the table used to record applied migrations
+const DefaultTable = "schema_migrations"
+
+var (
+	//ErrMigrationModified is returned when an applied migration was edited afterwards
+	ErrMigrationModified = errors.New("Migration has been modified after it was applied")
+	//ErrNoDownMigration is returned when reverting a migration which has no down script
+	ErrNoDownMigration = errors.New("Migration has no down script")
+	//ErrUnknownVersion is returned when a version is not found in the migration source
+	ErrUnknownVersion = errors.New("Migration version is unknown")
+)
+
+//Status represent the state of a single migration
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	//Modified is true when the migration source no longer matches the checksum recorded when it was applied
+	Modified bool
+	//Missing is true when the migration is recorded as applied but not found in the migration source
+	Missing bool
+}
+
+//appliedMigration represent a row of the migrations table
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+//Migrator applies and reverts migrations
+type Migrator struct {
+	client     *dbx.Client
+	migrations []*Migration
+	table      string
+}
+
+//WithTable set the table used to record applied migrations
+func (me *Migrator) WithTable(table string) *Migrator {
+	me.table = table
+	return me
+}
+
+//Up applies all pending migrations
+func (me *Migrator) Up(ctx context.Context) error {
+	return me.run(ctx, func(applied map[int64]*appliedMigration) error {
+		for _, migration := range me.migrations {
+			if applied[migration.Version] != nil {
+				continue
+			}
+			if err := me.apply(ctx, migration); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//Down reverts the most recently applied migration
+func (me *Migrator) Down(ctx context.Context) error {
+	return me.run(ctx, func(applied map[int64]*appliedMigration) error {
+		var latest *appliedMigration
+		for _, record := range applied {
+			if latest == nil || record.Version > latest.Version {
+				latest = record
+			}
+		}
+		if latest == nil {
+			return nil
+		}
+		return me.revert(ctx, latest.Version)
+	})
+}
+
+//To applies or reverts migrations until version is the latest applied migration. Version 0 reverts all migrations
+func (me *Migrator) To(ctx context.Context, version int64) error {
+	if version != 0 && me.find(version) == nil {
+		return fmt.Errorf("migration %d: %w", version, ErrUnknownVersion)
+	}
+	return me.run(ctx, func(applied map[int64]*appliedMigration) error {
+		for _, record := range applied {
+			if record.Version > version && me.find(record.Version) == nil {
+				return fmt.Errorf("migration %d: %w", record.Version, ErrUnknownVersion)
+			}
+		}
+		for i := len(me.migrations) - 1; i >= 0; i-- {
+			migration := me.migrations[i]
+			if migration.Version > version && applied[migration.Version] != nil {
+				if err := me.revert(ctx, migration.Version); err != nil {
+					return err
+				}
+			}
+		}
+		for _, migration := range me.migrations {
+			if migration.Version <= version && applied[migration.Version] == nil {
+				if err := me.apply(ctx, migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+//Status returns the state of every known and applied migration ordered by version
+func (me *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	if err := me.createTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := me.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []*Status{}
+	for _, migration := range me.migrations {
+		status := &Status{Version: migration.Version, Name: migration.Name}
+		if record := applied[migration.Version]; record != nil {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = record.Checksum != migration.Checksum()
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		if me.find(record.Version) == nil {
+			appliedAt := record.AppliedAt
+			statuses = append(statuses, &Status{Version: record.Version, Name: record.Name, Applied: true, AppliedAt: &appliedAt, Missing: true})
+		}
+	}
+	sortStatuses(statuses)
+	return statuses, nil
+}
+
+//run takes the migration lock, creates the migrations table, verifies applied migrations then call fn
+func (me *Migrator) run(ctx context.Context, fn func(applied map[int64]*appliedMigration) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	unlock, err := me.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := me.createTable(ctx); err != nil {
+		return err
+	}
+
+	applied, err := me.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for _, migration := range me.migrations {
+		record := applied[migration.Version]
+		if record != nil && record.Checksum != migration.Checksum() {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, ErrMigrationModified)
+		}
+	}
+	return fn(applied)
+}
+
+//apply runs the migration and records it in a single transaction
+func (me *Migrator) apply(ctx context.Context, migration *Migration) error {
+	return me.inTransaction(ctx, migration, func(tx *dbx.Transaction) error {
+		if err := migration.up(ctx, tx); err != nil {
+			return err
+		}
+		statement := dbx.NewStatement("INSERT INTO " + me.table + " (version, name, checksum, applied_at) VALUES (:version, :name, :checksum, :applied_at)")
+		statement.AddParameter("version", migration.Version)
+		statement.AddParameter("name", migration.Name)
+		statement.AddParameter("checksum", migration.Checksum())
+		statement.AddParameter("applied_at", time.Now().UTC())
+		_, err := tx.ExecStatementContext(ctx, statement)
+		return err
+	})
+}
+
+//revert reverts the migration and removes its record in a single transaction
+func (me *Migrator) revert(ctx context.Context, version int64) error {
+	migration := me.find(version)
+	if migration == nil {
+		return fmt.Errorf("migration %d: %w", version, ErrUnknownVersion)
+	}
+	if !migration.hasDown() {
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, ErrNoDownMigration)
+	}
+	return me.inTransaction(ctx, migration, func(tx *dbx.Transaction) error {
+		if err := migration.down(ctx, tx); err != nil {
+			return err
+		}
+		statement := dbx.NewStatement("DELETE FROM " + me.table + " WHERE version = :version")
+		statement.AddParameter("version", migration.Version)
+		_, err := tx.ExecStatementContext(ctx, statement)
+		return err
+	})
+}
+
+//inTransaction runs fn inside a new transaction, commit it on success and rollback on failure
+func (me *Migrator) inTransaction(ctx context.Context, migration *Migration, fn func(tx *dbx.Transaction) error) error {
+	tx, err := dbx.NewTransaction(me.client.DB)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		if rollbackError := tx.Rollback(); rollbackError != nil {
+			return rollbackError
+		}
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return tx.Commit()
+}
+
+//createTable creates the migrations table if it does not exist
+func (me *Migrator) createTable(ctx context.Context) error {
+	_, err := me.client.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+me.table+" (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, checksum VARCHAR(64) NOT NULL, applied_at TIMESTAMP NOT NULL)")
+	return err
+}
+
+//applied returns applied migrations keyed by version
+func (me *Migrator) applied(ctx context.Context) (map[int64]*appliedMigration, error) {
+	rows, err := me.client.QueryStatementContext(ctx, dbx.NewStatement("SELECT version, name, checksum, applied_at FROM "+me.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]*appliedMigration{}
+	for rows.Next() {
+		record := &appliedMigration{}
+		err = rows.Scan(&record.Version, &record.Name, &record.Checksum, &record.AppliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[record.Version] = record
+	}
+	return applied, rows.Err()
+}
+
+//lock takes a session advisory lock so concurrent runners apply migrations one at a time, see Client.AdvisoryLock.
+//Drivers without advisory locks are not locked
+func (me *Migrator) lock(ctx context.Context) (func() error, error) {
+	lock, err := me.client.AdvisoryLock(ctx, "dbx/migrate:"+me.table)
+	if errors.Is(err, dbx.ErrAdvisoryLockUnsupported) {
+		return func() error { return nil }, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		return lock.Unlock(context.Background())
+	}, nil
+}
+
+//find returns the migration of the version, nil if not found
+func (me *Migrator) find(version int64) *Migration {
+	for _, migration := range me.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+//sortStatuses sorts statuses by version ascending
+func sortStatuses(statuses []*Status) {
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+}
+
+//NewMigrator create new migrator instance
+func NewMigrator(client *dbx.Client, migrations []*Migration) *Migrator {
+	sorted := append([]*Migration{}, migrations...)
+	sortMigrations(sorted)
+	return &Migrator{
+		client:     client,
+		migrations: sorted,
+		table:      DefaultTable,
+	}
+}
+
@@ -0,0 +1,171 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3" //needed
+	"github.com/supendi/dbx"
+	"github.com/supendi/dbx/dbxtest"
+)
+
+func newTestClient(t *testing.T) *dbx.Client {
+	db, err := sqlx.Open("sqlite3", filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatalf("Open db error: %s", err.Error())
+	}
+	t.Cleanup(func() { db.Close() })
+	return dbx.NewClient(db)
+}
+
+func testMigrations() []*Migration {
+	return []*Migration{
+		{Version: 1, Name: "create_person", UpSQL: "CREATE TABLE person (id TEXT PRIMARY KEY, name TEXT);", DownSQL: "DROP TABLE person;"},
+		{Version: 2, Name: "create_order", UpSQL: `CREATE TABLE "order" (id TEXT PRIMARY KEY); CREATE INDEX order_id ON "order" (id);`, DownSQL: `DROP TABLE "order";`},
+		{Version: 3, Name: "create_person_email", UpSQL: "CREATE TABLE person_email (person_id TEXT, email TEXT);", DownSQL: "DROP TABLE person_email;"},
+	}
+}
+
+func appliedVersions(t *testing.T, migrator *Migrator) []int64 {
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status error: %s", err.Error())
+	}
+	versions := []int64{}
+	for _, status := range statuses {
+		if status.Applied {
+			versions = append(versions, status.Version)
+		}
+	}
+	return versions
+}
+
+func Test_Migrator_UpDown(t *testing.T) {
+	client := newTestClient(t)
+	migrator := NewMigrator(client, testMigrations())
+	ctx := context.Background()
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up error: %s", err.Error())
+	}
+	if versions := appliedVersions(t, migrator); len(versions) != 3 {
+		t.Fatalf("Expected 3 applied migrations, but got %v", versions)
+	}
+	if _, err := client.ExecStatement(dbx.NewStatement("INSERT INTO person_email (person_id, email) VALUES ('1', 'dadang@example.com')")); err != nil {
+		t.Fatalf("Migrated table expected to be usable: %s", err.Error())
+	}
+
+	if err := migrator.Down(ctx); err != nil {
+		t.Fatalf("Down error: %s", err.Error())
+	}
+	if versions := appliedVersions(t, migrator); len(versions) != 2 || versions[1] != 2 {
+		t.Errorf("Expected migrations 1 and 2 applied, but got %v", versions)
+	}
+}
+
+func Test_Migrator_To(t *testing.T) {
+	client := newTestClient(t)
+	migrator := NewMigrator(client, testMigrations())
+	ctx := context.Background()
+
+	if err := migrator.To(ctx, 2); err != nil {
+		t.Fatalf("To(2) error: %s", err.Error())
+	}
+	if versions := appliedVersions(t, migrator); len(versions) != 2 {
+		t.Errorf("Expected 2 applied migrations, but got %v", versions)
+	}
+	if err := migrator.To(ctx, 0); err != nil {
+		t.Fatalf("To(0) error: %s", err.Error())
+	}
+	if versions := appliedVersions(t, migrator); len(versions) != 0 {
+		t.Errorf("Expected no applied migration, but got %v", versions)
+	}
+	if err := migrator.To(ctx, 42); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("To unknown version expected to return ErrUnknownVersion, but got %v", err)
+	}
+}
+
+func Test_Migrator_FailedMigrationIsRolledBack(t *testing.T) {
+	client := newTestClient(t)
+	migrations := append(testMigrations()[:1], &Migration{Version: 2, Name: "broken", UpSQL: "CREATE TABLE broken (id TEXT); INSERT INTO missing VALUES (1);"})
+	migrator := NewMigrator(client, migrations)
+
+	if err := migrator.Up(context.Background()); err == nil {
+		t.Fatalf("Up expected to fail")
+	}
+	if versions := appliedVersions(t, migrator); len(versions) != 1 {
+		t.Errorf("Expected only the first migration applied, but got %v", versions)
+	}
+	if _, err := client.ExecStatement(dbx.NewStatement("SELECT * FROM broken")); err == nil {
+		t.Errorf("Table of the failed migration must be rolled back")
+	}
+}
+
+func Test_Migrator_DetectModified(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	if err := NewMigrator(client, testMigrations()).Up(ctx); err != nil {
+		t.Fatalf("Up error: %s", err.Error())
+	}
+
+	edited := testMigrations()
+	edited[0].UpSQL = "CREATE TABLE person (id TEXT PRIMARY KEY, name TEXT, age INT);"
+	migrator := NewMigrator(client, edited)
+
+	if err := migrator.Up(ctx); !errors.Is(err, ErrMigrationModified) {
+		t.Errorf("Up expected to return ErrMigrationModified, but got %v", err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status error: %s", err.Error())
+	}
+	if !statuses[0].Modified || statuses[1].Modified {
+		t.Errorf("Only the first migration expected to be modified")
+	}
+}
+
+func Test_Migrator_GoMigration(t *testing.T) {
+	client := newTestClient(t)
+	migrations := append(testMigrations()[:1], &Migration{
+		Version: 2,
+		Name:    "seed_person",
+		UpFunc: func(ctx context.Context, tx *dbx.Transaction) error {
+			statement := dbx.NewStatement("INSERT INTO person (id, name) VALUES (:id, :name)")
+			statement.AddParameter("id", "1")
+			statement.AddParameter("name", "Andi Setiawan")
+			_, err := tx.ExecStatementContext(ctx, statement)
+			return err
+		},
+	})
+
+	if err := NewMigrator(client, migrations).Up(context.Background()); err != nil {
+		t.Fatalf("Up error: %s", err.Error())
+	}
+	rows, err := client.QueryStatement(dbx.NewStatement("SELECT id FROM person"))
+	if err != nil {
+		t.Fatalf("Query error: %s", err.Error())
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Errorf("Go migration expected to insert a person")
+	}
+}
+
+func Test_Migrator_LocksBeforeCreatingTable(t *testing.T) {
+	fake := dbxtest.NewFake().WithDriverName("postgres")
+	fake.OnQuery(`pg_advisory_(un)?lock`).ReturnRows(dbxtest.NewRows("locked").AddRow(""))
+	if err := NewMigrator(fake.Client(), nil).Up(context.Background()); err != nil {
+		t.Fatalf("Up error: %s", err.Error())
+	}
+
+	statements := []string{}
+	for _, call := range fake.Calls() {
+		statements = append(statements, call.SQL)
+	}
+	if len(statements) < 3 || statements[0] != "SELECT pg_advisory_lock($1)" || statements[1] != "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, checksum VARCHAR(64) NOT NULL, applied_at TIMESTAMP NOT NULL)" || statements[len(statements)-1] != "SELECT pg_advisory_unlock($1)" {
+		t.Errorf("Expected the migrations table to be created under the advisory lock, got %q", statements)
+	}
+}
@@ -0,0 +1,132 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//ErrDataSourceNameRequired is returned by Listen when the client has no data source name, see WithDataSourceName
+var ErrDataSourceNameRequired = errors.New("Data source name is required to listen")
+
+const (
+	listenMinReconnect = time.Second
+	listenMaxReconnect = time.Minute
+	listenPing         = 90 * time.Second
+)
+
+//Notification represent a notification received on a listened channel. Reconnected notifications have no channel,
+//they report that the connection was lost and notifications sent meanwhile were missed
+type Notification struct {
+	Channel     string
+	Payload     string
+	PID         int
+	Reconnected bool
+}
+
+//listener is the connection notifications are received from, pq.Listener implements it
+type listener interface {
+	Listen(channel string) error
+	NotificationChannel() <-chan *pq.Notification
+	Ping() error
+	Close() error
+}
+
+//newListener create new listener connecting to the data source name, it's replaced by tests
+var newListener = func(dataSourceName string, callback pq.EventCallbackType) listener {
+	return pq.NewListener(dataSourceName, listenMinReconnect, listenMaxReconnect, callback)
+}
+
+//WithDataSourceName set the data source name Listen connects to. It must be set before the client is used
+func (me *Client) WithDataSourceName(dataSourceName string) *Client {
+	me.dataSourceName = dataSourceName
+	return me
+}
+
+//Listen listens the channels on a dedicated connection and returns the received notifications until ctx is done,
+//the returned channel is closed afterwards. The connection is reestablished and the channels listened again
+//when it's lost, a Reconnected notification is sent then. It's supported on Postgres only
+func (me *Client) Listen(ctx context.Context, channels ...string) (<-chan *Notification, error) {
+	if me.dataSourceName == "" {
+		return nil, ErrDataSourceNameRequired
+	}
+	if len(channels) == 0 {
+		return nil, errors.New("At least one channel is required to listen")
+	}
+
+	failed := make(chan error, 1)
+	conn := newListener(me.dataSourceName, func(event pq.ListenerEventType, err error) {
+		if event == pq.ListenerEventConnectionAttemptFailed {
+			select {
+			case failed <- err:
+			default:
+			}
+		}
+	})
+	listened := make(chan error, 1)
+	go func() {
+		for _, channel := range channels {
+			if err := conn.Listen(channel); err != nil {
+				listened <- err
+				return
+			}
+		}
+		listened <- nil
+	}()
+
+	var err error
+	select {
+	case err = <-listened:
+	case err = <-failed:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	notifications := make(chan *Notification)
+	go me.receive(ctx, conn, notifications)
+	return notifications, nil
+}
+
+//receive sends the notifications of the listener until ctx is done, then closes the listener and notifications
+func (me *Client) receive(ctx context.Context, conn listener, notifications chan<- *Notification) {
+	defer close(notifications)
+	defer conn.Close()
+
+	ping := time.NewTicker(listenPing)
+	defer ping.Stop()
+	for {
+		var notification *Notification
+		select {
+		case <-ctx.Done():
+			return
+		case <-ping.C:
+			go conn.Ping()
+			continue
+		case received := <-conn.NotificationChannel():
+			notification = &Notification{Reconnected: true}
+			if received != nil {
+				notification = &Notification{Channel: received.Channel, Payload: received.Extra, PID: received.BePid}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case notifications <- notification:
+		}
+	}
+}
+
+//AddNotify add the statement notifying the channel with the payload. Postgres delivers it when the transaction
+//SaveChanges runs in commits, or right away without one. It's supported on Postgres only
+func (me *Context) AddNotify(channel string, payload string) {
+	statement := NewStatement("SELECT pg_notify(:dbx_channel, :dbx_payload)")
+	statement.AddParameter("dbx_channel", channel)
+	statement.AddParameter("dbx_payload", payload)
+	me.AddStatement(statement)
+}
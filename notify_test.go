@@ -0,0 +1,134 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type fakeListener struct {
+	mutex         sync.Mutex
+	channels      []string
+	notifications chan *pq.Notification
+	closed        bool
+}
+
+func (me *fakeListener) Listen(channel string) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.channels = append(me.channels, channel)
+	return nil
+}
+
+func (me *fakeListener) NotificationChannel() <-chan *pq.Notification {
+	return me.notifications
+}
+
+func (me *fakeListener) Ping() error {
+	return nil
+}
+
+func (me *fakeListener) Close() error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.closed = true
+	return nil
+}
+
+func Test_Client_Listen(t *testing.T) {
+	conn := &fakeListener{notifications: make(chan *pq.Notification)}
+	defer func(original func(string, pq.EventCallbackType) listener) { newListener = original }(newListener)
+	newListener = func(dataSourceName string, callback pq.EventCallbackType) listener {
+		return conn
+	}
+
+	client := NewClient(sqlx.NewDb(nil, "postgres"))
+	if _, err := client.Listen(context.Background(), "orders"); err != ErrDataSourceNameRequired {
+		t.Errorf("Expected ErrDataSourceNameRequired, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	notifications, err := client.WithDataSourceName("postgres://localhost/dbx").Listen(ctx, "orders", "payments")
+	if err != nil {
+		t.Fatalf("Listen error: %s", err.Error())
+	}
+	if len(conn.channels) != 2 {
+		t.Errorf("Expected both channels to be listened, got %v", conn.channels)
+	}
+
+	conn.notifications <- &pq.Notification{Channel: "orders", Extra: "o1", BePid: 42}
+	if notification := <-notifications; notification.Channel != "orders" || notification.Payload != "o1" || notification.PID != 42 {
+		t.Errorf("Unexpected notification %+v", notification)
+	}
+	conn.notifications <- nil
+	if notification := <-notifications; !notification.Reconnected {
+		t.Errorf("Expected reconnected notification, got %+v", notification)
+	}
+
+	cancel()
+	if _, ok := <-notifications; ok {
+		t.Errorf("Expected notifications to be closed when ctx is done")
+	}
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if !conn.closed {
+		t.Errorf("Expected the listener to be closed when ctx is done")
+	}
+}
+
+func Test_Client_ListenConnectionFailed(t *testing.T) {
+	defer func(original func(string, pq.EventCallbackType) listener) { newListener = original }(newListener)
+	conn := &blockingListener{fakeListener: fakeListener{notifications: make(chan *pq.Notification)}, release: make(chan struct{})}
+	defer close(conn.release)
+	newListener = func(dataSourceName string, callback pq.EventCallbackType) listener {
+		go callback(pq.ListenerEventConnectionAttemptFailed, errors.New("connection refused"))
+		return conn
+	}
+
+	client := NewClient(sqlx.NewDb(nil, "postgres")).WithDataSourceName("postgres://localhost/dbx")
+	if _, err := client.Listen(context.Background(), "orders"); err == nil || err.Error() != "connection refused" {
+		t.Errorf("Expected the connection error, got %v", err)
+	}
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if !conn.closed {
+		t.Errorf("Expected the listener to be closed when it cannot connect")
+	}
+}
+
+//blockingListener blocks Listen until it's released, like pq.Listener does while it's not connected
+type blockingListener struct {
+	fakeListener
+	release chan struct{}
+}
+
+func (me *blockingListener) Listen(channel string) error {
+	<-me.release
+	return nil
+}
+
+func Test_Context_AddNotify(t *testing.T) {
+	client, database := newScriptedClient(t, "postgres", nil)
+	dbContext := client.NewContext()
+	statement := NewStatement("UPDATE \"order\" SET total = :total WHERE id = :id")
+	statement.AddParameter("total", 100)
+	statement.AddParameter("id", "o1")
+	dbContext.AddStatement(statement)
+	dbContext.AddNotify("orders", "o1")
+	if _, err := dbContext.SaveChanges(context.Background()); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+
+	if kinds := strings.Join(database.kinds(), " "); kinds != "begin exec exec commit" {
+		t.Fatalf("Expected the statements to be saved in a transaction, got %s", kinds)
+	}
+	notify := database.statements("")[1]
+	if notify.SQL != "SELECT pg_notify($1, $2)" || notify.Args[0] != "orders" || notify.Args[1] != "o1" {
+		t.Errorf("Expected the notification to be sent in the transaction, got %+v", notify)
+	}
+}
@@ -0,0 +1,523 @@
+package dbx
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+//DefaultPageLimit is the page size used when PageRequest.Limit is not set
+const DefaultPageLimit = 10
+
+const totalCountColumn = "dbx_total_count"
+
+//ErrInvalidCursor is returned when a cursor token is malformed, was signed with another secret or belongs to another
+//paginator, one of another statement or ordering
+var ErrInvalidCursor = errors.New("Cursor is invalid or has been tampered with")
+
+//CountStrategy determine how the total number of records is calculated
+type CountStrategy int
+
+const (
+	//CountNone does not calculate the total number of records
+	CountNone CountStrategy = iota
+	//CountWindow calculates the total by adding COUNT(*) OVER() to the page query
+	CountWindow
+	//CountQuery calculates the total by running a separate COUNT query
+	CountQuery
+)
+
+//SortColumn represent a result column used to order and paginate a statement
+type SortColumn struct {
+	Name string
+	Desc bool
+}
+
+//Asc returns ascending sort column
+func Asc(name string) SortColumn {
+	return SortColumn{Name: name}
+}
+
+//Desc returns descending sort column
+func Desc(name string) SortColumn {
+	return SortColumn{Name: name, Desc: true}
+}
+
+//PageRequest represent the requested page. An empty cursor requests the first page
+type PageRequest struct {
+	Limit  int
+	Cursor string
+}
+
+//Page represent a single page of records
+type Page[T any] struct {
+	Items      []T
+	Total      *int64
+	NextCursor string
+	PrevCursor string
+}
+
+//HasNext determine if there is a next page
+func (me *Page[T]) HasNext() bool {
+	return me.NextCursor != ""
+}
+
+//HasPrev determine if there is a previous page
+func (me *Page[T]) HasPrev() bool {
+	return me.PrevCursor != ""
+}
+
+//cursor is the payload of a cursor token
+type cursor struct {
+	Key      string        `json:"k"`
+	Offset   int           `json:"o,omitempty"`
+	Values   []interface{} `json:"v,omitempty"`
+	Backward bool          `json:"b,omitempty"`
+}
+
+//CursorCodec encodes and decodes signed cursor tokens
+type CursorCodec struct {
+	secret []byte
+}
+
+//encode serializes and signs the cursor
+func (me *CursorCodec) encode(c *cursor) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(payload) + "." + encoding.EncodeToString(me.sign(payload)), nil
+}
+
+//decode verifies and deserializes the cursor token
+func (me *CursorCodec) decode(token string) (*cursor, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+	encoding := base64.RawURLEncoding
+	payload, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	signature, err := encoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, me.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	c := &cursor{}
+	if err := decoder.Decode(c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+//sign returns HMAC-SHA256 signature of the payload
+func (me *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, me.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+//NewCursorCodec create new cursor codec instance. The secret must be kept private, it prevents clients from forging cursors
+func NewCursorCodec(secret []byte) *CursorCodec {
+	return &CursorCodec{
+		secret: secret,
+	}
+}
+
+//Paginator wraps a statement with offset or keyset pagination
+type Paginator struct {
+	statement *Statement
+	orderBy   []SortColumn
+	keyset    bool
+	count     CountStrategy
+	codec     *CursorCodec
+}
+
+//WithCount set the strategy used to calculate the total number of records.
+//Keyset paginators always use a separate COUNT query, whatever strategy is set. So do paginators of locking
+//statements with CountWindow, window functions can't be used with FOR UPDATE
+func (me *Paginator) WithCount(strategy CountStrategy) *Paginator {
+	me.count = strategy
+	return me
+}
+
+//windowCount determine if the total is calculated by the page query
+func (me *Paginator) windowCount() bool {
+	return me.count == CountWindow && !me.keyset && !me.statement.IsLocking()
+}
+
+//key returns fingerprint of the paginator, it binds cursors to the statement and the ordering they were created for
+func (me *Paginator) key() string {
+	var builder strings.Builder
+	if me.keyset {
+		builder.WriteString("keyset")
+	} else {
+		builder.WriteString("offset")
+	}
+	for _, column := range me.orderBy {
+		builder.WriteString("|" + column.Name)
+		if column.Desc {
+			builder.WriteString(" desc")
+		}
+	}
+	hash := sha256.Sum256([]byte(builder.String() + "\x00" + me.baseSQL()))
+	return base64.RawURLEncoding.EncodeToString(hash[:12])
+}
+
+//baseSQL returns the wrapped statement SQL without trailing semicolon
+func (me *Paginator) baseSQL() string {
+	return strings.TrimRight(strings.TrimSpace(me.statement.SQL), "; \n\t")
+}
+
+//newStatement returns a statement with a copy of the wrapped statement parameters and options, such as its lock
+func (me *Paginator) newStatement(sql string) *Statement {
+	statement := me.statement.clone()
+	statement.SQL = sql
+	return statement
+}
+
+//orderByClause returns the ORDER BY clause, reversed when backward is true
+func (me *Paginator) orderByClause(backward bool) string {
+	columns := make([]string, len(me.orderBy))
+	for i, column := range me.orderBy {
+		desc := column.Desc != backward
+		if desc {
+			columns[i] = column.Name + " DESC"
+		} else {
+			columns[i] = column.Name + " ASC"
+		}
+	}
+	return " ORDER BY " + strings.Join(columns, ", ")
+}
+
+//buildOffsetStatement returns the statement fetching one record more than the limit starting from offset
+func (me *Paginator) buildOffsetStatement(offset int, limit int) *Statement {
+	selectClause := "SELECT dbx_page.*"
+	if me.windowCount() {
+		selectClause += ", COUNT(*) OVER() AS " + totalCountColumn
+	}
+	statement := me.newStatement(selectClause + " FROM (" + me.baseSQL() + ") AS dbx_page" + me.orderByClause(false) + " LIMIT :dbx_limit OFFSET :dbx_offset")
+	statement.AddParameter("dbx_limit", limit+1)
+	statement.AddParameter("dbx_offset", offset)
+	return statement
+}
+
+//buildKeysetStatement returns the statement fetching one record more than the limit after (or before) the cursor
+func (me *Paginator) buildKeysetStatement(c *cursor, limit int) *Statement {
+	sql := "SELECT dbx_page.* FROM (" + me.baseSQL() + ") AS dbx_page"
+	backward := c != nil && c.Backward
+
+	if c != nil {
+		var predicates []string
+		for i := range me.orderBy {
+			var terms []string
+			for j := 0; j < i; j++ {
+				terms = append(terms, fmt.Sprintf("%s = :dbx_cursor_%d", me.orderBy[j].Name, j))
+			}
+			operator := ">"
+			if me.orderBy[i].Desc != backward {
+				operator = "<"
+			}
+			terms = append(terms, fmt.Sprintf("%s %s :dbx_cursor_%d", me.orderBy[i].Name, operator, i))
+			predicates = append(predicates, "("+strings.Join(terms, " AND ")+")")
+		}
+		sql += " WHERE " + strings.Join(predicates, " OR ")
+	}
+
+	statement := me.newStatement(sql + me.orderByClause(backward) + " LIMIT :dbx_limit")
+	if c != nil {
+		for i, value := range c.Values {
+			statement.AddParameter(fmt.Sprintf("dbx_cursor_%d", i), value)
+		}
+	}
+	statement.AddParameter("dbx_limit", limit+1)
+	return statement
+}
+
+//buildCountStatement returns the statement counting all records of the wrapped statement, it never locks them
+func (me *Paginator) buildCountStatement() *Statement {
+	statement := me.newStatement("SELECT COUNT(*) FROM (" + me.baseSQL() + ") AS dbx_count")
+	statement.lock, statement.lockWait = "", ""
+	return statement
+}
+
+//keysetValues returns the sort column values of a record, an error if a sort column is missing in the record
+func (me *Paginator) keysetValues(mapper *reflectx.Mapper, item interface{}) ([]interface{}, error) {
+	v := reflect.Indirect(reflect.ValueOf(item))
+	if !v.IsValid() || v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Item must be a struct or a pointer to struct, got %T", item)
+	}
+	names := make([]string, len(me.orderBy))
+	for i, column := range me.orderBy {
+		names[i] = column.Name
+	}
+	traversals := mapper.TraversalsByName(v.Type(), names)
+	values := make([]interface{}, len(me.orderBy))
+	for i, name := range names {
+		if len(traversals[i]) == 0 {
+			return nil, fmt.Errorf("Missing sort column %s in %T", name, item)
+		}
+		values[i] = reflectx.FieldByIndexes(v, traversals[i]).Interface()
+	}
+	return values, nil
+}
+
+//NewOffsetPaginator create paginator which pages the statement using LIMIT and OFFSET
+func NewOffsetPaginator(codec *CursorCodec, statement *Statement, orderBy ...SortColumn) *Paginator {
+	return &Paginator{
+		statement: statement,
+		orderBy:   orderBy,
+		codec:     codec,
+	}
+}
+
+//NewKeysetPaginator create paginator which pages the statement by comparing the sort column values
+//of the last record seen. The sort columns must be non null and together identify a record uniquely
+func NewKeysetPaginator(codec *CursorCodec, statement *Statement, orderBy ...SortColumn) *Paginator {
+	return &Paginator{
+		statement: statement,
+		orderBy:   orderBy,
+		keyset:    true,
+		codec:     codec,
+	}
+}
+
+//QueryPage queries a single page of records and scan each of them into T, which is a struct or a pointer to struct
+func QueryPage[T any](ctx context.Context, querier Querier, paginator *Paginator, request PageRequest) (*Page[T], error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(paginator.orderBy) == 0 {
+		return nil, errors.New("Paginator requires at least one sort column")
+	}
+	limit := request.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+
+	var current *cursor
+	if request.Cursor != "" {
+		decoded, err := paginator.codec.decode(request.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if decoded.Key != paginator.key() || (paginator.keyset && len(decoded.Values) != len(paginator.orderBy)) {
+			return nil, ErrInvalidCursor
+		}
+		current = decoded
+	}
+
+	offset := 0
+	var statement *Statement
+	if paginator.keyset {
+		statement = paginator.buildKeysetStatement(current, limit)
+	} else {
+		if current != nil {
+			offset = current.Offset
+		}
+		statement = paginator.buildOffsetStatement(offset, limit)
+	}
+
+	rows, err := querier.QueryStatementContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &Page[T]{}
+	var total int64
+	var extras map[string]interface{}
+	if paginator.windowCount() {
+		extras = map[string]interface{}{totalCountColumn: &total}
+	}
+	for rows.Next() {
+		item, err := scanItem[T](rows, extras)
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hasMore := len(page.Items) > limit
+	if hasMore {
+		page.Items = page.Items[:limit]
+	}
+	backward := current != nil && current.Backward
+	if backward {
+		for i, j := 0, len(page.Items)-1; i < j; i, j = i+1, j-1 {
+			page.Items[i], page.Items[j] = page.Items[j], page.Items[i]
+		}
+	}
+
+	if paginator.keyset {
+		err = paginator.setKeysetCursors(page, rows.Mapper, current, hasMore)
+	} else {
+		err = paginator.setOffsetCursors(page, offset, limit, hasMore)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case paginator.windowCount():
+		if len(page.Items) == 0 && offset > 0 {
+			err = countRecords(ctx, querier, paginator, &total)
+		}
+		page.Total = &total
+	case paginator.count != CountNone:
+		err = countRecords(ctx, querier, paginator, &total)
+		page.Total = &total
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+//setOffsetCursors sets next and previous cursor of an offset page
+func (me *Paginator) setOffsetCursors(page cursorPage, offset int, limit int, hasMore bool) error {
+	var next, prev string
+	var err error
+	if hasMore {
+		next, err = me.codec.encode(&cursor{Key: me.key(), Offset: offset + limit})
+		if err != nil {
+			return err
+		}
+	}
+	if offset > 0 {
+		prevOffset := offset - limit
+		if prevOffset < 0 {
+			prevOffset = 0
+		}
+		prev, err = me.codec.encode(&cursor{Key: me.key(), Offset: prevOffset})
+		if err != nil {
+			return err
+		}
+	}
+	page.setCursors(next, prev)
+	return nil
+}
+
+//setKeysetCursors sets next and previous cursor of a keyset page
+func (me *Paginator) setKeysetCursors(page cursorPage, mapper *reflectx.Mapper, current *cursor, hasMore bool) error {
+	first, last, ok := page.bounds()
+	if !ok {
+		return nil
+	}
+
+	backward := current != nil && current.Backward
+	hasNext := hasMore
+	hasPrev := current != nil
+	if backward {
+		hasNext = true
+		hasPrev = hasMore
+	}
+
+	var next, prev string
+	if hasNext {
+		values, err := me.keysetValues(mapper, last)
+		if err != nil {
+			return err
+		}
+		next, err = me.codec.encode(&cursor{Key: me.key(), Values: values})
+		if err != nil {
+			return err
+		}
+	}
+	if hasPrev {
+		values, err := me.keysetValues(mapper, first)
+		if err != nil {
+			return err
+		}
+		prev, err = me.codec.encode(&cursor{Key: me.key(), Values: values, Backward: true})
+		if err != nil {
+			return err
+		}
+	}
+	page.setCursors(next, prev)
+	return nil
+}
+
+//cursorPage is implemented by Page, it lets the non generic paginator set the page cursors
+type cursorPage interface {
+	setCursors(next, prev string)
+	bounds() (first interface{}, last interface{}, ok bool)
+}
+
+//setCursors sets next and previous cursor
+func (me *Page[T]) setCursors(next, prev string) {
+	me.NextCursor = next
+	me.PrevCursor = prev
+}
+
+//bounds returns the first and the last item of the page
+func (me *Page[T]) bounds() (interface{}, interface{}, bool) {
+	if len(me.Items) == 0 {
+		return nil, nil, false
+	}
+	return me.Items[0], me.Items[len(me.Items)-1], true
+}
+
+//countRecords counts all records of the paginated statement
+func countRecords(ctx context.Context, querier Querier, paginator *Paginator, total *int64) error {
+	rows, err := querier.QueryStatementContext(ctx, paginator.buildCountStatement())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := rows.Scan(total); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+//scanItem scans current row into a new T, which is a struct or a pointer to struct. Columns listed in extras are scanned into their own destination
+func scanItem[T any](rows *Rows, extras map[string]interface{}) (T, error) {
+	var item T
+	v := reflect.ValueOf(&item).Elem()
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return item, fmt.Errorf("Item must be a struct or a pointer to struct, got %T", item)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return item, err
+	}
+	traversals := rows.Mapper.TraversalsByName(v.Type(), columns)
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		if destination, ok := extras[column]; ok {
+			values[i] = destination
+			continue
+		}
+		if len(traversals[i]) == 0 {
+			return item, fmt.Errorf("Missing destination name %s in %T", column, item)
+		}
+		values[i] = reflectx.FieldByIndexes(v, traversals[i]).Addr().Interface()
+	}
+	return item, rows.Scan(values...)
+}
@@ -0,0 +1,185 @@
+package dbx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+func Test_CursorCodec_EncodeDecode(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+	token, err := codec.encode(&cursor{Key: "keyset|created_at desc", Values: []interface{}{"2019-10-01T00:00:00Z", 10}})
+	if err != nil {
+		t.Fatalf("Encode cursor error: %s", err.Error())
+	}
+
+	decoded, err := codec.decode(token)
+	if err != nil {
+		t.Fatalf("Decode cursor error: %s", err.Error())
+	}
+	if decoded.Key != "keyset|created_at desc" || len(decoded.Values) != 2 {
+		t.Errorf("Decoded cursor does not match the encoded one, got %+v", decoded)
+	}
+}
+
+func Test_CursorCodec_DecodeTampered(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+	token, err := codec.encode(&cursor{Key: "offset|id", Offset: 10})
+	if err != nil {
+		t.Fatalf("Encode cursor error: %s", err.Error())
+	}
+
+	forged, _ := NewCursorCodec([]byte("another secret")).encode(&cursor{Key: "offset|id", Offset: 1000})
+	tampered := []string{
+		"",
+		"not a cursor",
+		forged,
+		strings.Replace(token, ".", ".x", 1),
+		forged[:strings.Index(forged, ".")] + token[strings.Index(token, "."):],
+	}
+	for _, token := range tampered {
+		if _, err := codec.decode(token); err != ErrInvalidCursor {
+			t.Errorf("Decode %q expected to return ErrInvalidCursor, but got %v", token, err)
+		}
+	}
+}
+
+func Test_Paginator_BuildOffsetStatement(t *testing.T) {
+	statement := NewStatement("SELECT * FROM person WHERE name ILIKE :name;")
+	statement.AddParameter("name", "%a%")
+	paginator := NewOffsetPaginator(NewCursorCodec([]byte("secret")), statement, Desc("created_at"), Asc("id")).WithCount(CountWindow)
+
+	pageStatement := paginator.buildOffsetStatement(20, 10)
+	expected := "SELECT dbx_page.*, COUNT(*) OVER() AS dbx_total_count FROM (SELECT * FROM person WHERE name ILIKE :name) AS dbx_page ORDER BY created_at DESC, id ASC LIMIT :dbx_limit OFFSET :dbx_offset"
+	if pageStatement.SQL != expected {
+		t.Errorf("Expected SQL %q, but got %q", expected, pageStatement.SQL)
+	}
+	if pageStatement.Parameters["dbx_limit"] != 11 || pageStatement.Parameters["dbx_offset"] != 20 || pageStatement.Parameters["name"] != "%a%" {
+		t.Errorf("Unexpected parameters %v", pageStatement.Parameters)
+	}
+	if len(statement.Parameters) != 1 {
+		t.Errorf("Paginator must not modify the wrapped statement parameters")
+	}
+}
+
+func Test_Paginator_BuildKeysetStatement(t *testing.T) {
+	statement := NewStatement("SELECT * FROM person")
+	paginator := NewKeysetPaginator(NewCursorCodec([]byte("secret")), statement, Desc("created_at"), Asc("id"))
+
+	first := paginator.buildKeysetStatement(nil, 10)
+	expected := "SELECT dbx_page.* FROM (SELECT * FROM person) AS dbx_page ORDER BY created_at DESC, id ASC LIMIT :dbx_limit"
+	if first.SQL != expected {
+		t.Errorf("Expected SQL %q, but got %q", expected, first.SQL)
+	}
+
+	next := paginator.buildKeysetStatement(&cursor{Values: []interface{}{"2019-10-01", "a"}}, 10)
+	expected = "SELECT dbx_page.* FROM (SELECT * FROM person) AS dbx_page WHERE (created_at < :dbx_cursor_0) OR (created_at = :dbx_cursor_0 AND id > :dbx_cursor_1) ORDER BY created_at DESC, id ASC LIMIT :dbx_limit"
+	if next.SQL != expected {
+		t.Errorf("Expected SQL %q, but got %q", expected, next.SQL)
+	}
+
+	prev := paginator.buildKeysetStatement(&cursor{Values: []interface{}{"2019-10-01", "a"}, Backward: true}, 10)
+	expected = "SELECT dbx_page.* FROM (SELECT * FROM person) AS dbx_page WHERE (created_at > :dbx_cursor_0) OR (created_at = :dbx_cursor_0 AND id < :dbx_cursor_1) ORDER BY created_at ASC, id DESC LIMIT :dbx_limit"
+	if prev.SQL != expected {
+		t.Errorf("Expected SQL %q, but got %q", expected, prev.SQL)
+	}
+	if prev.Parameters["dbx_cursor_0"] != "2019-10-01" || prev.Parameters["dbx_cursor_1"] != "a" {
+		t.Errorf("Unexpected parameters %v", prev.Parameters)
+	}
+}
+
+func Test_Paginator_CursorBoundToOrdering(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+	byName := NewKeysetPaginator(codec, NewStatement("SELECT * FROM person"), Asc("name"))
+	byCreatedAt := NewKeysetPaginator(codec, NewStatement("SELECT * FROM person"), Asc("created_at"))
+
+	token, _ := codec.encode(&cursor{Key: byName.key(), Values: []interface{}{"Dadang"}})
+	_, err := QueryPage[struct{}](nil, nil, byCreatedAt, PageRequest{Cursor: token})
+	if err != ErrInvalidCursor {
+		t.Errorf("Cursor of another paginator expected to be rejected, but got %v", err)
+	}
+
+	byJobName := NewKeysetPaginator(codec, NewStatement("SELECT * FROM job"), Asc("name"))
+	if _, err := QueryPage[struct{}](nil, nil, byJobName, PageRequest{Cursor: token}); err != ErrInvalidCursor {
+		t.Errorf("Cursor of another statement expected to be rejected, but got %v", err)
+	}
+}
+
+func Test_Paginator_KeysetValues(t *testing.T) {
+	paginator := NewKeysetPaginator(NewCursorCodec([]byte("secret")), NewStatement("SELECT * FROM person"), Asc("name"), Asc("id"))
+	mapper := reflectx.NewMapperFunc("db", strings.ToLower)
+	values, err := paginator.keysetValues(mapper, &struct{ ID, Name string }{ID: "p1", Name: "Dadang"})
+	if err != nil || values[0] != "Dadang" || values[1] != "p1" {
+		t.Errorf("Expected the sort column values, got %v, %v", values, err)
+	}
+	if _, err := paginator.keysetValues(mapper, &struct{ Name string }{Name: "Dadang"}); err == nil {
+		t.Errorf("Expected an error of a missing sort column")
+	}
+}
+
+func Test_Paginator_KeepsLock(t *testing.T) {
+	paginator := NewOffsetPaginator(NewCursorCodec([]byte("secret")), NewStatement("SELECT * FROM job").SkipLocked(), Asc("id"))
+	page, _ := paginator.buildOffsetStatement(0, 10).Bind(context.Background())
+	if !strings.HasSuffix(page.SQL, "LIMIT :dbx_limit OFFSET :dbx_offset FOR UPDATE SKIP LOCKED") {
+		t.Errorf("Expected the page statement to lock, got %s", page.SQL)
+	}
+	if paginator.buildCountStatement().IsLocking() {
+		t.Errorf("Expected the count statement not to lock")
+	}
+
+	paginator.WithCount(CountWindow)
+	if page := paginator.buildOffsetStatement(0, 10); strings.Contains(page.SQL, "OVER()") || paginator.windowCount() {
+		t.Errorf("Expected a locking statement to be counted by a separate query, got %s", page.SQL)
+	}
+}
+
+func Test_QueryPage(t *testing.T) {
+	db := getSQLiteFileDb(t)
+	for _, id := range []string{"p3", "p1", "p5", "p2", "p4"} {
+		db.MustExec("INSERT INTO person (id, name, created_at) VALUES (?, ?, CURRENT_TIMESTAMP)", id, "Person "+id)
+	}
+	client := NewClient(db)
+	codec := NewCursorCodec([]byte("secret"))
+	statement := NewStatement("SELECT id, name FROM person")
+	type pagedPerson struct {
+		ID   string `db:"id"`
+		Name string `db:"name"`
+	}
+
+	paginators := map[string]*Paginator{
+		"offset window": NewOffsetPaginator(codec, statement, Asc("id")).WithCount(CountWindow),
+		"offset query":  NewOffsetPaginator(codec, statement, Asc("id")).WithCount(CountQuery),
+		"keyset":        NewKeysetPaginator(codec, statement, Asc("id")).WithCount(CountQuery),
+	}
+	for name, paginator := range paginators {
+		t.Run(name, func(t *testing.T) {
+			token := ""
+			query := func(expected string, hasPrev bool, hasNext bool) *Page[*pagedPerson] {
+				t.Helper()
+				page, err := QueryPage[*pagedPerson](context.Background(), client, paginator, PageRequest{Limit: 2, Cursor: token})
+				if err != nil {
+					t.Fatalf("QueryPage error: %s", err.Error())
+				}
+				ids := []string{}
+				for _, person := range page.Items {
+					ids = append(ids, person.ID)
+				}
+				if strings.Join(ids, ",") != expected || page.HasPrev() != hasPrev || page.HasNext() != hasNext {
+					t.Errorf("Expected page %s (prev %v, next %v), got %v (prev %v, next %v)", expected, hasPrev, hasNext, ids, page.HasPrev(), page.HasNext())
+				}
+				if page.Total == nil || *page.Total != 5 {
+					t.Errorf("Expected a total of 5, got %v", page.Total)
+				}
+				return page
+			}
+
+			token = query("p1,p2", false, true).NextCursor
+			token = query("p3,p4", true, true).NextCursor
+			token = query("p5", true, false).PrevCursor
+			token = query("p3,p4", true, true).PrevCursor
+			query("p1,p2", false, true)
+		})
+	}
+}
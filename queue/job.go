@@ -0,0 +1,56 @@
+package queue
+
+import (
+	"encoding/json"
+	"time"
+)
+
+//Status represent the state of a job
+type Status string
+
+const (
+	//StatusPending is the state of a job waiting to run, including a failed job waiting to be retried
+	StatusPending Status = "pending"
+	//StatusDead is the state of a job which failed its last attempt, it's not retried until Queue.Retry
+	StatusDead Status = "dead"
+)
+
+//DefaultMaxAttempts is the number of attempts of a job before it's dead
+const DefaultMaxAttempts = 10
+
+//Job represent a unit of background work. Jobs are removed once they succeed
+type Job struct {
+	ID      string `db:"id"`
+	Queue   string `db:"queue"`
+	Kind    string `db:"kind"`
+	Payload []byte `db:"payload"`
+	Status  Status `db:"status"`
+	//Attempts is the number of failed attempts
+	Attempts    int `db:"attempts"`
+	MaxAttempts int `db:"max_attempts"`
+	//RunAt is the time the job runs at the earliest, it's now if zero when the job is enqueued
+	RunAt time.Time `db:"run_at"`
+	//UniqueKey prevents enqueuing the job while a pending job has the same key, unless empty
+	UniqueKey string    `db:"unique_key"`
+	LastError string    `db:"last_error"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+//Decode decodes the JSON payload of the job into v
+func (me *Job) Decode(v interface{}) error {
+	return json.Unmarshal(me.Payload, v)
+}
+
+//NewJob create new job instance of the kind with the payload encoded as JSON
+func NewJob(kind string, payload interface{}) (*Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Job{
+		Kind:        kind,
+		Payload:     encoded,
+		Status:      StatusPending,
+		MaxAttempts: DefaultMaxAttempts,
+	}, nil
+}
@@ -0,0 +1,157 @@
+//Package queue provides a job queue stored in the database. Jobs are enqueued in the unit of work of the changes
+//they follow, and run by workers in a transaction their changes are saved in
+package queue
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/supendi/dbx"
+)
+
+//DefaultTable is the table jobs are stored in
+const DefaultTable = "dbx_jobs"
+
+//ErrJobNotFound is returned when retrying a job which is not dead
+var ErrJobNotFound = errors.New("Dead job is not found")
+
+//Queue represent a named queue of jobs, several queues may share a table
+type Queue struct {
+	client *dbx.Client
+	name   string
+	table  string
+}
+
+//WithTable set the table jobs are stored in
+func (me *Queue) WithTable(table string) *Queue {
+	me.table = table
+	return me
+}
+
+//Name returns the name of the queue
+func (me *Queue) Name() string {
+	return me.name
+}
+
+//Schema returns the statement creating the jobs table
+func (me *Queue) Schema() string {
+	return `CREATE TABLE IF NOT EXISTS ` + me.table + ` (
+	id VARCHAR(36) PRIMARY KEY,
+	queue VARCHAR(255) NOT NULL,
+	kind VARCHAR(255) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	attempts INTEGER NOT NULL,
+	max_attempts INTEGER NOT NULL,
+	run_at TIMESTAMP NOT NULL,
+	unique_key VARCHAR(255) UNIQUE,
+	last_error TEXT,
+	created_at TIMESTAMP NOT NULL
+)`
+}
+
+//Enqueue add the statement inserting the job to the unit of work, the job is enqueued when its changes are saved.
+//A job whose unique key is taken by a pending job is not enqueued
+func (me *Queue) Enqueue(dbContext *dbx.Context, job *Job) {
+	now := me.client.Now().UTC()
+	job.ID = uuid.New().String()
+	job.Queue = me.name
+	job.Status = StatusPending
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	job.CreatedAt = now
+
+	insert := "INSERT INTO "
+	conflict := ""
+	if job.UniqueKey != "" {
+		if me.client.DriverName() == "mysql" {
+			insert = "INSERT IGNORE INTO "
+		} else {
+			conflict = " ON CONFLICT (unique_key) DO NOTHING"
+		}
+	}
+	statement := dbx.NewStatement(insert + me.table + " (id, queue, kind, payload, status, attempts, max_attempts, run_at, unique_key, created_at) " +
+		"VALUES (:id, :queue, :kind, :payload, :status, 0, :max_attempts, :run_at, :unique_key, :created_at)" + conflict)
+	statement.AddParameter("id", job.ID)
+	statement.AddParameter("queue", job.Queue)
+	statement.AddParameter("kind", job.Kind)
+	statement.AddParameter("payload", string(job.Payload))
+	statement.AddParameter("status", string(job.Status))
+	statement.AddParameter("max_attempts", job.MaxAttempts)
+	statement.AddParameter("run_at", job.RunAt.UTC())
+	statement.AddParameter("unique_key", nullable(job.UniqueKey))
+	statement.AddParameter("created_at", job.CreatedAt)
+	dbContext.AddStatement(statement)
+}
+
+//Dead returns the dead jobs of the queue, oldest first
+func (me *Queue) Dead(ctx context.Context) ([]*Job, error) {
+	statement := dbx.NewStatement(me.selectSQL() + " WHERE queue = :queue AND status = :status ORDER BY created_at, id")
+	statement.AddParameter("queue", me.name)
+	statement.AddParameter("status", string(StatusDead))
+	rows, err := me.client.QueryStatementContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*Job{}
+	for rows.Next() {
+		job := &Job{}
+		if err := rows.StructScan(job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+//Retry makes the dead job pending again with no failed attempts
+func (me *Queue) Retry(ctx context.Context, id string) error {
+	statement := dbx.NewStatement("UPDATE " + me.table + " SET status = :pending, attempts = 0, run_at = :run_at WHERE id = :id AND queue = :queue AND status = :dead")
+	statement.AddParameter("pending", string(StatusPending))
+	statement.AddParameter("run_at", me.client.Now().UTC())
+	statement.AddParameter("id", id)
+	statement.AddParameter("queue", me.name)
+	statement.AddParameter("dead", string(StatusDead))
+	result, err := me.client.ExecStatementContext(ctx, statement)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+//selectSQL returns the statement selecting jobs
+func (me *Queue) selectSQL() string {
+	return "SELECT id, queue, kind, payload, status, attempts, max_attempts, run_at, COALESCE(unique_key, '') AS unique_key, " +
+		"COALESCE(last_error, '') AS last_error, created_at FROM " + me.table
+}
+
+//nullable returns nil if value is empty
+func nullable(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+//NewQueue create new queue instance of the name
+func NewQueue(client *dbx.Client, name string) *Queue {
+	return &Queue{
+		client: client,
+		name:   name,
+		table:  DefaultTable,
+	}
+}
@@ -0,0 +1,218 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3" //needed
+	"github.com/supendi/dbx"
+	"github.com/supendi/dbx/dbxtest"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (me *clock) Now() time.Time {
+	return me.now
+}
+
+func newTestQueue(t *testing.T) (*Queue, *clock) {
+	db, err := sqlx.Open("sqlite3", filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatalf("Open db error: %s", err.Error())
+	}
+	t.Cleanup(func() { db.Close() })
+	db.MustExec("CREATE TABLE shipment (order_id TEXT PRIMARY KEY)")
+
+	now := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	queue := NewQueue(dbx.NewClient(db).WithClock(now.Now), "orders")
+	db.MustExec(queue.Schema())
+	return queue, now
+}
+
+func enqueue(t *testing.T, queue *Queue, job *Job) {
+	dbContext := queue.client.NewContext()
+	queue.Enqueue(dbContext, job)
+	if _, err := dbContext.SaveChanges(context.Background()); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+}
+
+func ship(ctx context.Context, job *Job) error {
+	var payload struct{ OrderID string }
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	statement := dbx.NewStatement("INSERT INTO shipment (order_id) VALUES (:order_id)")
+	statement.AddParameter("order_id", payload.OrderID)
+	scope := dbx.ScopeFrom(ctx)
+	scope.AddStatement(statement)
+	_, err := scope.SaveChanges(ctx)
+	return err
+}
+
+func count(t *testing.T, queue *Queue, sql string) int {
+	var count int
+	if err := queue.client.Get(&count, sql); err != nil {
+		t.Fatalf("Count error: %s", err.Error())
+	}
+	return count
+}
+
+func Test_Worker_Work(t *testing.T) {
+	queue, _ := newTestQueue(t)
+	job, _ := NewJob("ship", map[string]string{"OrderID": "o1"})
+	dbContext := queue.client.NewContext()
+	dbContext.AddStatement(dbx.NewStatement("INSERT INTO shipment (order_id) VALUES ('o0')"))
+	queue.Enqueue(dbContext, job)
+	if _, err := dbContext.SaveChanges(context.Background()); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+
+	worker := NewWorker(queue).Handle("ship", ship)
+	if worked, err := worker.Work(context.Background()); !worked || err != nil {
+		t.Fatalf("Expected the job to run, got %v, %v", worked, err)
+	}
+	if shipped := count(t, queue, "SELECT COUNT(*) FROM shipment"); shipped != 2 {
+		t.Errorf("Expected the changes of the job to be committed, got %d shipments", shipped)
+	}
+	if jobs := count(t, queue, "SELECT COUNT(*) FROM dbx_jobs"); jobs != 0 {
+		t.Errorf("Expected the job to be removed, got %d jobs", jobs)
+	}
+	if worked, err := worker.Work(context.Background()); worked || err != nil {
+		t.Errorf("Expected no job to run, got %v, %v", worked, err)
+	}
+}
+
+func Test_Worker_RetryAndDead(t *testing.T) {
+	queue, now := newTestQueue(t)
+	job, _ := NewJob("ship", map[string]string{"OrderID": "o1"})
+	job.MaxAttempts = 2
+	enqueue(t, queue, job)
+
+	var failures []error
+	worker := NewWorker(queue).
+		WithBackoff(ExponentialBackoff(time.Minute, time.Hour)).
+		WithErrorHandler(func(job *Job, err error) { failures = append(failures, err) }).
+		Handle("ship", func(ctx context.Context, job *Job) error {
+			if err := ship(ctx, job); err != nil {
+				return err
+			}
+			return errors.New("carrier is unavailable")
+		})
+
+	if worked, err := worker.Work(context.Background()); !worked || err != nil {
+		t.Fatalf("Expected the job to run, got %v, %v", worked, err)
+	}
+	if shipped := count(t, queue, "SELECT COUNT(*) FROM shipment"); shipped != 0 {
+		t.Errorf("Expected the changes of the failed job to be rolled back, got %d shipments", shipped)
+	}
+	if worked, _ := worker.Work(context.Background()); worked {
+		t.Errorf("Expected the failed job to wait for the backoff")
+	}
+
+	now.now = now.now.Add(time.Minute)
+	if worked, err := worker.Work(context.Background()); !worked || err != nil {
+		t.Fatalf("Expected the job to be retried, got %v, %v", worked, err)
+	}
+	dead, err := queue.Dead(context.Background())
+	if err != nil {
+		t.Fatalf("Dead error: %s", err.Error())
+	}
+	if len(dead) != 1 || dead[0].Attempts != 2 || dead[0].LastError != "carrier is unavailable" || len(failures) != 2 {
+		t.Fatalf("Expected the job to be dead after 2 attempts, got %+v and %d failures", dead, len(failures))
+	}
+
+	if err := queue.Retry(context.Background(), dead[0].ID); err != nil {
+		t.Fatalf("Retry error: %s", err.Error())
+	}
+	if err := queue.Retry(context.Background(), dead[0].ID); err != ErrJobNotFound {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+	worker.Handle("ship", ship)
+	if worked, err := worker.Work(context.Background()); !worked || err != nil {
+		t.Errorf("Expected the retried job to run, got %v, %v", worked, err)
+	}
+}
+
+func Test_Worker_HandlerPanics(t *testing.T) {
+	queue, _ := newTestQueue(t)
+	job, _ := NewJob("ship", nil)
+	enqueue(t, queue, job)
+
+	worker := NewWorker(queue).Handle("ship", func(ctx context.Context, job *Job) error { panic("nil order") })
+	if worked, err := worker.Work(context.Background()); !worked || err != nil {
+		t.Fatalf("Expected the job to run, got %v, %v", worked, err)
+	}
+	var lastError string
+	queue.client.Get(&lastError, "SELECT last_error FROM dbx_jobs")
+	if lastError != "job panicked: nil order" {
+		t.Errorf("Expected the panic to be recorded, got %q", lastError)
+	}
+}
+
+func Test_Worker_HandlerSaveChangesFails(t *testing.T) {
+	queue, _ := newTestQueue(t)
+	queue.client.MustExec("INSERT INTO shipment (order_id) VALUES ('o1')")
+	job, _ := NewJob("ship", map[string]string{"OrderID": "o1"})
+	enqueue(t, queue, job)
+
+	worker := NewWorker(queue).Handle("ship", ship)
+	if worked, err := worker.Work(context.Background()); !worked || err != nil {
+		t.Fatalf("Expected the job to run, got %v, %v", worked, err)
+	}
+	if attempts := count(t, queue, "SELECT attempts FROM dbx_jobs"); attempts != 1 {
+		t.Errorf("Expected the failed attempt to be recorded, got %d attempts", attempts)
+	}
+}
+
+func Test_Queue_ScheduledAndUnique(t *testing.T) {
+	queue, now := newTestQueue(t)
+	scheduled, _ := NewJob("ship", map[string]string{"OrderID": "o1"})
+	scheduled.RunAt = now.now.Add(time.Hour)
+	scheduled.UniqueKey = "ship:o1"
+	enqueue(t, queue, scheduled)
+	duplicate, _ := NewJob("ship", map[string]string{"OrderID": "o1"})
+	duplicate.UniqueKey = "ship:o1"
+	enqueue(t, queue, duplicate)
+	if jobs := count(t, queue, "SELECT COUNT(*) FROM dbx_jobs"); jobs != 1 {
+		t.Fatalf("Expected the duplicate job not to be enqueued, got %d jobs", jobs)
+	}
+
+	worker := NewWorker(queue).Handle("ship", ship)
+	if worked, _ := worker.Work(context.Background()); worked {
+		t.Errorf("Expected the scheduled job not to run before its time")
+	}
+	now.now = now.now.Add(time.Hour)
+	if worked, err := worker.Work(context.Background()); !worked || err != nil {
+		t.Errorf("Expected the scheduled job to run, got %v, %v", worked, err)
+	}
+	enqueue(t, queue, duplicate)
+	if jobs := count(t, queue, "SELECT COUNT(*) FROM dbx_jobs"); jobs != 1 {
+		t.Errorf("Expected the unique key to be released when the job is done, got %d jobs", jobs)
+	}
+}
+
+func Test_Worker_ClaimSkipsLocked(t *testing.T) {
+	fake := dbxtest.NewFake().WithDriverName("postgres")
+	worker := NewWorker(NewQueue(fake.Client(), "orders"))
+	if worked, err := worker.Work(context.Background()); worked || err != nil {
+		t.Fatalf("Expected no job to run, got %v, %v", worked, err)
+	}
+	fake.AssertExecuted(t, `run_at <= \$3 ORDER BY run_at, id LIMIT 1 FOR UPDATE SKIP LOCKED$`)
+	fake.AssertRolledBack(t)
+}
+
+func Test_ExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, time.Minute)
+	for attempts, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 10: time.Minute} {
+		if delay := backoff(attempts); delay != expected {
+			t.Errorf("Expected %s after %d attempts, got %s", expected, attempts, delay)
+		}
+	}
+}
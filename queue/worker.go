@@ -0,0 +1,224 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/supendi/dbx"
+)
+
+//DefaultPollInterval is the time a worker waits for jobs when the queue is empty
+const DefaultPollInterval = time.Second
+
+//Handler runs a job. ctx carries the unit of work of the transaction the job is claimed in, see dbx.ScopeFrom,
+//changes saved in it are committed with the job. A failed job is retried or dead, and its changes are discarded
+type Handler func(ctx context.Context, job *Job) error
+
+//Backoff returns the delay before retrying a job which failed the attempts
+type Backoff = dbx.Backoff
+
+//ExponentialBackoff returns the backoff doubling the base delay after each failed attempt, up to max
+func ExponentialBackoff(base time.Duration, max time.Duration) Backoff {
+	return dbx.ExponentialBackoff(base, max)
+}
+
+//Worker claims the jobs of a queue one at a time and runs their handlers. Run several workers,
+//on the same or other replicas, to run jobs concurrently
+type Worker struct {
+	queue        *Queue
+	handlers     map[string]Handler
+	backoff      Backoff
+	pollInterval time.Duration
+	onError      func(job *Job, err error)
+}
+
+//Handle set the handler of the jobs of the kind
+func (me *Worker) Handle(kind string, handler Handler) *Worker {
+	me.handlers[kind] = handler
+	return me
+}
+
+//WithBackoff set the delay before retrying failed jobs, ExponentialBackoff(time.Second, time.Hour) by default
+func (me *Worker) WithBackoff(backoff Backoff) *Worker {
+	me.backoff = backoff
+	return me
+}
+
+//WithPollInterval set the time the worker waits for jobs when the queue is empty
+func (me *Worker) WithPollInterval(interval time.Duration) *Worker {
+	me.pollInterval = interval
+	return me
+}
+
+//WithErrorHandler set the function called with the error of each failed job once its failure is recorded
+func (me *Worker) WithErrorHandler(onError func(job *Job, err error)) *Worker {
+	me.onError = onError
+	return me
+}
+
+//Work claims the next due job and runs it. It reports whether a job has run, false if none is due.
+//The error is the one of the queue, the error of the job is recorded in the job and given to the error handler
+func (me *Worker) Work(ctx context.Context) (bool, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, scope := me.queue.client.NewScope(ctx)
+	transaction, err := scope.BeginTransactionContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	job, err := me.claim(ctx, transaction)
+	if err != nil || job == nil {
+		scope.ResetTransaction()
+		if rollbackError := transaction.Rollback(); err == nil {
+			err = rollbackError
+		}
+		return false, err
+	}
+
+	jobErr, err := me.run(ctx, transaction, job)
+	scope.ClearStatements()
+	if err == nil && jobErr == nil {
+		err = me.complete(ctx, transaction, job)
+	} else if err == nil {
+		err = me.fail(ctx, transaction, job, jobErr)
+	}
+	if err != nil {
+		scope.ResetTransaction()
+		transaction.Rollback()
+		return true, err
+	}
+	if err := scope.CompleteTransaction(); err != nil {
+		return true, err
+	}
+	if jobErr != nil && me.onError != nil {
+		me.onError(job, jobErr)
+	}
+	return true, nil
+}
+
+//Run works until ctx is done or the queue fails, waiting the poll interval whenever no job is due.
+//It returns the error of the queue, nil when ctx is done
+func (me *Worker) Run(ctx context.Context) error {
+	if me.pollInterval <= 0 {
+		return errors.New("Poll interval must be positive")
+	}
+	for {
+		worked, err := me.Work(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if worked {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(me.pollInterval):
+		}
+	}
+}
+
+//claim selects the next due job and locks it for the transaction, skipping the ones locked by other workers.
+//It returns nil if no job is due
+func (me *Worker) claim(ctx context.Context, transaction *dbx.Transaction) (*Job, error) {
+	statement := dbx.NewStatement(me.queue.selectSQL() + " WHERE queue = :queue AND status = :status AND run_at <= :now ORDER BY run_at, id LIMIT 1")
+	statement.AddParameter("queue", me.queue.name)
+	statement.AddParameter("status", string(StatusPending))
+	statement.AddParameter("now", me.queue.client.Now().UTC())
+	switch me.queue.client.DriverName() {
+	case "postgres", "pgx", "mysql":
+		statement.SkipLocked()
+	}
+
+	rows, err := transaction.QueryStatementContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	job := &Job{}
+	if err := rows.StructScan(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+//run runs the handler of the job behind a savepoint and returns its error. Its changes are rolled back to the savepoint
+//if it fails, the transaction starts over if the handler rolled it back
+func (me *Worker) run(ctx context.Context, transaction *dbx.Transaction, job *Job) (error, error) {
+	if _, err := transaction.ExecStatementContext(ctx, dbx.NewStatement("SAVEPOINT dbx_job")); err != nil {
+		return nil, err
+	}
+	jobErr := me.handle(ctx, job)
+	if jobErr == nil {
+		return nil, nil
+	}
+	if transaction.IsComplete() {
+		return jobErr, transaction.StartOver()
+	}
+	_, err := transaction.ExecStatementContext(ctx, dbx.NewStatement("ROLLBACK TO SAVEPOINT dbx_job"))
+	return jobErr, err
+}
+
+//handle runs the handler of the job, a panic is returned as error
+func (me *Worker) handle(ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+
+	handler := me.handlers[job.Kind]
+	if handler == nil {
+		return fmt.Errorf("job kind %s has no handler", job.Kind)
+	}
+	return handler(ctx, job)
+}
+
+//complete removes the job which succeeded
+func (me *Worker) complete(ctx context.Context, transaction *dbx.Transaction, job *Job) error {
+	statement := dbx.NewStatement("DELETE FROM " + me.queue.table + " WHERE id = :id")
+	statement.AddParameter("id", job.ID)
+	_, err := transaction.ExecStatementContext(ctx, statement)
+	return err
+}
+
+//fail records the failed attempt of the job, it's retried after the backoff or dead if it was the last attempt.
+//The unique key of a dead job is released
+func (me *Worker) fail(ctx context.Context, transaction *dbx.Transaction, job *Job, jobErr error) error {
+	job.Attempts++
+	job.LastError = jobErr.Error()
+	statement := dbx.NewStatement("UPDATE " + me.queue.table + " SET attempts = :attempts, run_at = :run_at, last_error = :last_error WHERE id = :id")
+	if job.Attempts >= job.MaxAttempts {
+		job.Status = StatusDead
+		job.UniqueKey = ""
+		statement = dbx.NewStatement("UPDATE " + me.queue.table + " SET status = :status, attempts = :attempts, unique_key = NULL, last_error = :last_error WHERE id = :id")
+		statement.AddParameter("status", string(StatusDead))
+	} else {
+		job.RunAt = me.queue.client.Now().UTC().Add(me.backoff(job.Attempts))
+		statement.AddParameter("run_at", job.RunAt)
+	}
+	statement.AddParameter("attempts", job.Attempts)
+	statement.AddParameter("last_error", job.LastError)
+	statement.AddParameter("id", job.ID)
+	_, err := transaction.ExecStatementContext(ctx, statement)
+	return err
+}
+
+//NewWorker create new worker instance of the queue
+func NewWorker(queue *Queue) *Worker {
+	return &Worker{
+		queue:        queue,
+		handlers:     map[string]Handler{},
+		backoff:      ExponentialBackoff(time.Second, time.Hour),
+		pollInterval: DefaultPollInterval,
+	}
+}
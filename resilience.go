@@ -0,0 +1,244 @@
+package dbx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+//ErrCircuitOpen is returned without running the statement while the circuit breaker is open
+var ErrCircuitOpen = errors.New("Circuit breaker is open")
+
+//Backoff returns the delay before the next attempt after the attempts failed
+type Backoff func(attempts int) time.Duration
+
+//ExponentialBackoff returns the backoff doubling the base delay after each failed attempt, up to max
+func ExponentialBackoff(base time.Duration, max time.Duration) Backoff {
+	return func(attempts int) time.Duration {
+		delay := base
+		for i := 1; i < attempts && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			return max
+		}
+		return delay
+	}
+}
+
+//RetryPolicy represent how idempotent statements are retried on transient errors, see IsTransient
+type RetryPolicy struct {
+	//Attempts is the number of attempts including the first one
+	Attempts int
+	Backoff  Backoff
+}
+
+//DefaultRetryPolicy is the retry policy of a client without one
+var DefaultRetryPolicy = &RetryPolicy{
+	Attempts: 3,
+	Backoff:  ExponentialBackoff(100*time.Millisecond, 2*time.Second),
+}
+
+//Idempotent marks the statement as safe to run more than once, it's retried on transient errors
+//according to the retry policy of the client when it runs outside a transaction. See Client.WithRetryPolicy
+func (me *Statement) Idempotent() *Statement {
+	me.idempotent = true
+	return me
+}
+
+//WithRetryPolicy marks the statement as idempotent and retried according to the policy instead of
+//the one of the client
+func (me *Statement) WithRetryPolicy(policy *RetryPolicy) *Statement {
+	me.idempotent = true
+	me.retryPolicy = policy
+	return me
+}
+
+//WithRetryPolicy set the retry policy of idempotent statements, see Statement.Idempotent.
+//It must be set before the client is used
+func (me *Client) WithRetryPolicy(policy *RetryPolicy) *Client {
+	me.retryPolicy = policy
+	return me
+}
+
+//WithCircuitBreaker makes the client fail fast with ErrCircuitOpen once threshold statements in a row failed
+//with transient errors. After the cooldown a single statement is let through, the breaker closes if it succeeds
+//and opens again otherwise. Statements of a transaction are not guarded, beginning one is.
+//A threshold which is not positive disables the breaker. It must be set before the client is used
+func (me *Client) WithCircuitBreaker(threshold int, cooldown time.Duration) *Client {
+	me.breaker = nil
+	if threshold > 0 {
+		me.breaker = &breaker{threshold: threshold, cooldown: cooldown}
+	}
+	return me
+}
+
+//CircuitState represent the state of a circuit breaker
+type CircuitState string
+
+const (
+	//CircuitClosed is the state of a circuit breaker letting statements through
+	CircuitClosed CircuitState = "closed"
+	//CircuitOpen is the state of a circuit breaker failing statements fast
+	CircuitOpen CircuitState = "open"
+	//CircuitHalfOpen is the state of a circuit breaker whose cooldown is over, the next statement is let through
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+//CircuitState returns the state of the circuit breaker of the client, closed if it has none
+func (me *Client) CircuitState() CircuitState {
+	return me.breaker.state(me.Now())
+}
+
+//breaker is a circuit breaker counting consecutive transient failures
+type breaker struct {
+	mutex     sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+//state returns the state of the breaker at now
+func (me *breaker) state(now time.Time) CircuitState {
+	if me == nil {
+		return CircuitClosed
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.failures < me.threshold {
+		return CircuitClosed
+	}
+	if me.probing || now.Before(me.openedAt.Add(me.cooldown)) {
+		return CircuitOpen
+	}
+	return CircuitHalfOpen
+}
+
+//allow returns ErrCircuitOpen if the breaker is open at now, otherwise the statement is let through.
+//probe determine if the statement is the single one let through while half open
+func (me *breaker) allow(now time.Time) (probe bool, err error) {
+	if me == nil {
+		return false, nil
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.failures < me.threshold {
+		return false, nil
+	}
+	if me.probing || now.Before(me.openedAt.Add(me.cooldown)) {
+		return false, ErrCircuitOpen
+	}
+	me.probing = true
+	return true, nil
+}
+
+//record records the outcome of a statement let through at now. While the breaker is open only the outcome
+//of the probe counts, statements let through before it opened don't close it
+func (me *breaker) record(now time.Time, err error, probe bool) {
+	if me == nil {
+		return
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if probe {
+		me.probing = false
+	} else if me.failures >= me.threshold {
+		return
+	}
+	if err == nil || !IsTransient(err) {
+		me.failures = 0
+		return
+	}
+	me.failures++
+	if me.failures >= me.threshold {
+		me.openedAt = now
+	}
+}
+
+//resilient runs fn guarded by the circuit breaker, it's retried on transient errors if the statement is idempotent.
+//It must not be used in a transaction
+func (me *Client) resilient(ctx context.Context, statement *Statement, fn func() error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	policy := me.retryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy
+	}
+	if statement != nil && statement.retryPolicy != nil {
+		policy = statement.retryPolicy
+	}
+	attempts := 1
+	if statement != nil && statement.idempotent {
+		attempts = policy.Attempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		probe, err := me.breaker.allow(me.Now())
+		if err != nil {
+			return err
+		}
+		err = fn()
+		me.breaker.record(me.Now(), err, probe)
+		if err == nil || attempt >= attempts || !IsTransient(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(policy.Backoff(attempt)):
+		}
+	}
+}
+
+//transientCodes are the SQLSTATE classes and codes of errors which may not happen again
+var transientCodes = []string{
+	"08",    //connection_exception
+	"40001", //serialization_failure
+	"40P01", //deadlock_detected
+	"53300", //too_many_connections
+	"57P01", //admin_shutdown
+	"57P02", //crash_shutdown
+	"57P03", //cannot_connect_now
+}
+
+//IsTransient determine if err may not happen again when the statement is retried, such as a lost connection,
+//a failover or a deadlock. Timeouts and canceled contexts are not transient
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, ErrStatementTimeout) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr *net.OpError
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	code := ""
+	var pqErr *pq.Error
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &pqErr) {
+		code = string(pqErr.Code)
+	} else if errors.As(err, &stateErr) {
+		code = stateErr.SQLState()
+	}
+	for _, transient := range transientCodes {
+		if code != "" && strings.HasPrefix(code, transient) {
+			return true
+		}
+	}
+	return strings.Contains(err.Error(), "invalid connection")
+}
@@ -0,0 +1,151 @@
+package dbx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/supendi/dbx/internal/driverutil"
+)
+
+func Test_IsTransient(t *testing.T) {
+	cases := map[error]bool{
+		driver.ErrBadConn:                          true,
+		fmt.Errorf("exec: %w", syscall.ECONNRESET): true,
+		&pq.Error{Code: "08006"}:                   true,
+		&pq.Error{Code: "40P01"}:                   true,
+		&pq.Error{Code: "23505"}:                   false,
+		&timeoutError{err: errors.New("slow")}:     false,
+		context.Canceled:                           false,
+		errors.New("syntax error"):                 false,
+	}
+	for err, expected := range cases {
+		if IsTransient(err) != expected {
+			t.Errorf("Expected IsTransient(%v) to be %v", err, expected)
+		}
+	}
+}
+
+func Test_Breaker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := &breaker{threshold: 2, cooldown: time.Minute}
+	transient := &pq.Error{Code: "08006"}
+
+	breaker.record(now, transient, false)
+	if _, err := breaker.allow(now); err != nil {
+		t.Fatalf("Expected the breaker to be closed below the threshold")
+	}
+	breaker.record(now, transient, false)
+	if _, err := breaker.allow(now); err != ErrCircuitOpen || breaker.state(now) != CircuitOpen {
+		t.Fatalf("Expected the breaker to be open at the threshold")
+	}
+
+	now = now.Add(time.Minute)
+	if breaker.state(now) != CircuitHalfOpen {
+		t.Fatalf("Expected the breaker to be half open after the cooldown")
+	}
+	if probe, err := breaker.allow(now); err != nil || !probe {
+		t.Fatalf("Expected the breaker to let a probe through after the cooldown")
+	}
+	if _, err := breaker.allow(now); err != ErrCircuitOpen {
+		t.Errorf("Expected a single statement to be let through while half open")
+	}
+	breaker.record(now, nil, false)
+	if _, err := breaker.allow(now); err != ErrCircuitOpen {
+		t.Errorf("Expected the outcome of a statement let through before the breaker opened not to close it")
+	}
+	breaker.record(now, transient, true)
+	if _, err := breaker.allow(now); err != ErrCircuitOpen {
+		t.Errorf("Expected the breaker to open again when the probe fails")
+	}
+
+	now = now.Add(time.Minute)
+	probe, _ := breaker.allow(now)
+	breaker.record(now, nil, probe)
+	if breaker.state(now) != CircuitClosed {
+		t.Errorf("Expected the breaker to close when the probe succeeds")
+	}
+}
+
+func Test_Client_RetryIdempotent(t *testing.T) {
+	failures := 2
+	client, database := newScriptedClient(t, "postgres", func(call *scriptedCall) (*driverutil.Rows, error) {
+		if call.Kind == "exec" && strings.HasPrefix(call.SQL, "UPDATE") && failures != 0 {
+			failures--
+			return nil, &pq.Error{Code: "08006"}
+		}
+		return nil, nil
+	})
+	client.WithRetryPolicy(&RetryPolicy{Attempts: 3, Backoff: ExponentialBackoff(0, 0)})
+	ctx := context.Background()
+
+	if _, err := client.ExecStatementContext(ctx, NewStatement("UPDATE job SET status = 'done'").Idempotent()); err != nil {
+		t.Fatalf("Expected the idempotent statement to be retried, got %s", err.Error())
+	}
+	if calls := len(database.statements(`^UPDATE`)); calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+
+	database.reset()
+	failures = -1
+	if _, err := client.ExecStatementContext(ctx, NewStatement("UPDATE job SET attempts = attempts + 1")); err == nil {
+		t.Errorf("Expected the statement which is not idempotent to fail")
+	}
+	dbContext := client.NewContext()
+	if _, err := dbContext.BeginTransaction(); err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+	defer dbContext.CompleteTransaction()
+	if _, err := dbContext.ExecStatementContext(ctx, NewStatement("UPDATE job SET status = 'done'").Idempotent()); err == nil {
+		t.Errorf("Expected the statement not to be retried in a transaction")
+	}
+	if calls := len(database.statements(`^UPDATE`)); calls != 2 {
+		t.Errorf("Expected a single attempt of each statement, got %d", calls)
+	}
+}
+
+func Test_Client_CircuitBreaker(t *testing.T) {
+	failures := 2
+	client, database := newScriptedClient(t, "postgres", func(call *scriptedCall) (*driverutil.Rows, error) {
+		if call.Kind == "query" && failures > 0 {
+			failures--
+			return nil, &pq.Error{Code: "57P03"}
+		}
+		return nil, nil
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	client.WithCircuitBreaker(2, time.Minute).WithClock(func() time.Time { return now })
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.QueryStatementContext(ctx, NewStatement("SELECT 1")); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Expected the breaker to be closed below the threshold")
+		}
+	}
+	if _, err := client.QueryStatementContext(ctx, NewStatement("SELECT 1")); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if _, err := client.NewContext().BeginTransaction(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected beginning a transaction to fail fast, got %v", err)
+	}
+	if calls := len(database.kinds()); calls != 2 {
+		t.Errorf("Expected statements not to run while the breaker is open, got %d calls", calls)
+	}
+
+	now = now.Add(time.Minute)
+	rows, err := client.QueryStatementContext(ctx, NewStatement("SELECT 1"))
+	if err != nil {
+		t.Fatalf("Expected the breaker to let the statement through after the cooldown, got %s", err.Error())
+	}
+	rows.Close()
+	if state := client.CircuitState(); state != CircuitClosed {
+		t.Errorf("Expected the breaker to be closed, got %s", state)
+	}
+}
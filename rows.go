@@ -0,0 +1,53 @@
+package dbx
+
+import (
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//Rows represent the rows of a query. The deadline of the statement covers reading them, it's released once they
+//are closed or read to the end
+type Rows struct {
+	*sqlx.Rows
+	release    func() error
+	releaseErr error
+	once       sync.Once
+}
+
+//newRows create new rows instance calling release once they are closed
+func newRows(rows *sqlx.Rows, release func() error) *Rows {
+	return &Rows{
+		Rows:    rows,
+		release: release,
+	}
+}
+
+//Next prepares the next row for reading, the rows are released once there is none
+func (me *Rows) Next() bool {
+	if me.Rows.Next() {
+		return true
+	}
+	me.done()
+	return false
+}
+
+//Close closes the rows and releases them, it can be called more than once. It returns the error of releasing them
+//if closing them succeeds
+func (me *Rows) Close() error {
+	err := me.Rows.Close()
+	me.done()
+	if err == nil {
+		err = me.releaseErr
+	}
+	return err
+}
+
+//done releases the rows once
+func (me *Rows) done() {
+	me.once.Do(func() {
+		if me.release != nil {
+			me.releaseErr = me.release()
+		}
+	})
+}
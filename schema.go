@@ -0,0 +1,200 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+//ErrSchemaMismatch is returned when entities do not match the database schema
+var ErrSchemaMismatch = errors.New("Entities do not match the database schema")
+
+//Schema represent the tables of a database
+type Schema struct {
+	Tables []*Table
+}
+
+//Table returns the table by its name, nil if not found
+func (me *Schema) Table(name string) *Table {
+	for _, table := range me.Tables {
+		if table.Name == name {
+			return table
+		}
+	}
+	return nil
+}
+
+//Table represent a database table
+type Table struct {
+	Name              string
+	Columns           []*Column
+	PrimaryKey        []string
+	ForeignKeys       []*ForeignKey
+	Indexes           []*Index
+	UniqueConstraints []*UniqueConstraint
+}
+
+//Column returns the column by its name, nil if not found
+func (me *Table) Column(name string) *Column {
+	for _, column := range me.Columns {
+		if column.Name == name {
+			return column
+		}
+	}
+	return nil
+}
+
+//IsPrimaryKey determine if the column is part of the primary key
+func (me *Table) IsPrimaryKey(column string) bool {
+	for _, key := range me.PrimaryKey {
+		if key == column {
+			return true
+		}
+	}
+	return false
+}
+
+//Column represent a table column. Type is the type name reported by the database
+type Column struct {
+	Name     string
+	Type     string
+	Nullable bool
+	Default  *string
+}
+
+//ForeignKey represent a foreign key constraint
+type ForeignKey struct {
+	Name              string
+	Columns           []string
+	ReferencedTable   string
+	ReferencedColumns []string
+	OnUpdate          string
+	OnDelete          string
+}
+
+//Index represent a secondary index, primary key indexes are not included
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+//UniqueConstraint represent a unique constraint
+type UniqueConstraint struct {
+	Name    string
+	Columns []string
+}
+
+//TableNamer is implemented by entities which know the table they are mapped to
+type TableNamer interface {
+	TableName() string
+}
+
+//Schema returns the tables of the connected database ordered by name. Postgres tables are read from the
+//current schema, MySQL tables from the current database and SQLite tables from the main database
+func (me *Client) Schema(ctx context.Context) (*Schema, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if me.DB == nil {
+		return nil, errors.New("DB instance of type (*sql.DB) is nil")
+	}
+
+	var schema *Schema
+	var err error
+	switch me.DriverName() {
+	case "postgres", "pgx":
+		schema, err = readPostgresSchema(ctx, me.DB)
+	case "mysql":
+		schema, err = readMySQLSchema(ctx, me.DB)
+	case "sqlite3", "sqlite":
+		schema, err = readSQLiteSchema(ctx, me.DB)
+	default:
+		return nil, fmt.Errorf("Schema introspection is not supported for driver %s", me.DriverName())
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(schema.Tables, func(i, j int) bool {
+		return schema.Tables[i].Name < schema.Tables[j].Name
+	})
+	return schema, nil
+}
+
+//VerifyEntities checks that the table of every entity exists and has a column for every field the entity maps,
+//using the same field mapping as StructScan. It's meant to be called at startup to fail fast on schema drift
+func (me *Client) VerifyEntities(ctx context.Context, entities ...TableNamer) error {
+	schema, err := me.Schema(ctx)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, entity := range entities {
+		table := schema.Table(entity.TableName())
+		if table == nil {
+			problems = append(problems, fmt.Sprintf("table %s of %T does not exist", entity.TableName(), entity))
+			continue
+		}
+		for _, field := range mappedFields(me.Mapper.TypeMap(reflect.TypeOf(entity)).Tree) {
+			if table.Column(field.Name) == nil {
+				problems = append(problems, fmt.Sprintf("column %s.%s of field %T.%s does not exist", table.Name, field.Name, entity, field.Field.Name))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrSchemaMismatch, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+//mappedFields returns the fields mapped to a column, fields of embedded structs are included
+func mappedFields(parent *reflectx.FieldInfo) []*reflectx.FieldInfo {
+	var fields []*reflectx.FieldInfo
+	for _, field := range parent.Children {
+		if field == nil {
+			continue
+		}
+		if field.Embedded && field.Field.Type.Kind() == reflect.Struct {
+			fields = append(fields, mappedFields(field)...)
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+//schemaBuilder collects tables in the order they are first seen
+type schemaBuilder struct {
+	schema *Schema
+	tables map[string]*Table
+}
+
+//table returns the table of the name, it's created if not seen yet
+func (me *schemaBuilder) table(name string) *Table {
+	table, ok := me.tables[name]
+	if !ok {
+		table = &Table{Name: name}
+		me.tables[name] = table
+		me.schema.Tables = append(me.schema.Tables, table)
+	}
+	return table
+}
+
+//lookup returns the table of the name, nil if it's not seen
+func (me *schemaBuilder) lookup(name string) *Table {
+	return me.tables[name]
+}
+
+//newSchemaBuilder create new schema builder instance
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		schema: &Schema{},
+		tables: map[string]*Table{},
+	}
+}
@@ -0,0 +1,126 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//readMySQLSchema reads the tables of the current database from information_schema
+func readMySQLSchema(ctx context.Context, db *sqlx.DB) (*Schema, error) {
+	builder := newSchemaBuilder()
+
+	rows, err := db.QueryxContext(ctx, `SELECT c.table_name, c.column_name, c.column_type, c.is_nullable = 'YES', c.column_default
+		FROM information_schema.columns c
+		JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = DATABASE() AND t.table_type = 'BASE TABLE'
+		ORDER BY c.table_name, c.ordinal_position`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tableName string
+		var defaultValue sql.NullString
+		column := &Column{}
+		if err := rows.Scan(&tableName, &column.Name, &column.Type, &column.Nullable, &defaultValue); err != nil {
+			return nil, err
+		}
+		if defaultValue.Valid {
+			column.Default = &defaultValue.String
+		}
+		table := builder.table(tableName)
+		table.Columns = append(table.Columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	constraintRows, err := db.QueryxContext(ctx, `SELECT tc.table_name, tc.constraint_name, tc.constraint_type, k.column_name,
+			COALESCE(k.referenced_table_name, ''), COALESCE(k.referenced_column_name, ''),
+			COALESCE(r.update_rule, ''), COALESCE(r.delete_rule, '')
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage k ON k.constraint_schema = tc.constraint_schema
+			AND k.constraint_name = tc.constraint_name AND k.table_name = tc.table_name
+		LEFT JOIN information_schema.referential_constraints r ON r.constraint_schema = tc.constraint_schema
+			AND r.constraint_name = tc.constraint_name AND r.table_name = tc.table_name
+		WHERE tc.table_schema = DATABASE() AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE', 'FOREIGN KEY')
+		ORDER BY tc.table_name, tc.constraint_name, k.ordinal_position`)
+	if err != nil {
+		return nil, err
+	}
+	defer constraintRows.Close()
+	uniqueConstraints := map[string]*UniqueConstraint{}
+	foreignKeys := map[string]*ForeignKey{}
+	for constraintRows.Next() {
+		var tableName, name, constraintType, columnName, referencedTable, referencedColumn, onUpdate, onDelete string
+		err := constraintRows.Scan(&tableName, &name, &constraintType, &columnName, &referencedTable, &referencedColumn, &onUpdate, &onDelete)
+		if err != nil {
+			return nil, err
+		}
+		table := builder.lookup(tableName)
+		if table == nil {
+			continue
+		}
+		key := tableName + "." + name
+		switch constraintType {
+		case "PRIMARY KEY":
+			table.PrimaryKey = append(table.PrimaryKey, columnName)
+		case "UNIQUE":
+			constraint, ok := uniqueConstraints[key]
+			if !ok {
+				constraint = &UniqueConstraint{Name: name}
+				uniqueConstraints[key] = constraint
+				table.UniqueConstraints = append(table.UniqueConstraints, constraint)
+			}
+			constraint.Columns = append(constraint.Columns, columnName)
+		case "FOREIGN KEY":
+			foreignKey, ok := foreignKeys[key]
+			if !ok {
+				foreignKey = &ForeignKey{Name: name, ReferencedTable: referencedTable, OnUpdate: onUpdate, OnDelete: onDelete}
+				foreignKeys[key] = foreignKey
+				table.ForeignKeys = append(table.ForeignKeys, foreignKey)
+			}
+			foreignKey.Columns = append(foreignKey.Columns, columnName)
+			foreignKey.ReferencedColumns = append(foreignKey.ReferencedColumns, referencedColumn)
+		}
+	}
+	if err := constraintRows.Err(); err != nil {
+		return nil, err
+	}
+
+	indexRows, err := db.QueryxContext(ctx, `SELECT table_name, index_name, non_unique = 0, column_name
+		FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND index_name <> 'PRIMARY'
+		ORDER BY table_name, index_name, seq_in_index`)
+	if err != nil {
+		return nil, err
+	}
+	defer indexRows.Close()
+	indexes := map[string]*Index{}
+	for indexRows.Next() {
+		var tableName, name, columnName string
+		var unique bool
+		if err := indexRows.Scan(&tableName, &name, &unique, &columnName); err != nil {
+			return nil, err
+		}
+		table := builder.lookup(tableName)
+		if table == nil {
+			continue
+		}
+		key := tableName + "." + name
+		index, ok := indexes[key]
+		if !ok {
+			index = &Index{Name: name, Unique: unique}
+			indexes[key] = index
+			table.Indexes = append(table.Indexes, index)
+		}
+		index.Columns = append(index.Columns, columnName)
+	}
+	if err := indexRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return builder.schema, nil
+}
@@ -0,0 +1,126 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+//postgresReferentialActions maps pg_constraint action codes to their SQL names
+var postgresReferentialActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+//readPostgresSchema reads the tables of the current schema from information_schema and pg_catalog
+func readPostgresSchema(ctx context.Context, db *sqlx.DB) (*Schema, error) {
+	builder := newSchemaBuilder()
+
+	rows, err := db.QueryxContext(ctx, `SELECT c.table_name, c.column_name, c.data_type, c.is_nullable = 'YES', c.column_default
+		FROM information_schema.columns c
+		JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = current_schema() AND t.table_type = 'BASE TABLE'
+		ORDER BY c.table_name, c.ordinal_position`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tableName string
+		var defaultValue sql.NullString
+		column := &Column{}
+		if err := rows.Scan(&tableName, &column.Name, &column.Type, &column.Nullable, &defaultValue); err != nil {
+			return nil, err
+		}
+		if defaultValue.Valid {
+			column.Default = &defaultValue.String
+		}
+		table := builder.table(tableName)
+		table.Columns = append(table.Columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	constraintRows, err := db.QueryxContext(ctx, `SELECT rel.relname, con.conname, con.contype,
+			ARRAY(SELECT a.attname FROM unnest(con.conkey) WITH ORDINALITY k(attnum, n)
+				JOIN pg_catalog.pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum ORDER BY k.n)::text[],
+			COALESCE(ref.relname, ''),
+			ARRAY(SELECT a.attname FROM unnest(con.confkey) WITH ORDINALITY k(attnum, n)
+				JOIN pg_catalog.pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum ORDER BY k.n)::text[],
+			con.confupdtype, con.confdeltype
+		FROM pg_catalog.pg_constraint con
+		JOIN pg_catalog.pg_class rel ON rel.oid = con.conrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = rel.relnamespace
+		LEFT JOIN pg_catalog.pg_class ref ON ref.oid = con.confrelid
+		WHERE n.nspname = current_schema() AND con.contype IN ('p', 'u', 'f')
+		ORDER BY rel.relname, con.conname`)
+	if err != nil {
+		return nil, err
+	}
+	defer constraintRows.Close()
+	for constraintRows.Next() {
+		var tableName, name, constraintType, referencedTable, onUpdate, onDelete string
+		var columns, referencedColumns []string
+		err := constraintRows.Scan(&tableName, &name, &constraintType, pq.Array(&columns), &referencedTable, pq.Array(&referencedColumns), &onUpdate, &onDelete)
+		if err != nil {
+			return nil, err
+		}
+		table := builder.lookup(tableName)
+		if table == nil {
+			continue
+		}
+		switch constraintType {
+		case "p":
+			table.PrimaryKey = columns
+		case "u":
+			table.UniqueConstraints = append(table.UniqueConstraints, &UniqueConstraint{Name: name, Columns: columns})
+		case "f":
+			table.ForeignKeys = append(table.ForeignKeys, &ForeignKey{
+				Name:              name,
+				Columns:           columns,
+				ReferencedTable:   referencedTable,
+				ReferencedColumns: referencedColumns,
+				OnUpdate:          postgresReferentialActions[onUpdate],
+				OnDelete:          postgresReferentialActions[onDelete],
+			})
+		}
+	}
+	if err := constraintRows.Err(); err != nil {
+		return nil, err
+	}
+
+	indexRows, err := db.QueryxContext(ctx, `SELECT t.relname, i.relname, ix.indisunique,
+			ARRAY(SELECT a.attname FROM unnest(ix.indkey::int2[]) WITH ORDINALITY k(attnum, n)
+				JOIN pg_catalog.pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum ORDER BY k.n)::text[]
+		FROM pg_catalog.pg_index ix
+		JOIN pg_catalog.pg_class t ON t.oid = ix.indrelid
+		JOIN pg_catalog.pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = current_schema() AND NOT ix.indisprimary
+		ORDER BY t.relname, i.relname`)
+	if err != nil {
+		return nil, err
+	}
+	defer indexRows.Close()
+	for indexRows.Next() {
+		var tableName string
+		index := &Index{}
+		if err := indexRows.Scan(&tableName, &index.Name, &index.Unique, pq.Array(&index.Columns)); err != nil {
+			return nil, err
+		}
+		if table := builder.lookup(tableName); table != nil {
+			table.Indexes = append(table.Indexes, index)
+		}
+	}
+	if err := indexRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return builder.schema, nil
+}
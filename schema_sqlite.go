@@ -0,0 +1,119 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//readSQLiteSchema reads the tables of the main database from sqlite_master and table pragmas
+func readSQLiteSchema(ctx context.Context, db *sqlx.DB) (*Schema, error) {
+	builder := newSchemaBuilder()
+
+	var names []string
+	err := db.SelectContext(ctx, &names, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		table := builder.table(name)
+		if err := readSQLiteColumns(ctx, db, table); err != nil {
+			return nil, err
+		}
+		if err := readSQLiteForeignKeys(ctx, db, table); err != nil {
+			return nil, err
+		}
+		if err := readSQLiteIndexes(ctx, db, table); err != nil {
+			return nil, err
+		}
+	}
+	return builder.schema, nil
+}
+
+//readSQLiteColumns reads columns and primary key of the table
+func readSQLiteColumns(ctx context.Context, db *sqlx.DB, table *Table) error {
+	rows, err := db.QueryxContext(ctx, `SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?) ORDER BY cid`, table.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	keys := map[int]string{}
+	for rows.Next() {
+		column := &Column{}
+		var notNull bool
+		var defaultValue sql.NullString
+		var keyPosition int
+		if err := rows.Scan(&column.Name, &column.Type, &notNull, &defaultValue, &keyPosition); err != nil {
+			return err
+		}
+		column.Nullable = !notNull && keyPosition == 0
+		if defaultValue.Valid {
+			column.Default = &defaultValue.String
+		}
+		if keyPosition > 0 {
+			keys[keyPosition] = column.Name
+		}
+		table.Columns = append(table.Columns, column)
+	}
+	for position := 1; position <= len(keys); position++ {
+		table.PrimaryKey = append(table.PrimaryKey, keys[position])
+	}
+	return rows.Err()
+}
+
+//readSQLiteForeignKeys reads foreign keys of the table. SQLite foreign keys are unnamed
+func readSQLiteForeignKeys(ctx context.Context, db *sqlx.DB, table *Table) error {
+	rows, err := db.QueryxContext(ctx, `SELECT id, "table", "from", COALESCE("to", ''), on_update, on_delete FROM pragma_foreign_key_list(?) ORDER BY id, seq`, table.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	foreignKeys := map[int]*ForeignKey{}
+	for rows.Next() {
+		var id int
+		var referencedTable, columnName, referencedColumn, onUpdate, onDelete string
+		if err := rows.Scan(&id, &referencedTable, &columnName, &referencedColumn, &onUpdate, &onDelete); err != nil {
+			return err
+		}
+		foreignKey, ok := foreignKeys[id]
+		if !ok {
+			foreignKey = &ForeignKey{ReferencedTable: referencedTable, OnUpdate: onUpdate, OnDelete: onDelete}
+			foreignKeys[id] = foreignKey
+			table.ForeignKeys = append(table.ForeignKeys, foreignKey)
+		}
+		foreignKey.Columns = append(foreignKey.Columns, columnName)
+		foreignKey.ReferencedColumns = append(foreignKey.ReferencedColumns, referencedColumn)
+	}
+	return rows.Err()
+}
+
+//readSQLiteIndexes reads indexes and unique constraints of the table
+func readSQLiteIndexes(ctx context.Context, db *sqlx.DB, table *Table) error {
+	type indexInfo struct {
+		Name   string `db:"name"`
+		Unique bool   `db:"unique"`
+		Origin string `db:"origin"`
+	}
+	var infos []indexInfo
+	err := db.SelectContext(ctx, &infos, `SELECT name, "unique", origin FROM pragma_index_list(?) WHERE origin <> 'pk' ORDER BY name`, table.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		index := &Index{Name: info.Name, Unique: info.Unique}
+		err := db.SelectContext(ctx, &index.Columns, `SELECT name FROM pragma_index_info(?) ORDER BY seqno`, info.Name)
+		if err != nil {
+			return err
+		}
+		table.Indexes = append(table.Indexes, index)
+		if info.Origin == "u" {
+			table.UniqueConstraints = append(table.UniqueConstraints, &UniqueConstraint{Name: info.Name, Columns: index.Columns})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3" //needed
+)
+
+func getSQLiteDb(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Fatal create db error: %s", err.Error())
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type schemaTestOrder struct {
+	ID          string     `db:"id"`
+	OrderNumber string     `db:"order_number"`
+	Total       float64    `db:"total"`
+	CreatedAt   time.Time  `db:"created_at"`
+	UpdatedAt   *time.Time `db:"updated_at"`
+}
+
+func (me *schemaTestOrder) TableName() string {
+	return "order"
+}
+
+func Test_Client_Schema_SQLite(t *testing.T) {
+	db := getSQLiteDb(t)
+	db.MustExec(`CREATE TABLE customer (id TEXT PRIMARY KEY, email TEXT NOT NULL UNIQUE);
+		CREATE TABLE "order" (
+			id TEXT PRIMARY KEY,
+			customer_id TEXT REFERENCES customer (id) ON DELETE CASCADE,
+			order_number TEXT NOT NULL,
+			total NUMERIC NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP
+		);
+		CREATE INDEX order_created_at ON "order" (created_at, id);`)
+
+	schema, err := NewClient(db).Schema(context.Background())
+	if err != nil {
+		t.Fatalf("Schema error: %s", err.Error())
+	}
+	if len(schema.Tables) != 2 || schema.Tables[0].Name != "customer" || schema.Tables[1].Name != "order" {
+		t.Fatalf("Expected customer and order tables")
+	}
+
+	order := schema.Table("order")
+	if len(order.Columns) != 6 || len(order.PrimaryKey) != 1 || !order.IsPrimaryKey("id") {
+		t.Errorf("Unexpected order columns or primary key")
+	}
+	if total := order.Column("total"); total.Nullable || total.Default == nil || *total.Default != "0" || total.Type != "NUMERIC" {
+		t.Errorf("Unexpected total column %+v", total)
+	}
+	if !order.Column("updated_at").Nullable || order.Column("id").Nullable {
+		t.Errorf("Unexpected nullability")
+	}
+	if len(order.ForeignKeys) != 1 || order.ForeignKeys[0].ReferencedTable != "customer" || order.ForeignKeys[0].Columns[0] != "customer_id" || order.ForeignKeys[0].OnDelete != "CASCADE" {
+		t.Errorf("Unexpected foreign keys %+v", order.ForeignKeys)
+	}
+	if len(order.Indexes) != 1 || order.Indexes[0].Name != "order_created_at" || len(order.Indexes[0].Columns) != 2 || order.Indexes[0].Unique {
+		t.Errorf("Unexpected indexes %+v", order.Indexes)
+	}
+
+	customer := schema.Table("customer")
+	if len(customer.UniqueConstraints) != 1 || customer.UniqueConstraints[0].Columns[0] != "email" {
+		t.Errorf("Unexpected unique constraints %+v", customer.UniqueConstraints)
+	}
+}
+
+func Test_Client_VerifyEntities(t *testing.T) {
+	db := getSQLiteDb(t)
+	db.MustExec(`CREATE TABLE "order" (id TEXT PRIMARY KEY, order_number TEXT NOT NULL, total NUMERIC NOT NULL, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP)`)
+	client := NewClient(db)
+
+	if err := client.VerifyEntities(context.Background(), &schemaTestOrder{}); err != nil {
+		t.Errorf("Entity expected to match the schema: %s", err.Error())
+	}
+
+	db.MustExec(`ALTER TABLE "order" RENAME COLUMN total TO grand_total`)
+	err := client.VerifyEntities(context.Background(), &schemaTestOrder{})
+	if !errors.Is(err, ErrSchemaMismatch) {
+		t.Fatalf("VerifyEntities expected to return ErrSchemaMismatch, but got %v", err)
+	}
+	if err.Error() != "Entities do not match the database schema: column order.total of field *dbx.schemaTestOrder.Total does not exist" {
+		t.Errorf("Unexpected error message %q", err.Error())
+	}
+}
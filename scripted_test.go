@@ -0,0 +1,167 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/supendi/dbx/internal/driverutil"
+)
+
+//scriptedCall represent an interaction with a scripted database, Kind is begin, exec, query, commit or rollback
+type scriptedCall struct {
+	Kind    string
+	SQL     string
+	Args    []driver.Value
+	Context context.Context
+}
+
+//scripted is an in-memory database answering every interaction with a function, it records the interactions.
+//The tests of the package can't import the dbxtest fake, which imports dbx
+type scripted struct {
+	mutex  sync.Mutex
+	calls  []*scriptedCall
+	answer func(call *scriptedCall) (*driverutil.Rows, error)
+}
+
+//newScriptedClient returns a client of a scripted database reporting the driver name. answer returns the rows
+//of queries, nil for no row, or the error of the interaction
+func newScriptedClient(t *testing.T, driverName string, answer func(call *scriptedCall) (*driverutil.Rows, error)) (*Client, *scripted) {
+	if answer == nil {
+		answer = func(call *scriptedCall) (*driverutil.Rows, error) { return nil, nil }
+	}
+	database := &scripted{answer: answer}
+	db := sql.OpenDB(database)
+	t.Cleanup(func() { db.Close() })
+	return NewClient(sqlx.NewDb(db, driverName)), database
+}
+
+//scriptedRows returns the rows of a single column
+func scriptedRows(column string, values ...driver.Value) *driverutil.Rows {
+	rows := [][]driver.Value{}
+	for _, value := range values {
+		rows = append(rows, []driver.Value{value})
+	}
+	return driverutil.NewRows([]string{column}, rows, nil)
+}
+
+//record records the call and returns its answer
+func (me *scripted) record(call *scriptedCall) (*driverutil.Rows, error) {
+	me.mutex.Lock()
+	me.calls = append(me.calls, call)
+	me.mutex.Unlock()
+	return me.answer(call)
+}
+
+//kinds returns the kinds of the recorded calls in order
+func (me *scripted) kinds() []string {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	kinds := []string{}
+	for _, call := range me.calls {
+		kinds = append(kinds, call.Kind)
+	}
+	return kinds
+}
+
+//statements returns the recorded exec and query calls whose SQL matches the regular expression pattern
+func (me *scripted) statements(pattern string) []*scriptedCall {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	expression := regexp.MustCompile(pattern)
+	calls := []*scriptedCall{}
+	for _, call := range me.calls {
+		if (call.Kind == "exec" || call.Kind == "query") && expression.MatchString(call.SQL) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+//reset forgets the recorded calls
+func (me *scripted) reset() {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.calls = nil
+}
+
+//Connect implements driver.Connector
+func (me *scripted) Connect(ctx context.Context) (driver.Conn, error) {
+	return &scriptedConn{database: me}, nil
+}
+
+//Driver implements driver.Connector
+func (me *scripted) Driver() driver.Driver {
+	return me
+}
+
+//Open implements driver.Driver
+func (me *scripted) Open(name string) (driver.Conn, error) {
+	return me.Connect(context.Background())
+}
+
+//scriptedConn is a connection to a scripted database
+type scriptedConn struct {
+	database *scripted
+}
+
+//Prepare implements driver.Conn, statements are run without being prepared
+func (me *scriptedConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("scripted database doesn't prepare statements")
+}
+
+//Close implements driver.Conn
+func (me *scriptedConn) Close() error {
+	return nil
+}
+
+//Begin implements driver.Conn
+func (me *scriptedConn) Begin() (driver.Tx, error) {
+	return me.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+//BeginTx implements driver.ConnBeginTx
+func (me *scriptedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if _, err := me.database.record(&scriptedCall{Kind: "begin", Context: ctx}); err != nil {
+		return nil, err
+	}
+	return me, nil
+}
+
+//Commit implements driver.Tx
+func (me *scriptedConn) Commit() error {
+	_, err := me.database.record(&scriptedCall{Kind: "commit"})
+	return err
+}
+
+//Rollback implements driver.Tx
+func (me *scriptedConn) Rollback() error {
+	_, err := me.database.record(&scriptedCall{Kind: "rollback"})
+	return err
+}
+
+//ExecContext implements driver.ExecerContext
+func (me *scriptedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if _, err := me.database.record(&scriptedCall{Kind: "exec", SQL: query, Args: driverutil.Values(args), Context: ctx}); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+//QueryContext implements driver.QueryerContext
+func (me *scriptedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := me.database.record(&scriptedCall{Kind: "query", SQL: query, Args: driverutil.Values(args), Context: ctx})
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		rows = driverutil.NewRows([]string{}, nil, nil)
+	}
+	return rows, nil
+}
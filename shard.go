@@ -0,0 +1,313 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//shardKey is the context key of the shard key
+const shardKey txContextKey = "shard"
+
+//ErrShardKeyRequired is returned when a sharded client runs a statement whose shard key is neither set on it
+//nor carried by ctx
+var ErrShardKeyRequired = errors.New("Shard key is required but neither the statement nor ctx has one")
+
+//ErrShardNotFound is returned when the router has no shard for a key
+var ErrShardNotFound = errors.New("Shard of the key is not found")
+
+//ErrMultipleShards is returned by SaveChanges when its statements belong to more than one shard,
+//see Context.AllowMultipleShards
+var ErrMultipleShards = errors.New("Statements belong to multiple shards")
+
+//ShardRouter picks the shard of a key
+type ShardRouter interface {
+	//Shard returns the index of the shard of the key among the shards
+	Shard(key string, shards int) (int, error)
+}
+
+//hashRouter routes keys by their FNV-1a hash
+type hashRouter struct{}
+
+func (me hashRouter) Shard(key string, shards int) (int, error) {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	return int(hash.Sum64() % uint64(shards)), nil
+}
+
+//NewHashRouter create new router spreading keys evenly by their hash. Keys move when shards are added
+func NewHashRouter() ShardRouter {
+	return hashRouter{}
+}
+
+//RangeRouter routes integer keys by range
+type RangeRouter struct {
+	bounds []int64
+}
+
+//Shard returns the number of bounds the key is greater than or equal to
+func (me *RangeRouter) Shard(key string, shards int) (int, error) {
+	value, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s is not an integer", ErrShardNotFound, key)
+	}
+	shard := 0
+	for shard < len(me.bounds) && value >= me.bounds[shard] {
+		shard++
+	}
+	return shard, nil
+}
+
+//NewRangeRouter create new router of integer keys, the first shard holds the keys lower than the first bound,
+//the next one the keys from the first bound up to the second one and so on. The last shard holds the keys
+//from the last bound, bounds must be in ascending order
+func NewRangeRouter(bounds ...int64) *RangeRouter {
+	return &RangeRouter{bounds: bounds}
+}
+
+//LookupRouter routes keys by a lookup table, it's safe for concurrent use
+type LookupRouter struct {
+	mutex sync.RWMutex
+	table map[string]int
+}
+
+//Set set the shard of the key
+func (me *LookupRouter) Set(key string, shard int) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.table[key] = shard
+}
+
+//Shard returns the shard of the key in the table, ErrShardNotFound if it's missing
+func (me *LookupRouter) Shard(key string, shards int) (int, error) {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	shard, ok := me.table[key]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s is not in the lookup table", ErrShardNotFound, key)
+	}
+	return shard, nil
+}
+
+//NewLookupRouter create new router of the lookup table mapping keys to shards
+func NewLookupRouter(table map[string]int) *LookupRouter {
+	copied := map[string]int{}
+	for key, shard := range table {
+		copied[key] = shard
+	}
+	return &LookupRouter{table: copied}
+}
+
+//NewShardedClient create new client instance over the shards, each statement and transaction runs on the shard
+//the router picks from its shard key, see Statement.ShardKey and WithShardKey. The first shard serves the features
+//which are not sharded such as advisory locks and notifications
+func NewShardedClient(router ShardRouter, shards ...*sqlx.DB) *Client {
+	var db *sqlx.DB
+	if len(shards) > 0 {
+		db = shards[0]
+	}
+	client := NewClient(db)
+	client.router = router
+	client.shards = shards
+	return client
+}
+
+//WithShardKey returns ctx carrying the shard key of the statements and transactions of a sharded client
+func WithShardKey(ctx context.Context, key string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, shardKey, key)
+}
+
+//ShardKeyFrom returns the shard key carried by ctx and whether there is one
+func ShardKeyFrom(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	key, ok := ctx.Value(shardKey).(string)
+	return key, ok && key != ""
+}
+
+//ShardKey set the shard key of the statement, it overrides the one of ctx
+func (me *Statement) ShardKey(key string) *Statement {
+	me.shardKey = key
+	return me
+}
+
+//AllowMultipleShards lets SaveChanges save statements belonging to several shards, each shard in its own
+//transaction committed one after another. The unit of work is not atomic, the shards committed before one
+//failing keep their changes
+func (me *Context) AllowMultipleShards() *Context {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.multipleShards = true
+	return me
+}
+
+//isSharded determine if the client runs over shards
+func (me *Client) isSharded() bool {
+	return me.router != nil && len(me.shards) > 0
+}
+
+//shardOf returns the shard of the statement, or of ctx if statement is nil. It's -1 if the client is not sharded
+func (me *Client) shardOf(ctx context.Context, statement *Statement) (int, error) {
+	if !me.isSharded() {
+		return -1, nil
+	}
+	key, ok := ShardKeyFrom(ctx)
+	if statement != nil && statement.shardKey != "" {
+		key, ok = statement.shardKey, true
+	}
+	if !ok {
+		return -1, ErrShardKeyRequired
+	}
+	shard, err := me.router.Shard(key, len(me.shards))
+	if err != nil {
+		return -1, err
+	}
+	if shard < 0 || shard >= len(me.shards) {
+		return -1, fmt.Errorf("%w: shard %d of %s is out of range", ErrShardNotFound, shard, key)
+	}
+	return shard, nil
+}
+
+//database returns the pool of the shard, the database if the client is not sharded
+func (me *Client) database(shard int) *sqlx.DB {
+	if shard < 0 {
+		return me.DB
+	}
+	return me.shards[shard]
+}
+
+//shardStatements represent the statements of a unit of work belonging to a shard
+type shardStatements struct {
+	shard      int
+	statements []*Statement
+}
+
+//groupByShard groups the statements by shard in the order the shards first appear, a single group if the client
+//is not sharded
+func (me *Client) groupByShard(ctx context.Context, statements []*Statement) ([]*shardStatements, error) {
+	groups := []*shardStatements{}
+	for _, statement := range statements {
+		shard, err := me.shardOf(ctx, statement)
+		if err != nil {
+			return nil, err
+		}
+		var group *shardStatements
+		for _, existing := range groups {
+			if existing.shard == shard {
+				group = existing
+			}
+		}
+		if group == nil {
+			group = &shardStatements{shard: shard}
+			groups = append(groups, group)
+		}
+		group.statements = append(group.statements, statement)
+	}
+	return groups, nil
+}
+
+//checkTransactionShard returns ErrMultipleShards if a statement with a shard key belongs to another shard than
+//the one of the transaction
+func (me *Client) checkTransactionShard(ctx context.Context, transaction *Transaction, statements []*Statement) error {
+	if !me.isSharded() {
+		return nil
+	}
+	for _, statement := range statements {
+		shard, err := me.shardOf(ctx, statement)
+		if errors.Is(err, ErrShardKeyRequired) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if shard != transaction.shard {
+			return ErrMultipleShards
+		}
+	}
+	return nil
+}
+
+//ScatterGather runs the query of the statement on every shard of the client concurrently and scans the records
+//into T like Select does. The records are merged in the order of the shards, those of each shard in the order
+//the query returns them, a client which is not sharded has a single shard. It fails with the error of the first
+//failed shard, the queries of the other shards are cancelled then
+func ScatterGather[T any](ctx context.Context, client *Client, statement *Statement) ([]T, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := client.lifecycle.enter(); err != nil {
+		return nil, err
+	}
+	defer client.lifecycle.leave()
+	if client.tenantSetup(ctx) != nil {
+		return nil, ErrTenantTransactionRequired
+	}
+	if statement.IsLocking() {
+		return nil, ErrLockRequiresTransaction
+	}
+
+	shards := []int{-1}
+	if client.isSharded() {
+		shards = make([]int, len(client.shards))
+		for i := range shards {
+			shards[i] = i
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([][]T, len(shards))
+	var firstErr error
+	var once sync.Once
+	var wait sync.WaitGroup
+	for i, shard := range shards {
+		wait.Add(1)
+		go func(i int, shard int) {
+			defer wait.Done()
+			var err error
+			results[i], err = gather[T](ctx, client, client.database(shard), statement)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i, shard)
+	}
+	wait.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	items := []T{}
+	for i := range shards {
+		items = append(items, results[i]...)
+	}
+	return items, nil
+}
+
+//gather queries the records of the statement on the pool and scans them into T
+func gather[T any](ctx context.Context, client *Client, db *sqlx.DB, statement *Statement) ([]T, error) {
+	rows, err := client.query(ctx, db, statement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []T{}
+	for rows.Next() {
+		item, err := scanItem[T](rows, nil)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
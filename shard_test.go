@@ -0,0 +1,210 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/supendi/dbx/internal/driverutil"
+)
+
+func Test_ShardRouters(t *testing.T) {
+	hash := NewHashRouter()
+	first, _ := hash.Shard("customer-1", 4)
+	again, _ := hash.Shard("customer-1", 4)
+	if first != again || first < 0 || first >= 4 {
+		t.Errorf("Expected a stable shard in range, got %d and %d", first, again)
+	}
+
+	ranges := NewRangeRouter(100, 200)
+	for key, expected := range map[string]int{"-5": 0, "99": 0, "100": 1, "199": 1, "200": 2, "5000": 2} {
+		if shard, err := ranges.Shard(key, 3); err != nil || shard != expected {
+			t.Errorf("Expected shard %d of %s, got %d %v", expected, key, shard, err)
+		}
+	}
+	if _, err := ranges.Shard("abc", 3); !errors.Is(err, ErrShardNotFound) {
+		t.Errorf("Expected ErrShardNotFound, got %v", err)
+	}
+
+	lookup := NewLookupRouter(map[string]int{"acme": 1})
+	lookup.Set("globex", 0)
+	if shard, err := lookup.Shard("acme", 2); err != nil || shard != 1 {
+		t.Errorf("Expected shard 1, got %d %v", shard, err)
+	}
+	if shard, err := lookup.Shard("globex", 2); err != nil || shard != 0 {
+		t.Errorf("Expected shard 0, got %d %v", shard, err)
+	}
+	if _, err := lookup.Shard("initech", 2); !errors.Is(err, ErrShardNotFound) {
+		t.Errorf("Expected ErrShardNotFound, got %v", err)
+	}
+}
+
+func newShardedTestClient(t *testing.T) (*Client, []*sqlx.DB) {
+	shards := []*sqlx.DB{getSQLiteFileDb(t), getSQLiteFileDb(t)}
+	router := NewLookupRouter(map[string]int{"acme": 0, "globex": 1, "lost": 5})
+	return NewShardedClient(router, shards...), shards
+}
+
+func Test_Context_SaveChangesSharded(t *testing.T) {
+	client, shards := newShardedTestClient(t)
+	ctx := WithShardKey(context.Background(), "acme")
+
+	dbContext := client.NewContext()
+	dbContext.AddStatements(newPersonStatement("Dadang"), newPersonStatement("Asep"), newPersonStatement("Bowo").ShardKey("acme"))
+	if _, err := dbContext.SaveChanges(ctx); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	dbContext.AddStatement(newPersonStatement("Ujang").ShardKey("globex"))
+	if _, err := dbContext.SaveChanges(ctx); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	if countPersons(t, shards[0]) != 3 || countPersons(t, shards[1]) != 1 {
+		t.Errorf("Expected the statements to be saved on their shard, got %d and %d", countPersons(t, shards[0]), countPersons(t, shards[1]))
+	}
+
+	dbContext.AddStatement(newPersonStatement("Keyless"))
+	if _, err := dbContext.SaveChanges(context.Background()); !errors.Is(err, ErrShardKeyRequired) {
+		t.Errorf("Expected ErrShardKeyRequired, got %v", err)
+	}
+	dbContext.ClearStatements()
+	dbContext.AddStatement(newPersonStatement("Lost").ShardKey("lost"))
+	if _, err := dbContext.SaveChanges(ctx); !errors.Is(err, ErrShardNotFound) {
+		t.Errorf("Expected ErrShardNotFound, got %v", err)
+	}
+	dbContext.ClearStatements()
+
+	dbContext.AddStatements(newPersonStatement("Cecep"), newPersonStatement("Dudung").ShardKey("globex"))
+	if _, err := dbContext.SaveChanges(ctx); !errors.Is(err, ErrMultipleShards) {
+		t.Errorf("Expected ErrMultipleShards, got %v", err)
+	}
+	if len(dbContext.Statements()) != 2 {
+		t.Fatalf("Expected the statements to be kept, got %d", len(dbContext.Statements()))
+	}
+	results, err := dbContext.AllowMultipleShards().SaveChanges(ctx)
+	if err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	if len(results) != 2 || countPersons(t, shards[0]) != 4 || countPersons(t, shards[1]) != 2 {
+		t.Errorf("Expected each shard to save its statement, got %d results", len(results))
+	}
+}
+
+func Test_Context_TransactionSharded(t *testing.T) {
+	client, shards := newShardedTestClient(t)
+	ctx := WithShardKey(context.Background(), "globex")
+	dbContext := client.NewContext()
+	if _, err := dbContext.BeginTransaction(); !errors.Is(err, ErrShardKeyRequired) {
+		t.Errorf("Expected ErrShardKeyRequired, got %v", err)
+	}
+	if _, err := dbContext.BeginTransactionContext(ctx); err != nil {
+		t.Fatalf("BeginTransactionContext error: %s", err.Error())
+	}
+
+	dbContext.AddStatements(newPersonStatement("Dadang"), newPersonStatement("Asep").ShardKey("globex"))
+	if _, err := dbContext.SaveChanges(context.Background()); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	dbContext.AddStatement(newPersonStatement("Bowo").ShardKey("acme"))
+	if _, err := dbContext.AllowMultipleShards().SaveChanges(ctx); !errors.Is(err, ErrMultipleShards) {
+		t.Errorf("Expected ErrMultipleShards, got %v", err)
+	}
+	if err := dbContext.CompleteTransaction(); err != nil {
+		t.Fatalf("CompleteTransaction error: %s", err.Error())
+	}
+	if countPersons(t, shards[0]) != 0 || countPersons(t, shards[1]) != 2 {
+		t.Errorf("Expected the transaction to run on its shard, got %d and %d", countPersons(t, shards[0]), countPersons(t, shards[1]))
+	}
+}
+
+func Test_ScatterGather(t *testing.T) {
+	client, shards := newShardedTestClient(t)
+	for i, name := range []string{"Dadang", "Asep"} {
+		statement := newPersonStatement(name)
+		shards[i].NamedExec(statement.SQL, statement.Parameters)
+	}
+
+	rows, err := client.QueryStatement(NewStatement("SELECT name FROM person").ShardKey("globex"))
+	if err != nil {
+		t.Fatalf("QueryStatement error: %s", err.Error())
+	}
+	if !rows.Next() {
+		t.Fatal("Expected the person of the shard")
+	}
+	var name string
+	rows.Scan(&name)
+	rows.Close()
+	if name != "Asep" {
+		t.Errorf("Expected Asep, got %s", name)
+	}
+	if _, err := client.QueryStatement(NewStatement("SELECT name FROM person")); !errors.Is(err, ErrShardKeyRequired) {
+		t.Errorf("Expected ErrShardKeyRequired, got %v", err)
+	}
+
+	persons, err := ScatterGather[struct{ Name string }](context.Background(), client, NewStatement("SELECT name FROM person"))
+	if err != nil {
+		t.Fatalf("ScatterGather error: %s", err.Error())
+	}
+	if len(persons) != 2 || persons[0].Name != "Dadang" || persons[1].Name != "Asep" {
+		t.Errorf("Expected the persons of both shards in order, got %+v", persons)
+	}
+	if _, err := ScatterGather[struct{ Name string }](context.Background(), client, NewStatement("SELECT missing FROM person")); err == nil {
+		t.Error("Expected the error of the shards")
+	}
+
+	health := client.Health(context.Background())
+	if !health.Healthy || len(health.Databases) != 2 || health.Databases[0].Name != "shard 0" || health.Databases[1].Name != "shard 1" {
+		t.Errorf("Expected the health of both shards, got %+v", health)
+	}
+	if stats := client.Stats(); len(stats.Shards) != 2 {
+		t.Errorf("Expected the statistics of both shards, got %+v", stats)
+	}
+}
+
+func Test_Select_CacheSharded(t *testing.T) {
+	client, shards := newShardedTestClient(t)
+	client.WithCache(NewMemoryCache(100))
+	for i, name := range []string{"Dadang", "Asep"} {
+		statement := newPersonStatement(name)
+		shards[i].NamedExec(statement.SQL, statement.Parameters)
+	}
+
+	query := NewStatement("SELECT name FROM person").Cache(time.Minute, "person")
+	for _, shard := range []struct{ key, name string }{{"acme", "Dadang"}, {"globex", "Asep"}, {"acme", "Dadang"}} {
+		persons, err := Select[struct{ Name string }](WithShardKey(context.Background(), shard.key), client, query)
+		if err != nil {
+			t.Fatalf("Select error: %s", err.Error())
+		}
+		if len(persons) != 1 || persons[0].Name != shard.name {
+			t.Errorf("Expected %s of the shard of %s, got %+v", shard.name, shard.key, persons)
+		}
+	}
+}
+
+func Test_ScatterGather_CancelsOnFirstError(t *testing.T) {
+	errShard := errors.New("shard is down")
+	failing, _ := newScriptedClient(t, "sqlite3", func(call *scriptedCall) (*driverutil.Rows, error) {
+		return nil, errShard
+	})
+	slow, _ := newScriptedClient(t, "sqlite3", func(call *scriptedCall) (*driverutil.Rows, error) {
+		<-call.Context.Done()
+		return nil, call.Context.Err()
+	})
+	client := NewShardedClient(NewHashRouter(), failing.DB, slow.DB)
+
+	done := make(chan error)
+	go func() {
+		_, err := ScatterGather[struct{ Name string }](context.Background(), client, NewStatement("SELECT name FROM person"))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, errShard) {
+			t.Errorf("Expected the error of the failed shard, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the query of the other shard to be cancelled")
+	}
+}
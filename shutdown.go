@@ -0,0 +1,192 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+//ErrClientClosed is returned by statements and transactions of a client which is shut down, see Client.Shutdown
+var ErrClientClosed = errors.New("Client is closed")
+
+//drainInterval is the interval Shutdown checks whether the units of work are drained
+const drainInterval = 10 * time.Millisecond
+
+//shutdownHookTimeout bounds the functions registered by OnShutdown when the drain timed out
+const shutdownHookTimeout = 5 * time.Second
+
+//lifecycle counts the units of work in flight outside transactions and the open rows, and rejects new units of work
+//once the client is closed
+type lifecycle struct {
+	mutex      sync.Mutex
+	closed     bool
+	inFlight   int
+	openRows   int
+	onShutdown []func(ctx context.Context) error
+}
+
+//enter admits a unit of work, ErrClientClosed if the client is closed. It must be followed by leave
+func (me *lifecycle) enter() error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.closed {
+		return ErrClientClosed
+	}
+	me.inFlight++
+	return nil
+}
+
+//leave ends a unit of work admitted by enter
+func (me *lifecycle) leave() {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.inFlight--
+}
+
+//track counts the rows as open until they are released, rows is returned as is if me is nil
+func (me *lifecycle) track(rows *Rows) *Rows {
+	if me == nil {
+		return rows
+	}
+	me.mutex.Lock()
+	me.openRows++
+	me.mutex.Unlock()
+	release := rows.release
+	rows.release = func() error {
+		err := release()
+		me.mutex.Lock()
+		defer me.mutex.Unlock()
+		me.openRows--
+		return err
+	}
+	return rows
+}
+
+//close rejects new units of work, false if it was closed already
+func (me *lifecycle) close() bool {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.closed {
+		return false
+	}
+	me.closed = true
+	return true
+}
+
+//isClosed determine if the client is closed
+func (me *lifecycle) isClosed() bool {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.closed
+}
+
+//idle determine if no unit of work is in flight and no rows are open
+func (me *lifecycle) idle() bool {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.inFlight == 0 && me.openRows == 0
+}
+
+//rows returns the number of open rows
+func (me *lifecycle) rows() int {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.openRows
+}
+
+//newLifecycle create new lifecycle instance
+func newLifecycle() *lifecycle {
+	return &lifecycle{}
+}
+
+//OnShutdown registers fn to be called by Shutdown once the units of work are drained, before the pools are closed.
+//It's the place to flush logs and metrics of the database work. fn is called with the ctx of Shutdown, or if the
+//drain timed out, with a context of its values which is done after 5 seconds
+func (me *Client) OnShutdown(fn func(ctx context.Context) error) {
+	me.lifecycle.mutex.Lock()
+	defer me.lifecycle.mutex.Unlock()
+	me.lifecycle.onShutdown = append(me.lifecycle.onShutdown, fn)
+}
+
+//Shutdown stops the client gracefully. Statements, SaveChanges and transactions begun afterwards fail with
+//ErrClientClosed, while the ones in flight, the rows which are not closed yet and the open transactions are waited for
+//until ctx is done. Open transactions may still run statements and be completed meanwhile, the ones remaining are
+//rolled back. Then the functions registered by OnShutdown are called and the pools of the database and its replicas
+//are closed. It returns an error wrapping ctx.Err() if the drain timed out, joined with the errors of the functions
+//and of closing the pools, ErrClientClosed if the client is shut down already
+func (me *Client) Shutdown(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !me.lifecycle.close() {
+		return ErrClientClosed
+	}
+
+	var drainErr error
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+	for !me.lifecycle.idle() || me.transactions.count() > 0 {
+		select {
+		case <-ctx.Done():
+			drainErr = ctx.Err()
+		case <-ticker.C:
+			continue
+		}
+		break
+	}
+	for _, open := range me.transactions.list() {
+		open.Transaction.Rollback()
+	}
+
+	hookCtx := ctx
+	if drainErr != nil {
+		var cancel context.CancelFunc
+		hookCtx, cancel = context.WithTimeout(detachedContext{ctx}, shutdownHookTimeout)
+		defer cancel()
+	}
+	errs := []string{}
+	me.lifecycle.mutex.Lock()
+	onShutdown := me.lifecycle.onShutdown
+	me.lifecycle.mutex.Unlock()
+	for _, fn := range onShutdown {
+		if err := fn(hookCtx); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if err := me.Close(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if drainErr != nil && len(errs) > 0 {
+		return fmt.Errorf("%w; %s", drainErr, strings.Join(errs, "; "))
+	}
+	if drainErr != nil {
+		return drainErr
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+//detachedContext carries the values of its parent context without its deadline and cancellation
+type detachedContext struct {
+	context.Context
+}
+
+//Deadline implements context.Context, a detached context has no deadline
+func (me detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+//Done implements context.Context, a detached context is never cancelled
+func (me detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+//Err implements context.Context
+func (me detachedContext) Err() error {
+	return nil
+}
@@ -0,0 +1,174 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Client_Shutdown(t *testing.T) {
+	client := NewClient(getSQLiteFileDb(t))
+	flushed := false
+	client.OnShutdown(func(ctx context.Context) error {
+		flushed = true
+		return nil
+	})
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown error: %s", err.Error())
+	}
+	if !flushed {
+		t.Error("Expected the shutdown functions to be called")
+	}
+	if err := client.DB.Ping(); err == nil {
+		t.Error("Expected the pool to be closed")
+	}
+
+	if _, err := client.ExecStatement(newPersonStatement("Closed")); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed, got %v", err)
+	}
+	if _, err := client.QueryStatement(NewStatement("SELECT 1")); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed, got %v", err)
+	}
+	dbContext := client.NewContext()
+	if _, err := dbContext.BeginTransaction(); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed, got %v", err)
+	}
+	dbContext.AddStatement(newPersonStatement("Closed"))
+	if _, err := dbContext.SaveChanges(context.Background()); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed, got %v", err)
+	}
+	if len(dbContext.Statements()) != 1 {
+		t.Errorf("Expected the statements to be kept, got %d", len(dbContext.Statements()))
+	}
+	if health := client.Health(context.Background()); health.Healthy || !health.Closed {
+		t.Errorf("Expected a closed client to be unhealthy, got %+v", health)
+	}
+	if err := client.Shutdown(context.Background()); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed, got %v", err)
+	}
+}
+
+func Test_Client_ShutdownDrainsTransactions(t *testing.T) {
+	client := NewClient(getSQLiteFileDb(t))
+	dbContext := client.NewContext()
+	if _, err := dbContext.BeginTransaction(); err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+
+	done := make(chan error)
+	go func() { done <- client.Shutdown(context.Background()) }()
+	time.Sleep(3 * drainInterval)
+	select {
+	case err := <-done:
+		t.Fatalf("Expected Shutdown to wait for the open transaction, got %v", err)
+	default:
+	}
+
+	dbContext.AddStatements(newPersonStatement("Dadang"), newPersonStatement("Asep"))
+	if _, err := dbContext.SaveChanges(context.Background()); err != nil {
+		t.Fatalf("Expected the open transaction to save changes, got %s", err.Error())
+	}
+	if err := dbContext.CompleteTransaction(); err != nil {
+		t.Fatalf("Expected the open transaction to commit, got %s", err.Error())
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Shutdown error: %s", err.Error())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Shutdown to return once the transaction is complete")
+	}
+}
+
+func Test_Client_ShutdownRollsBackAtDeadline(t *testing.T) {
+	client := NewClient(getSQLiteFileDb(t))
+	transaction, err := client.NewContext().BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+	if _, err := transaction.ExecStatement(newPersonStatement("Dadang")); err != nil {
+		t.Fatalf("ExecStatement error: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*drainInterval)
+	defer cancel()
+	if err := client.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if !transaction.IsComplete() || len(client.OpenTransactions()) != 0 {
+		t.Error("Expected the open transaction to be rolled back")
+	}
+	if err := transaction.Commit(); err == nil {
+		t.Error("Expected the rolled back transaction not to commit")
+	}
+}
+
+func Test_Client_ShutdownWaitsForRows(t *testing.T) {
+	client := NewClient(getSQLiteFileDb(t))
+	for _, name := range []string{"Dadang", "Asep"} {
+		if _, err := client.ExecStatement(newPersonStatement(name)); err != nil {
+			t.Fatalf("ExecStatement error: %s", err.Error())
+		}
+	}
+	rows, err := client.QueryStatement(NewStatement("SELECT name FROM person ORDER BY name"))
+	if err != nil {
+		t.Fatalf("QueryStatement error: %s", err.Error())
+	}
+
+	done := make(chan error)
+	go func() { done <- client.Shutdown(context.Background()) }()
+	time.Sleep(3 * drainInterval)
+	select {
+	case err := <-done:
+		t.Fatalf("Expected Shutdown to wait for the open rows, got %v", err)
+	default:
+	}
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("Scan error: %s", err.Error())
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if len(names) != 2 {
+		t.Errorf("Expected the rows to be read while Shutdown waits, got %v", names)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Shutdown error: %s", err.Error())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Shutdown to return once the rows are closed")
+	}
+}
+
+func Test_Client_ShutdownJoinsErrors(t *testing.T) {
+	client := NewClient(getSQLiteFileDb(t))
+	var hookErr error
+	client.OnShutdown(func(ctx context.Context) error {
+		hookErr = ctx.Err()
+		return errors.New("flush failed")
+	})
+	rows, err := client.QueryStatement(NewStatement("SELECT 1"))
+	if err != nil {
+		t.Fatalf("QueryStatement error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*drainInterval)
+	defer cancel()
+	err = client.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "flush failed") {
+		t.Errorf("Expected the drain timeout joined with the error of the shutdown function, got %v", err)
+	}
+	if hookErr != nil {
+		t.Errorf("Expected the shutdown function to get a context which is not done, got %v", hookErr)
+	}
+}
@@ -0,0 +1,170 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+//DeletedAtColumn is the default soft delete column
+const DeletedAtColumn = "deleted_at"
+
+const withDeletedKey txContextKey = "withDeleted"
+
+//WithDeleted returns ctx which makes soft delete filters include soft deleted records
+func WithDeleted(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, withDeletedKey, true)
+}
+
+//IncludesDeleted determine if soft deleted records are included by queries given ctx, see WithDeleted
+func IncludesDeleted(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	included, _ := ctx.Value(withDeletedKey).(bool)
+	return included
+}
+
+//SoftDelete represent a table whose records are marked as deleted by setting a timestamp column instead of
+//being removed. The table is used as is in statements, quote it if needed
+type SoftDelete struct {
+	table    string
+	column   string
+	unscoped bool
+}
+
+//WithColumn returns a copy of the soft delete using the column instead of deleted_at
+func (me *SoftDelete) WithColumn(column string) *SoftDelete {
+	copied := *me
+	copied.column = column
+	return &copied
+}
+
+//Unscoped returns a copy of the soft delete which neither filters soft deleted records nor soft deletes,
+//its Delete statement removes the records
+func (me *SoftDelete) Unscoped() *SoftDelete {
+	copied := *me
+	copied.unscoped = true
+	return &copied
+}
+
+//IsUnscoped determine if the soft delete is unscoped
+func (me *SoftDelete) IsUnscoped() bool {
+	return me.unscoped
+}
+
+//Predicate returns the predicate excluding soft deleted records, empty if they are included
+//because the soft delete is unscoped or ctx is WithDeleted
+func (me *SoftDelete) Predicate(ctx context.Context) string {
+	if me.unscoped || IncludesDeleted(ctx) {
+		return ""
+	}
+	return me.column + " IS NULL"
+}
+
+//Where returns the WHERE clause joining the predicates and the soft delete predicate, empty if there is none
+func (me *SoftDelete) Where(ctx context.Context, predicates ...string) string {
+	if predicate := me.Predicate(ctx); predicate != "" {
+		predicates = append(predicates, predicate)
+	}
+	if len(predicates) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(predicates, " AND ")
+}
+
+//Delete returns the statement soft deleting the records matching the where predicate at the time, or deleting them
+//if the soft delete is unscoped. The time is stored in UTC like the one Purge compares with, pass the client clock
+//so both agree, see Client.Now
+func (me *SoftDelete) Delete(where string, at time.Time) *Statement {
+	if me.unscoped {
+		return NewStatement("DELETE FROM " + me.table + " WHERE " + where)
+	}
+	statement := NewStatement("UPDATE " + me.table + " SET " + me.column + " = :dbx_deleted_at WHERE (" + where + ") AND " + me.column + " IS NULL")
+	statement.AddParameter("dbx_deleted_at", at.UTC())
+	return statement
+}
+
+//Restore returns the statement restoring the soft deleted records matching the where predicate
+func (me *SoftDelete) Restore(where string) *Statement {
+	return NewStatement("UPDATE " + me.table + " SET " + me.column + " = NULL WHERE (" + where + ") AND " + me.column + " IS NOT NULL")
+}
+
+//Purge returns the statement removing the records soft deleted before the time
+func (me *SoftDelete) Purge(before time.Time) *Statement {
+	statement := NewStatement("DELETE FROM " + me.table + " WHERE " + me.column + " < :dbx_deleted_before")
+	statement.AddParameter("dbx_deleted_before", before.UTC())
+	return statement
+}
+
+//NewSoftDelete create new soft delete instance of the table using the deleted_at column
+func NewSoftDelete(table string) *SoftDelete {
+	return &SoftDelete{
+		table:  table,
+		column: DeletedAtColumn,
+	}
+}
+
+//Purger removes records soft deleted longer than the retention ago
+type Purger struct {
+	client    *Client
+	retention time.Duration
+	tables    []*SoftDelete
+}
+
+//Purge removes records of every table soft deleted longer than the retention ago by the client clock and returns
+//the number of removed records. Each table is purged in its own statement, the ones purged before a failure stay purged
+func (me *Purger) Purge(ctx context.Context) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	before := me.client.Now().Add(-me.retention)
+	var purged int64
+	for _, table := range me.tables {
+		result, err := me.client.ExecStatementContext(ctx, table.Purge(before))
+		if err != nil {
+			return purged, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+		purged += affected
+	}
+	return purged, nil
+}
+
+//Run purges every interval until ctx is done or a purge fails. It returns the purge error, nil when ctx is done
+func (me *Purger) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("Purge interval must be positive")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := me.Purge(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+//NewPurger create new purger instance removing records of the tables soft deleted longer than the retention ago
+func NewPurger(client *Client, retention time.Duration, tables ...*SoftDelete) *Purger {
+	return &Purger{
+		client:    client,
+		retention: retention,
+		tables:    tables,
+	}
+}
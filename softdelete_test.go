@@ -0,0 +1,108 @@
+package dbx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_SoftDelete_Statements(t *testing.T) {
+	softDelete := NewSoftDelete(`"order"`)
+	ctx := context.Background()
+
+	if where := softDelete.Where(ctx, "id = :id"); where != " WHERE id = :id AND deleted_at IS NULL" {
+		t.Errorf("Unexpected where clause %q", where)
+	}
+	if where := softDelete.Where(WithDeleted(ctx)); where != "" {
+		t.Errorf("Expected no where clause WithDeleted, got %q", where)
+	}
+	if where := softDelete.Unscoped().Where(ctx, "id = :id"); where != " WHERE id = :id" {
+		t.Errorf("Expected unscoped where clause without soft delete predicate, got %q", where)
+	}
+	if softDelete.IsUnscoped() {
+		t.Errorf("Unscoped must not change the original soft delete")
+	}
+
+	cases := map[string]string{
+		softDelete.Delete("id = :id", time.Now()).SQL:               `UPDATE "order" SET deleted_at = :dbx_deleted_at WHERE (id = :id) AND deleted_at IS NULL`,
+		softDelete.Unscoped().Delete("id = :id", time.Now()).SQL:    `DELETE FROM "order" WHERE id = :id`,
+		softDelete.WithColumn("removed_at").Restore("id = :id").SQL: `UPDATE "order" SET removed_at = NULL WHERE (id = :id) AND removed_at IS NOT NULL`,
+		softDelete.Purge(time.Now()).SQL:                            `DELETE FROM "order" WHERE deleted_at < :dbx_deleted_before`,
+	}
+	for actual, expected := range cases {
+		if actual != expected {
+			t.Errorf("Expected %q, but got %q", expected, actual)
+		}
+	}
+	at := time.Date(2024, 1, 1, 7, 0, 0, 0, time.FixedZone("WIB", 7*60*60))
+	if deletedAt := softDelete.Delete("id = :id", at).Parameters["dbx_deleted_at"]; deletedAt != at.UTC() {
+		t.Errorf("Expected the deletion time in UTC, got %v", deletedAt)
+	}
+}
+
+func Test_SoftDelete_DeleteRestoreAndPurge(t *testing.T) {
+	db, err := getSqlxDb(t)
+	if err != nil {
+		t.Fatalf("Fatal create db error: %s", err.Error())
+	}
+	db.MustExec("CREATE TABLE IF NOT EXISTS note (id INTEGER PRIMARY KEY, body VARCHAR(255) NOT NULL, deleted_at TIMESTAMP)")
+	db.MustExec("INSERT INTO note (id, body) VALUES (1, 'first'), (2, 'second')")
+
+	client := NewClient(db)
+	softDelete := NewSoftDelete("note")
+	ctx := context.Background()
+	count := func(ctx context.Context) int {
+		var count int
+		if err := db.Get(&count, "SELECT COUNT(*) FROM note"+softDelete.Where(ctx)); err != nil {
+			t.Fatalf("Count note error: %s", err.Error())
+		}
+		return count
+	}
+
+	dbContext := client.NewContext()
+	deleteStatement := softDelete.Delete("id = :id", client.Now())
+	deleteStatement.AddParameter("id", 1)
+	dbContext.AddStatement(deleteStatement)
+	if _, err := dbContext.SaveChanges(ctx); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	if count(ctx) != 1 || count(WithDeleted(ctx)) != 2 {
+		t.Fatalf("Expected soft deleted note to be filtered out, got %d of %d notes", count(ctx), count(WithDeleted(ctx)))
+	}
+
+	restoreStatement := softDelete.Restore("id = :id")
+	restoreStatement.AddParameter("id", 1)
+	if _, err := client.ExecStatementContext(ctx, restoreStatement); err != nil {
+		t.Fatalf("Restore error: %s", err.Error())
+	}
+	if count(ctx) != 2 {
+		t.Fatalf("Expected restored note, got %d notes", count(ctx))
+	}
+
+	if _, err := client.ExecStatementContext(ctx, deleteStatement); err != nil {
+		t.Fatalf("Delete error: %s", err.Error())
+	}
+	purger := NewPurger(client, time.Hour, softDelete)
+	if purged, err := purger.Purge(ctx); err != nil || purged != 0 {
+		t.Fatalf("Expected no note to be purged within the retention, got %d (%v)", purged, err)
+	}
+	client.WithClock(func() time.Time { return time.Now().Add(2 * time.Hour) })
+	if purged, err := purger.Purge(ctx); err != nil || purged != 1 {
+		t.Fatalf("Expected the soft deleted note to be purged, got %d (%v)", purged, err)
+	}
+	if count(WithDeleted(ctx)) != 1 {
+		t.Errorf("Expected a single note left, got %d", count(WithDeleted(ctx)))
+	}
+}
+
+func Test_Purger_Run(t *testing.T) {
+	purger := NewPurger(NewClient(nil), time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := purger.Run(ctx, time.Millisecond); err != nil {
+		t.Errorf("Expected Run to stop without error when ctx is done, got %s", err.Error())
+	}
+	if err := purger.Run(context.Background(), 0); err == nil {
+		t.Errorf("Expected an error for a non positive interval")
+	}
+}
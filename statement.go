@@ -1,5 +1,22 @@
 package dbx
 
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+const (
+	//CreatedAtColumn is the column stamped with the time a record is inserted
+	CreatedAtColumn = "created_at"
+	//UpdatedAtColumn is the column stamped with the time a record is updated
+	UpdatedAtColumn = "updated_at"
+	//CreatedByColumn is the column stamped with the actor inserting a record
+	CreatedByColumn = "created_by"
+	//UpdatedByColumn is the column stamped with the actor updating a record
+	UpdatedByColumn = "updated_by"
+)
+
 //SQLParameter represent the sql parameter
 type SQLParameter struct {
 	Name  string
@@ -8,8 +25,22 @@ type SQLParameter struct {
 
 //Statement represent the SQL statement
 type Statement struct {
-	SQL        string
-	Parameters map[string]interface{}
+	SQL          string
+	Parameters   map[string]interface{}
+	tenantScoped bool
+	table        string
+	keyColumns   []string
+	stamps       []string
+	lock         string
+	lockWait     string
+	cacheTTL     time.Duration
+	cacheTags    []string
+	invalidates  []string
+	timeout      time.Duration
+	idempotent   bool
+	retryPolicy  *RetryPolicy
+	replica      bool
+	shardKey     string
 }
 
 //AddParameter add new parameter to sql statement
@@ -17,6 +48,98 @@ func (me *Statement) AddParameter(name string, value interface{}) {
 	me.Parameters[name] = value
 }
 
+//ScopeTenant marks the statement as tenant scoped, the tenant of ctx is bound to its dbx_tenant parameter
+//when it's executed. See WithTenant
+func (me *Statement) ScopeTenant() *Statement {
+	me.tenantScoped = true
+	return me
+}
+
+//IsTenantScoped determine if the statement is tenant scoped
+func (me *Statement) IsTenantScoped() bool {
+	return me.tenantScoped
+}
+
+//Affects declares the statement changes the record of the table identified by the values of the key column
+//parameters. Changes of records of audited tables are recorded, see Client.WithAudit
+func (me *Statement) Affects(table string, keyColumns ...string) *Statement {
+	me.table = table
+	me.keyColumns = keyColumns
+	return me
+}
+
+//Stamp declares the parameters of the columns filled by Context.SaveChanges: the ones ending with _by
+//with the actor of ctx, nil if there is none, the others with the time of the client clock.
+//See WithActor and Client.WithClock
+func (me *Statement) Stamp(columns ...string) *Statement {
+	me.stamps = append(me.stamps, columns...)
+	return me
+}
+
+//stamp returns a copy of the statement with its stamped parameters filled, the statement itself if it has none
+func (me *Statement) stamp(ctx context.Context, now time.Time) *Statement {
+	if len(me.stamps) == 0 {
+		return me
+	}
+	stamped := me.clone()
+	for _, column := range me.stamps {
+		if !strings.HasSuffix(column, "_by") {
+			stamped.AddParameter(column, now)
+			continue
+		}
+		stamped.AddParameter(column, nil)
+		if actor, ok := ActorFrom(ctx); ok {
+			stamped.AddParameter(column, actor)
+		}
+	}
+	return stamped
+}
+
+//clone returns a copy of the statement
+func (me *Statement) clone() *Statement {
+	cloned := NewStatement(me.SQL)
+	for name, value := range me.Parameters {
+		cloned.AddParameter(name, value)
+	}
+	cloned.tenantScoped = me.tenantScoped
+	cloned.table = me.table
+	cloned.keyColumns = me.keyColumns
+	cloned.stamps = me.stamps
+	cloned.lock = me.lock
+	cloned.lockWait = me.lockWait
+	cloned.cacheTTL = me.cacheTTL
+	cloned.cacheTags = me.cacheTags
+	cloned.invalidates = me.invalidates
+	cloned.timeout = me.timeout
+	cloned.idempotent = me.idempotent
+	cloned.retryPolicy = me.retryPolicy
+	cloned.replica = me.replica
+	cloned.shardKey = me.shardKey
+	return cloned
+}
+
+//Bind returns the statement to be executed given ctx, with the parameters bound to ctx such as the tenant
+//and the locking clause appended. The statement itself is returned if there is nothing to bind,
+//otherwise it's left unchanged and a copy is returned
+func (me *Statement) Bind(ctx context.Context) (*Statement, error) {
+	if !me.tenantScoped && me.lock == "" {
+		return me, nil
+	}
+	bound := me.clone()
+	if me.tenantScoped {
+		tenant, ok := TenantFrom(ctx)
+		if !ok {
+			return nil, ErrTenantRequired
+		}
+		bound.AddParameter(TenantParameter, tenant)
+	}
+	if me.lock != "" {
+		bound.SQL = strings.TrimRight(strings.TrimSpace(me.SQL), "; \n\t") + " " + me.lockClause()
+		bound.lock, bound.lockWait = "", ""
+	}
+	return bound, nil
+}
+
 //NewStatement returns new SQL statement instance
 func NewStatement(sql string, params ...*SQLParameter) *Statement {
 	var statement = &Statement{
@@ -0,0 +1,77 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//TenantColumn is the default tenant column of tenant scoped tables
+const TenantColumn = "tenant_id"
+
+//TenantParameter is the parameter tenant scoped statements are bound to, see Statement.ScopeTenant
+const TenantParameter = "dbx_tenant"
+
+//TenantSettingName is the setting holding the tenant of the transaction with the TenancySetting strategy
+const TenantSettingName = "app.tenant_id"
+
+const tenantKey txContextKey = "tenant"
+
+//ErrTenantRequired is returned when a tenant scoped statement is executed given ctx without tenant
+var ErrTenantRequired = errors.New("Tenant is required but ctx has none")
+
+//ErrTenantTransactionRequired is returned when a query given ctx with tenant is not run in a transaction
+//while the tenancy strategy applies the tenant to transactions
+var ErrTenantTransactionRequired = errors.New("Tenant can only be applied to a query run in a transaction")
+
+//TenancyStrategy determine how the tenant of ctx is applied besides binding it to tenant scoped statements
+type TenancyStrategy int
+
+const (
+	//TenancyColumn only binds the tenant to tenant scoped statements, which filter and stamp the tenant column
+	TenancyColumn TenancyStrategy = iota
+	//TenancySetting sets app.tenant_id to the tenant for each transaction, to be used by Postgres row level
+	//security policies with current_setting('app.tenant_id')
+	TenancySetting
+	//TenancySchema sets the Postgres search_path to the schema named after the tenant for each transaction
+	TenancySchema
+)
+
+//WithTenant returns ctx carrying the tenant statements are scoped to
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+//TenantFrom returns the tenant carried by ctx and whether there is one
+func TenantFrom(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	tenant, ok := ctx.Value(tenantKey).(string)
+	return tenant, ok && tenant != ""
+}
+
+//tenantSetup returns the function applying the tenant of ctx to a new transaction according to the tenancy
+//strategy, nil if there is nothing to apply
+func (me *Client) tenantSetup(ctx context.Context) func(tx *sqlx.Tx) error {
+	if me.tenancy == TenancyColumn {
+		return nil
+	}
+	tenant, ok := TenantFrom(ctx)
+	if !ok {
+		return nil
+	}
+	name, value := TenantSettingName, tenant
+	if me.tenancy == TenancySchema {
+		name, value = "search_path", `"`+strings.ReplaceAll(tenant, `"`, `""`)+`"`
+	}
+	return func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(tx.Rebind("SELECT set_config(?, ?, true)"), name, value)
+		return err
+	}
+}
@@ -0,0 +1,146 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_Statement_Bind(t *testing.T) {
+	statement := NewStatement("SELECT * FROM invoice WHERE id = :id")
+	statement.AddParameter("id", 1)
+	if bound, err := statement.Bind(context.Background()); err != nil || bound != statement {
+		t.Errorf("Expected a statement which is not tenant scoped to be returned as is, got %v (%v)", bound, err)
+	}
+
+	statement.ScopeTenant()
+	if _, err := statement.Bind(context.Background()); !errors.Is(err, ErrTenantRequired) {
+		t.Errorf("Expected ErrTenantRequired, got %v", err)
+	}
+	if _, err := statement.Bind(WithTenant(context.Background(), "")); !errors.Is(err, ErrTenantRequired) {
+		t.Errorf("Expected ErrTenantRequired for an empty tenant, got %v", err)
+	}
+
+	bound, err := statement.Bind(WithTenant(context.Background(), "acme"))
+	if err != nil {
+		t.Fatalf("Bind error: %s", err.Error())
+	}
+	if bound == statement || bound.Parameters[TenantParameter] != "acme" || bound.Parameters["id"] != 1 || !bound.IsTenantScoped() {
+		t.Errorf("Expected a tenant scoped copy bound to the tenant, got %+v", bound)
+	}
+	if _, ok := statement.Parameters[TenantParameter]; ok {
+		t.Errorf("Bind must not change the statement")
+	}
+}
+
+func Test_Client_TenantScopedStatements(t *testing.T) {
+	db, err := getSqlxDb(t)
+	if err != nil {
+		t.Fatalf("Fatal create db error: %s", err.Error())
+	}
+	db.MustExec("CREATE TABLE IF NOT EXISTS invoice (id INTEGER PRIMARY KEY, tenant_id VARCHAR(36) NOT NULL, total INTEGER NOT NULL)")
+
+	client := NewClient(db)
+	insert := func(id int, total int) *Statement {
+		statement := NewStatement("INSERT INTO invoice (id, tenant_id, total) VALUES (:id, :dbx_tenant, :total)").ScopeTenant()
+		statement.AddParameter("id", id)
+		statement.AddParameter("total", total)
+		return statement
+	}
+	acme := WithTenant(context.Background(), "acme")
+	globex := WithTenant(context.Background(), "globex")
+
+	dbContext := client.NewContext()
+	dbContext.AddStatements(insert(1, 100), insert(2, 200))
+	if _, err := dbContext.SaveChanges(acme); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	dbContext.AddStatement(insert(3, 300))
+	if _, err := dbContext.SaveChanges(globex); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	dbContext.AddStatement(insert(4, 400))
+	if _, err := dbContext.SaveChanges(context.Background()); !errors.Is(err, ErrTenantRequired) {
+		t.Fatalf("Expected ErrTenantRequired, got %v", err)
+	}
+
+	sum := func(ctx context.Context) int {
+		rows, err := client.QueryStatementContext(ctx, NewStatement("SELECT COALESCE(SUM(total), 0) FROM invoice WHERE tenant_id = :dbx_tenant").ScopeTenant())
+		if err != nil {
+			t.Fatalf("QueryStatementContext error: %s", err.Error())
+		}
+		defer rows.Close()
+		var total int
+		for rows.Next() {
+			rows.Scan(&total)
+		}
+		return total
+	}
+	if total := sum(acme); total != 300 {
+		t.Errorf("Expected acme invoices only, got total %d", total)
+	}
+	if total := sum(globex); total != 300 {
+		t.Errorf("Expected globex invoices only, got total %d", total)
+	}
+	if _, err := client.QueryStatementContext(context.Background(), NewStatement("SELECT * FROM invoice WHERE tenant_id = :dbx_tenant").ScopeTenant()); !errors.Is(err, ErrTenantRequired) {
+		t.Errorf("Expected ErrTenantRequired, got %v", err)
+	}
+}
+
+func Test_Client_TenancySetting(t *testing.T) {
+	client, database := newScriptedClient(t, "postgres", nil)
+	client.WithTenancy(TenancySetting)
+	ctx := WithTenant(context.Background(), "acme")
+
+	dbContext := client.NewContext()
+	dbContext.AddStatement(NewStatement("UPDATE invoice SET paid = true WHERE tenant_id = :dbx_tenant").ScopeTenant())
+	if _, err := dbContext.SaveChanges(ctx); err != nil {
+		t.Fatalf("SaveChanges error: %s", err.Error())
+	}
+	if kinds := strings.Join(database.kinds(), " "); kinds != "begin exec exec commit" {
+		t.Fatalf("Expected the statement to be saved in a transaction, got %s", kinds)
+	}
+	calls := database.statements("")
+	if calls[0].SQL != "SELECT set_config($1, $2, true)" || calls[0].Args[0] != TenantSettingName || calls[0].Args[1] != "acme" {
+		t.Errorf("Expected the tenant to be set for the transaction, got %+v", calls[0])
+	}
+	if calls[1].Args[0] != "acme" {
+		t.Errorf("Expected the statement to be bound to the tenant, got %v", calls[1].Args)
+	}
+
+	database.reset()
+	if _, err := client.QueryStatementContext(ctx, NewStatement("SELECT * FROM invoice")); !errors.Is(err, ErrTenantTransactionRequired) {
+		t.Errorf("Expected ErrTenantTransactionRequired, got %v", err)
+	}
+	scope, unitOfWork := client.NewScope(ctx)
+	if _, err := unitOfWork.BeginTransactionContext(scope); err != nil {
+		t.Fatalf("BeginTransactionContext error: %s", err.Error())
+	}
+	rows, err := client.QueryStatementContext(scope, NewStatement("SELECT * FROM invoice"))
+	if err != nil {
+		t.Fatalf("QueryStatementContext error: %s", err.Error())
+	}
+	rows.Close()
+	if err := unitOfWork.CompleteTransaction(); err != nil {
+		t.Fatalf("CompleteTransaction error: %s", err.Error())
+	}
+	if kinds := strings.Join(database.kinds(), " "); kinds != "begin exec query commit" {
+		t.Errorf("Expected the query to run in the transaction of the scope, got %s", kinds)
+	}
+}
+
+func Test_Client_TenancySchema(t *testing.T) {
+	client, database := newScriptedClient(t, "postgres", nil)
+	client.WithTenancy(TenancySchema)
+
+	if _, err := client.ExecStatementContext(WithTenant(context.Background(), `acme"corp`), NewStatement("DELETE FROM invoice")); err != nil {
+		t.Fatalf("ExecStatementContext error: %s", err.Error())
+	}
+	if kinds := strings.Join(database.kinds(), " "); kinds != "begin exec exec commit" {
+		t.Fatalf("Expected the statement to run in a transaction, got %s", kinds)
+	}
+	if args := database.statements("")[0].Args; args[0] != "search_path" || args[1] != `"acme""corp"` {
+		t.Errorf("Expected search_path to be set to the quoted tenant schema, got %v", args)
+	}
+}
@@ -0,0 +1,187 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+//ErrStatementTimeout is returned when a statement runs longer than its timeout
+var ErrStatementTimeout = errors.New("Statement timeout is exceeded")
+
+//queryCanceledCode is the SQLSTATE of query_canceled, reported by Postgres statement timeouts
+const queryCanceledCode = "57014"
+
+//timeoutError is an error of a statement which ran longer than its timeout
+type timeoutError struct {
+	err error
+}
+
+func (me *timeoutError) Error() string {
+	return ErrStatementTimeout.Error() + ": " + me.err.Error()
+}
+
+//Is determine if target is ErrStatementTimeout
+func (me *timeoutError) Is(target error) bool {
+	return target == ErrStatementTimeout
+}
+
+//Unwrap returns the driver error
+func (me *timeoutError) Unwrap() error {
+	return me.err
+}
+
+//Timeout set the timeout of the statement overriding the default one of the client, a negative timeout disables it.
+//See Client.WithStatementTimeout
+func (me *Statement) Timeout(timeout time.Duration) *Statement {
+	me.timeout = timeout
+	return me
+}
+
+//WithStatementTimeout set the default timeout of statements, applied through the context they run with.
+//Rows of a query are read within its deadline. It must be set before the client is used
+func (me *Client) WithStatementTimeout(timeout time.Duration) *Client {
+	me.timeout = timeout
+	return me
+}
+
+//WithLocalStatementTimeout makes the Postgres transactions the client begins set their statement_timeout to the default
+//timeout, so it's enforced by the server as well. A statement with its own timeout sets it before running and the
+//default is restored afterwards, once the rows are closed for a query. It must be set before the client is used
+func (me *Client) WithLocalStatementTimeout() *Client {
+	me.localTimeout = true
+	return me
+}
+
+//newTransaction begins a transaction given ctx on the shard of ctx, see newTransactionOn
+func (me *Client) newTransaction(ctx context.Context) (*Transaction, error) {
+	shard, err := me.shardOf(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return me.newTransactionOn(ctx, shard)
+}
+
+//newTransactionOn begins a transaction given ctx on the shard, with the tenant of ctx applied and the default
+//statement timeout. Beginning it is guarded by the circuit breaker
+func (me *Client) newTransactionOn(ctx context.Context, shard int) (*Transaction, error) {
+	setups := []func(tx *sqlx.Tx) error{}
+	if setup := me.tenantSetup(ctx); setup != nil {
+		setups = append(setups, setup)
+	}
+	localTimeout := me.localTimeout && (me.DriverName() == "postgres" || me.DriverName() == "pgx")
+	if localTimeout && me.timeout > 0 {
+		setting := localTimeoutSetting(me.timeout)
+		setups = append(setups, func(tx *sqlx.Tx) error {
+			_, err := tx.Exec(setting)
+			return err
+		})
+	}
+
+	var setup func(tx *sqlx.Tx) error
+	if len(setups) > 0 {
+		setup = func(tx *sqlx.Tx) error {
+			for _, setup := range setups {
+				if err := setup(tx); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	var transaction *Transaction
+	err := me.resilient(ctx, nil, func() error {
+		var err error
+		transaction, err = newTransaction(me.database(shard), setup)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	transaction.timeout = me.timeout
+	transaction.localTimeout = localTimeout
+	transaction.shard = shard
+	transaction.tracker = me.transactions
+	transaction.lifecycle = me.lifecycle
+	me.transactions.add(transaction)
+	return transaction, nil
+}
+
+//localTimeoutSetting returns the statement setting the statement_timeout of a transaction to timeout, to the one
+//of the session if timeout is zero and to none if it's negative
+func localTimeoutSetting(timeout time.Duration) string {
+	if timeout == 0 {
+		return "SET LOCAL statement_timeout TO DEFAULT"
+	}
+	if timeout < 0 {
+		return "SET LOCAL statement_timeout = 0"
+	}
+	milliseconds := timeout.Milliseconds()
+	if milliseconds < 1 {
+		milliseconds = 1
+	}
+	return fmt.Sprintf("SET LOCAL statement_timeout = %d", milliseconds)
+}
+
+//applyLocalTimeout sets the statement_timeout of the transaction to the timeout of the statement if it differs from
+//the default one, the returned function restores the default
+func (me *Transaction) applyLocalTimeout(ctx context.Context, statement *Statement) (func() error, error) {
+	if !me.localTimeout || statement.timeout == 0 || statement.timeout == me.timeout {
+		return func() error { return nil }, nil
+	}
+	tx := me.tx()
+	if _, err := tx.ExecContext(ctx, localTimeoutSetting(statement.timeout)); err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := tx.Exec(localTimeoutSetting(me.timeout))
+		return err
+	}, nil
+}
+
+//timeoutOf returns the timeout of the statement given the default one, not positive if there is none
+func timeoutOf(statement *Statement, defaultTimeout time.Duration) time.Duration {
+	if statement.timeout != 0 {
+		return statement.timeout
+	}
+	return defaultTimeout
+}
+
+//statementContext returns ctx with the deadline of the timeout and the function releasing it,
+//ctx itself if timeout is not positive
+func statementContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+//timeoutErr returns err as ErrStatementTimeout if it's caused by the deadline of statementCtx, derived from ctx,
+//or reports a statement timeout of the server
+func timeoutErr(ctx context.Context, statementCtx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if statementCtx != ctx && errors.Is(statementCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return &timeoutError{err: err}
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == queryCanceledCode && strings.Contains(pqErr.Message, "statement timeout") {
+		return &timeoutError{err: err}
+	}
+	if strings.Contains(err.Error(), "maximum statement execution time exceeded") {
+		return &timeoutError{err: err}
+	}
+	return err
+}
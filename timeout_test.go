@@ -0,0 +1,159 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/supendi/dbx/internal/driverutil"
+)
+
+const slowStatement = "WITH RECURSIVE counter(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM counter WHERE x < 100000000) SELECT MAX(x) FROM counter"
+
+func Test_Client_StatementTimeout(t *testing.T) {
+	client := NewClient(getSQLiteFileDb(t)).WithStatementTimeout(20 * time.Millisecond)
+
+	if _, err := client.ExecStatement(NewStatement(slowStatement)); !errors.Is(err, ErrStatementTimeout) {
+		t.Errorf("Expected ErrStatementTimeout, got %v", err)
+	}
+	rows, err := client.QueryStatement(NewStatement(slowStatement).Timeout(10 * time.Millisecond))
+	if err != nil && !errors.Is(err, ErrStatementTimeout) {
+		t.Errorf("Expected ErrStatementTimeout, got %v", err)
+	}
+	if err == nil {
+		if rows.Next() || !errors.Is(rows.Err(), context.DeadlineExceeded) {
+			t.Errorf("Expected the rows to be closed by the deadline, got %v", rows.Err())
+		}
+		rows.Close()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.ExecStatementContext(ctx, NewStatement(slowStatement)); err == nil || errors.Is(err, ErrStatementTimeout) {
+		t.Errorf("Expected a canceled statement not to time out, got %v", err)
+	}
+
+	dbContext := client.NewContext()
+	transaction, err := dbContext.BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+	defer dbContext.CompleteTransaction()
+	if _, err := transaction.ExecStatement(NewStatement(slowStatement)); !errors.Is(err, ErrStatementTimeout) {
+		t.Errorf("Expected the default timeout to apply in the transaction, got %v", err)
+	}
+}
+
+func Test_Statement_TimeoutDisabled(t *testing.T) {
+	client := NewClient(getSQLiteFileDb(t)).WithStatementTimeout(time.Nanosecond)
+	rows, err := client.QueryStatement(NewStatement("SELECT 1").Timeout(-1))
+	if err != nil {
+		t.Fatalf("Expected the statement to run without timeout, got %s", err.Error())
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Errorf("Expected a row, got %v", rows.Err())
+	}
+}
+
+func Test_Rows_ReleaseDeadline(t *testing.T) {
+	client, database := newScriptedClient(t, "postgres", func(call *scriptedCall) (*driverutil.Rows, error) {
+		return scriptedRows("id", int64(1)), nil
+	})
+	client.WithStatementTimeout(time.Hour)
+
+	rows, err := client.QueryStatement(NewStatement("SELECT id FROM job"))
+	if err != nil {
+		t.Fatalf("QueryStatement error: %s", err.Error())
+	}
+	statementCtx := database.statements("")[0].Context
+	if statementCtx.Err() != nil {
+		t.Fatalf("Expected the deadline to cover reading the rows, got %v", statementCtx.Err())
+	}
+	rows.Close()
+	if !errors.Is(statementCtx.Err(), context.Canceled) {
+		t.Errorf("Expected the deadline to be released once the rows are closed, got %v", statementCtx.Err())
+	}
+
+	transaction, err := client.NewContext().BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+	defer transaction.Rollback()
+	rows, err = transaction.QueryStatement(NewStatement("SELECT id FROM job"))
+	if err != nil {
+		t.Fatalf("QueryStatement error: %s", err.Error())
+	}
+	statementCtx = database.statements("")[1].Context
+	for rows.Next() {
+	}
+	if !errors.Is(statementCtx.Err(), context.Canceled) {
+		t.Errorf("Expected the deadline to be released once the rows are read, got %v", statementCtx.Err())
+	}
+	rows.Close()
+}
+
+func Test_Client_LocalStatementTimeout(t *testing.T) {
+	client, database := newScriptedClient(t, "postgres", func(call *scriptedCall) (*driverutil.Rows, error) {
+		if call.Kind == "exec" && strings.HasPrefix(call.SQL, "UPDATE") {
+			return nil, &pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"}
+		}
+		return nil, nil
+	})
+	client.WithStatementTimeout(5 * time.Second).WithLocalStatementTimeout()
+
+	dbContext := client.NewContext()
+	dbContext.AddStatements(NewStatement("INSERT INTO job (id) VALUES (1)"), NewStatement("UPDATE job SET id = 2"))
+	if _, err := dbContext.SaveChanges(context.Background()); !errors.Is(err, ErrStatementTimeout) {
+		t.Errorf("Expected ErrStatementTimeout, got %v", err)
+	}
+	if sql := database.statements("")[0].SQL; sql != "SET LOCAL statement_timeout = 5000" {
+		t.Errorf("Expected the statement timeout to be set for the transaction, got %s", sql)
+	}
+	if kinds := strings.Join(database.kinds(), " "); kinds != "begin exec exec exec rollback" {
+		t.Errorf("Expected the transaction to be rolled back, got %s", kinds)
+	}
+
+	database.reset()
+	transaction, err := client.NewContext().BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction error: %s", err.Error())
+	}
+	defer transaction.Rollback()
+	if _, err := transaction.ExecStatement(NewStatement("DELETE FROM job").Timeout(-1)); err != nil {
+		t.Fatalf("ExecStatement error: %s", err.Error())
+	}
+	rows, err := transaction.QueryStatement(NewStatement("SELECT id FROM job").Timeout(time.Minute))
+	if err != nil {
+		t.Fatalf("QueryStatement error: %s", err.Error())
+	}
+	if calls := database.statements(""); calls[len(calls)-1].SQL != "SELECT id FROM job" {
+		t.Errorf("Expected the default to be restored once the rows are closed, got %s", calls[len(calls)-1].SQL)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatalf("Close error: %s", err.Error())
+	}
+	if _, err := transaction.ExecStatement(NewStatement("DELETE FROM job").Timeout(5 * time.Second)); err != nil {
+		t.Fatalf("ExecStatement error: %s", err.Error())
+	}
+
+	expected := []string{
+		"SET LOCAL statement_timeout = 5000",
+		"SET LOCAL statement_timeout = 0", "DELETE FROM job", "SET LOCAL statement_timeout = 5000",
+		"SET LOCAL statement_timeout = 60000", "SELECT id FROM job", "SET LOCAL statement_timeout = 5000",
+		"DELETE FROM job",
+	}
+	calls := database.statements("")
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected %d statements, got %d", len(expected), len(calls))
+	}
+	for i, call := range calls {
+		if call.SQL != expected[i] {
+			t.Errorf("Expected statement %d to be %s, got %s", i, expected[i], call.SQL)
+		}
+	}
+}
@@ -3,72 +3,166 @@ package dbx
 import (
 	"context"
 	"database/sql"
+	"sync"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
-//Transaction represent db transaction
+//Transaction represent db transaction. Its methods are safe for concurrent use, though statements of a
+//transaction run one at a time on its connection
 type Transaction struct {
 	*sqlx.Tx
-	db         *sqlx.DB
-	isComplete bool
+	db          *sqlx.DB
+	setup       func(tx *sqlx.Tx) error
+	mutex       sync.RWMutex
+	isComplete  bool
+	afterCommit []func()
+	timeout     time.Duration
+	//localTimeout determine if the statement_timeout of the transaction is set to the timeout of its statements
+	localTimeout bool
+	tracker      *transactionTracker
+	lifecycle    *lifecycle
+	shard        int
 }
 
 //IsComplete determine if current transaction is already committed or rolledback
 func (me *Transaction) IsComplete() bool {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
 	return me.isComplete
 }
 
-//ExecStatementContext Create, Update or Delete statement
+//tx returns the current sqlx transaction, it's replaced by StartOver
+func (me *Transaction) tx() *sqlx.Tx {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.Tx
+}
+
+//ExecStatementContext Create, Update or Delete statement, within its timeout or the default one of the client
+//which began the transaction
 func (me *Transaction) ExecStatementContext(ctx context.Context, statement *Statement) (sql.Result, error) {
-	return me.Tx.NamedExecContext(ctx, statement.SQL, statement.Parameters)
+	bound, err := statement.Bind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	restore, err := me.applyLocalTimeout(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	statementCtx, cancel := statementContext(ctx, timeoutOf(statement, me.timeout))
+	defer cancel()
+	result, err := me.tx().NamedExecContext(statementCtx, bound.SQL, bound.Parameters)
+	if err != nil {
+		return result, timeoutErr(ctx, statementCtx, lockErr(err))
+	}
+	return result, restore()
 }
 
-//QueryStatementContext records on database and return it as sql.Rows
-func (me *Transaction) QueryStatementContext(ctx context.Context, statement *Statement) (*sqlx.Rows, error) {
-	return sqlx.NamedQueryContext(ctx, me.Tx, statement.SQL, statement.Parameters)
+//QueryStatementContext records on database and return it as Rows, within its timeout or the default one
+//of the client which began the transaction
+func (me *Transaction) QueryStatementContext(ctx context.Context, statement *Statement) (*Rows, error) {
+	bound, err := statement.Bind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	restore, err := me.applyLocalTimeout(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	statementCtx, cancel := statementContext(ctx, timeoutOf(statement, me.timeout))
+	rows, err := sqlx.NamedQueryContext(statementCtx, me.tx(), bound.SQL, bound.Parameters)
+	if err != nil {
+		cancel()
+		return nil, timeoutErr(ctx, statementCtx, lockErr(err))
+	}
+	return me.lifecycle.track(newRows(rows, func() error {
+		cancel()
+		return restore()
+	})), nil
 }
 
 //ExecStatement Create, Update or Delete statement
 func (me *Transaction) ExecStatement(statement *Statement) (sql.Result, error) {
-	return me.Tx.NamedExec(statement.SQL, statement.Parameters)
+	return me.ExecStatementContext(context.Background(), statement)
 }
 
-//QueryStatement records on database and return it as sql.Rows
-func (me *Transaction) QueryStatement(statement *Statement) (*sqlx.Rows, error) {
-	return sqlx.NamedQuery(me.Tx, statement.SQL, statement.Parameters)
+//QueryStatement records on database and return it as Rows
+func (me *Transaction) QueryStatement(statement *Statement) (*Rows, error) {
+	return me.QueryStatementContext(context.Background(), statement)
 }
 
-//Commit the transaction
+//AfterCommit registers fn to be called once the transaction is committed, it's discarded if the transaction is rolled back
+func (me *Transaction) AfterCommit(fn func()) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.afterCommit = append(me.afterCommit, fn)
+}
+
+//Commit the transaction, then call the functions registered by AfterCommit
 func (me *Transaction) Commit() error {
-	me.isComplete = true
-	return me.Tx.Commit()
+	me.mutex.Lock()
+	me.complete()
+	afterCommit := me.afterCommit
+	me.afterCommit = nil
+	err := me.Tx.Commit()
+	me.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, fn := range afterCommit {
+		fn()
+	}
+	return nil
 }
 
 //Rollback the transaction
 func (me *Transaction) Rollback() error {
-	me.isComplete = true
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.rollback()
+}
+
+//rollback rolls back the transaction, the mutex must be held
+func (me *Transaction) rollback() error {
+	me.complete()
+	me.afterCommit = nil
 	return me.Tx.Rollback()
 }
 
+//complete marks the transaction complete and stops tracking it, the mutex must be held
+func (me *Transaction) complete() {
+	if !me.isComplete {
+		me.tracker.remove(me)
+	}
+	me.isComplete = true
+}
+
 //StartOver start over the transaction, current transaction will be overridden.
 //it's recommended to always check if current transaction is complete or not
 //by calling IsComplete() method
 func (me *Transaction) StartOver() error {
-	newTransaction, err := me.db.Beginx()
+	newTransaction, err := beginTx(me.db, me.setup)
 	if err != nil {
 		return err
 	}
 
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
 	me.Tx = newTransaction
+	if me.isComplete {
+		me.tracker.add(me)
+	}
 	me.isComplete = false
+	me.afterCommit = nil
 	return nil
 }
 
 //CommitAndStartOver commit current transaction and start a new one.
 //If commit failed, it will try to rollback the transaction
 func (me *Transaction) CommitAndStartOver() error {
-	if me.Tx != nil && !me.IsComplete() {
+	if me.tx() != nil && !me.IsComplete() {
 		err := me.Commit()
 		if err != nil {
 			rollBackError := me.Rollback()
@@ -81,14 +175,35 @@ func (me *Transaction) CommitAndStartOver() error {
 	return me.StartOver()
 }
 
-//NewTransaction create tx instance
-func NewTransaction(db *sqlx.DB) (*Transaction, error) {
+//beginTx begins a sqlx transaction and runs the setup in it, the transaction is rolled back if setup fails
+func beginTx(db *sqlx.DB, setup func(tx *sqlx.Tx) error) (*sqlx.Tx, error) {
 	tx, err := db.Beginx()
 	if err != nil {
 		return nil, err
 	}
+	if setup != nil {
+		if err := setup(tx); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
+//newTransaction create tx instance whose setup runs each time the transaction begins
+func newTransaction(db *sqlx.DB, setup func(tx *sqlx.Tx) error) (*Transaction, error) {
+	tx, err := beginTx(db, setup)
+	if err != nil {
+		return nil, err
+	}
 	return &Transaction{
-		db: db,
-		Tx: tx,
+		db:    db,
+		Tx:    tx,
+		setup: setup,
 	}, nil
 }
+
+//NewTransaction create tx instance
+func NewTransaction(db *sqlx.DB) (*Transaction, error) {
+	return newTransaction(db, nil)
+}
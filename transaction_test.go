@@ -9,7 +9,7 @@ import (
 )
 
 func Test_Transaction_ExecStatement(t *testing.T) {
-	db, err := getSqlxDb()
+	db, err := getSqlxDb(t)
 	defer db.Close()
 	if err != nil {
 		t.Errorf("Fatal create db error: %s", err.Error())
@@ -48,7 +48,7 @@ func Test_Transaction_ExecStatement(t *testing.T) {
 }
 
 func Test_Transaction_QueryStatement(t *testing.T) {
-	db, err := getSqlxDb()
+	db, err := getSqlxDb(t)
 	defer db.Close()
 	if err != nil {
 		t.Errorf("Fatal create db error: %s", err.Error())